@@ -1,24 +1,99 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
 
+	pgadapter "github.com/teaelephant/TeaElephantMemory/pkg/pg"
 	"github.com/teaelephant/TeaElephantMemory/printqr"
 )
 
+const (
+	formatUnidoc = "unidoc"
+	formatPDF    = "pdf"
+	formatSVG    = "svg"
+	formatPNG    = "png"
+	formatNDEF   = "ndef"
+)
+
 type configuration struct {
-	UnidocLicenseAPIKey string `required:"true"`
+	// UnidocLicenseAPIKey is only needed for the unidoc format.
+	UnidocLicenseAPIKey string
+	PGDSN               string `envconfig:"PG_DSN" required:"true"`
+	PrintedBy           string `envconfig:"PRINTED_BY" required:"true"`
 }
 
 func main() {
+	pages := flag.Int("pages", 10, "number of label sheets to fill")
+	output := flag.String("output", "", "output PDF file, or directory for svg/png/ndef (default qr_codes.pdf or qr_codes)")
+	format := flag.String("format", formatUnidoc, "output format: unidoc, pdf (license-free vector), svg or png (one file per code), ndef (NFC messages)")
+	templateName := flag.String("template", "classic", "label template: "+strings.Join(printqr.TemplateNames(), ", "))
+	paper := flag.String("paper", "", "paper size override: a4 or letter")
+	inverted := flag.Bool("inverted", true, "print white modules on black")
+	baseURL := flag.String("base-url", "https://tea-elephant.com", "encode universal links under this URL; empty prints bare UUIDs")
+	flag.Parse()
+
 	cfg := new(configuration)
 	if err := envconfig.Process("", cfg); err != nil {
 		panic(err)
 	}
 
-	gen := printqr.NewGenerator(cfg.UnidocLicenseAPIKey)
+	tmpl, err := printqr.LookupTemplate(*templateName, *paper)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := printqr.Options{Template: tmpl, Output: *output, Inverted: *inverted, BaseURL: *baseURL}
+	if opts.Output == "" {
+		opts.Output = "qr_codes"
+		if *format == formatUnidoc || *format == formatPDF {
+			opts.Output = "qr_codes.pdf"
+		}
+	}
+
+	log := logrus.New()
+
+	psql, err := sql.Open("pgx", cfg.PGDSN)
+	if err != nil {
+		panic(err)
+	}
+	defer psql.Close()
+
+	st := pgadapter.NewDB(psql, log.WithField("pkg", "pg"))
+
+	var gen printqr.Generator
+	switch *format {
+	case formatUnidoc:
+		if cfg.UnidocLicenseAPIKey == "" {
+			panic("UNIDOCLICENSEAPIKEY is required for the unidoc format")
+		}
+		gen = printqr.NewGenerator(cfg.UnidocLicenseAPIKey, st, opts)
+	case formatPDF:
+		gen = printqr.NewPDFGenerator(st, opts)
+	case formatSVG:
+		gen = printqr.NewSVGGenerator(st, opts)
+	case formatPNG:
+		gen = printqr.NewPNGGenerator(st, opts)
+	case formatNDEF:
+		if opts.BaseURL == "" {
+			panic("-base-url is required for the ndef format")
+		}
+		gen = printqr.NewNDEFGenerator(st, opts)
+	default:
+		panic(fmt.Sprintf("unknown format %q", *format))
+	}
 
-	if err := gen.GenerateAndSave(10); err != nil {
+	batch, err := gen.GenerateAndSave(context.Background(), *pages, cfg.PrintedBy)
+	if err != nil {
 		panic(err)
 	}
+
+	log.WithFields(logrus.Fields{"batch": batch, "output": opts.Output}).Info("qr codes generated")
 }
@@ -10,22 +10,31 @@ import (
 	"github.com/sideshow/apns2"
 	"github.com/sideshow/apns2/token"
 	"github.com/sirupsen/logrus"
+	"github.com/unidoc/unipdf/v3/common/license"
 
 	gql "github.com/99designs/gqlgen/graphql"
 
+	"github.com/teaelephant/TeaElephantMemory/common"
 	"github.com/teaelephant/TeaElephantMemory/internal/adviser"
 	"github.com/teaelephant/TeaElephantMemory/internal/apns"
 	"github.com/teaelephant/TeaElephantMemory/internal/auth"
+	"github.com/teaelephant/TeaElephantMemory/internal/catalog"
 	"github.com/teaelephant/TeaElephantMemory/internal/consumption"
 	"github.com/teaelephant/TeaElephantMemory/internal/descrgen"
 	"github.com/teaelephant/TeaElephantMemory/internal/expiration"
+	"github.com/teaelephant/TeaElephantMemory/internal/labels"
+	"github.com/teaelephant/TeaElephantMemory/internal/landing"
 	"github.com/teaelephant/TeaElephantMemory/internal/managers/collection"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/device"
 	"github.com/teaelephant/TeaElephantMemory/internal/managers/notification"
 	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
 	"github.com/teaelephant/TeaElephantMemory/internal/managers/tag"
 	"github.com/teaelephant/TeaElephantMemory/internal/managers/tea"
+	"github.com/teaelephant/TeaElephantMemory/internal/notifier"
 	"github.com/teaelephant/TeaElephantMemory/internal/openweather"
+	"github.com/teaelephant/TeaElephantMemory/internal/scheduler"
 	"github.com/teaelephant/TeaElephantMemory/internal/server"
+	"github.com/teaelephant/TeaElephantMemory/internal/teaoftheday"
 	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/graphql"
 	pgadapter "github.com/teaelephant/TeaElephantMemory/pkg/pg"
 )
@@ -38,6 +47,12 @@ type configuration struct {
 	LoggerLevel logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
 	OpenAIToken string       `envconfig:"OPEN_AI_TOKEN" require:"true"`
 	PGDSN       string       `envconfig:"PG_DSN" default:""`
+	// QRPublicFields lists QRRecord fields visible to callers that do not own the record.
+	QRPublicFields []string `envconfig:"QR_PUBLIC_FIELDS" default:"tea,bowlingTemp"`
+	// PublicBaseURL is the origin printed in QR universal links.
+	PublicBaseURL string `envconfig:"PUBLIC_BASE_URL" default:"https://tea-elephant.com"`
+	// UnidocLicenseAPIKey enables the collection catalog PDF, which is rendered with unipdf.
+	UnidocLicenseAPIKey string
 }
 
 //nolint:funlen // main wires dependencies; keep it in one place for clarity despite statement count
@@ -67,12 +82,19 @@ func main() {
 	st := pgadapter.NewDB(psql, logrusLogger.WithField(pkgKey, "pg"))
 
 	teaManager := tea.NewManager(st)
-	qrManager := qr.NewManager(st)
+	qrManager := qr.NewManager(st, cfg.QRPublicFields)
 	tagManager := tag.NewManager(st, teaManager, logrusLogger)
-	collectionManager := collection.NewManager(st)
+	collectionManager := collection.NewManager(st, qrManager)
 
+	// Dev mode is refused unless the server was built with -tags devmode.
 	authCfg := auth.Config()
-	authM := auth.NewAuth(authCfg, st, logrusLogger.WithField(pkgKey, "auth"))
+	var provider auth.Provider = auth.FakeProvider{}
+	if !authCfg.DevMode {
+		if provider, err = auth.NewAppleProvider(authCfg, logrusLogger.WithField(pkgKey, "apple")); err != nil {
+			panic(err)
+		}
+	}
+	authM := auth.NewAuth(authCfg, provider, st, logrusLogger.WithField(pkgKey, "auth"))
 
 	if err = authM.Start(); err != nil {
 		panic(err)
@@ -80,28 +102,50 @@ func main() {
 
 	ai := descrgen.NewGenerator(cfg.OpenAIToken, logrusLogger.WithField(pkgKey, "descrgen"))
 
-	notificationManager := notification.NewManager(st)
+	notifierCfg := notifier.Config()
+	channels := map[common.NotificationChannel]notifier.Channel{
+		common.NotificationChannelInbox: notifier.NewInbox(),
+	}
+	// Dev mode has no Apple key to sign APNs requests with, so push notifications are off.
+	if !authCfg.DevMode {
+		authKey, err := token.AuthKeyFromFile(authCfg.SecretPath)
+		if err != nil {
+			panic(err)
+		}
+
+		apnsToken := &token.Token{
+			AuthKey: authKey,
+			// KeyID from developer account (Certificates, Identifiers & Profiles -> Keys)
+			KeyID: authCfg.KeyID,
+			// TeamID from developer account (View Account -> Membership)
+			TeamID: authCfg.TeamID,
+		}
+		apnsClients := map[common.DeviceEnvironment]apns.Client{
+			common.DeviceEnvironmentSandbox:    apns2.NewTokenClient(apnsToken).Development(),
+			common.DeviceEnvironmentProduction: apns2.NewTokenClient(apnsToken).Production(),
+		}
+
+		channels[common.NotificationChannelAPNs] = apns.NewSender(
+			apnsClients, authCfg.ClientID, apns.Config(), st, logrusLogger.WithField(pkgKey, "apns"))
+	}
+	if notifierCfg.WebhookSecret != "" {
+		channels[common.NotificationChannelWebhook] = notifier.NewWebhook(notifierCfg.WebhookSecret, notifierCfg.WebhookTimeout)
+	}
+	if notifierCfg.SMTPAddr != "" {
+		channels[common.NotificationChannelEmail] = notifier.NewEmail(
+			notifierCfg.SMTPAddr, notifierCfg.SMTPUsername, notifierCfg.SMTPPassword, notifierCfg.SMTPFrom, notifierCfg.SMTPTimeout)
+	}
 
-	authKey, err := token.AuthKeyFromFile(authCfg.SecretPath)
+	notif, err := notifier.New(notifierCfg, st, channels, logrusLogger.WithField(pkgKey, "notifier"))
 	if err != nil {
 		panic(err)
 	}
 
-	apnsClient := apns2.NewTokenClient(&token.Token{
-		AuthKey: authKey,
-		// KeyID from developer account (Certificates, Identifiers & Profiles -> Keys)
-		KeyID: authCfg.KeyID,
-		// TeamID from developer account (View Account -> Membership)
-		TeamID: authCfg.TeamID,
-	}).Development()
+	notificationManager := notification.NewManager(st, notif, channels[common.NotificationChannelEmail], logrusLogger.WithField(pkgKey, "notification"))
+	deviceManager := device.NewManager(st)
 
-	apnsSender := apns.NewSender(apnsClient, authCfg.ClientID, st, logrusLogger.WithField(pkgKey, "apns"))
-
-	expirationAlerter := expiration.NewAlerter(apnsSender, st, logrusLogger.WithField(pkgKey, "expirationAlerter"))
-
-	if err = expirationAlerter.Start(); err != nil {
-		panic(err)
-	}
+	expirationCfg := expiration.Config()
+	expirationAlerter := expiration.NewAlerter(notificationManager, st, expirationCfg, logrusLogger.WithField(pkgKey, "expirationAlerter"))
 
 	weather := openweather.NewService(openweather.Config().ApiKey, logrusLogger.WithField(pkgKey, "openweather"))
 
@@ -111,18 +155,65 @@ func main() {
 	}
 
 	// Consumption history uses the same Postgres connection
-	cons := consumption.NewPGStore(psql, 0)
+	consumptionCfg := consumption.Config()
+	cons := consumption.NewPGStore(psql, consumptionCfg.Retention)
+
+	schedulerCfg := scheduler.Config()
+	jobs, err := scheduler.New(schedulerCfg, st, scheduler.NewPGLeader(psql, schedulerCfg.LockKey), []scheduler.Job{
+		{Name: expiration.JobName, Schedule: expirationCfg.Schedule, Run: expirationAlerter.Run},
+		{Name: expiration.PruneJobName, Schedule: expirationCfg.PruneSchedule, Run: func(ctx context.Context) error {
+			_, err := expirationAlerter.Prune(ctx)
+			return err
+		}},
+		{Name: consumption.PruneJobName, Schedule: consumptionCfg.PruneSchedule, Run: func(ctx context.Context) error {
+			_, err := cons.Prune(ctx)
+			return err
+		}},
+		{Name: auth.AdminRevocationsPruneJobName, Schedule: authCfg.AdminRevocationsPruneSchedule, Run: func(ctx context.Context) error {
+			_, err := authM.PruneAdminTokenRevocations(ctx)
+			return err
+		}},
+		{Name: auth.SessionsPruneJobName, Schedule: authCfg.SessionsPruneSchedule, Run: func(ctx context.Context) error {
+			_, err := authM.PruneSessions(ctx)
+			return err
+		}},
+	}, logrusLogger.WithField(pkgKey, "scheduler"))
+	if err != nil {
+		panic(err)
+	}
+
+	if err = jobs.Start(); err != nil {
+		panic(err)
+	}
+
+	teaOfTheDay := teaoftheday.NewService(st, notificationManager, adv, weather, cons, teaoftheday.Config(),
+		logrusLogger.WithField(pkgKey, "teaOfTheDay"))
+
+	if err = teaOfTheDay.Start(); err != nil {
+		panic(err)
+	}
 
 	resolvers := graphql.NewResolver(
 		logrusLogger.WithField(pkgKey, "graphql"),
-		teaManager, qrManager, tagManager, collectionManager, authM, ai, notificationManager, expirationAlerter,
-		adv, weather, cons,
+		teaManager, qrManager, tagManager, collectionManager, authM, ai, notificationManager, deviceManager, jobs,
+		adv, weather, teaOfTheDay, cfg.PublicBaseURL,
 	)
 
-	s := server.NewServer(resolvers, []gql.HandlerExtension{authM.Middleware()}, authM.WsInitFunc)
+	s := server.NewServer(resolvers, graphql.NewDirectives(), []gql.HandlerExtension{authM.Middleware()}, authM.WsInitFunc)
 	s.InitV2Api()
+	s.InitLanding(landing.NewHandler(qrManager, teaManager, tagManager, landing.Config(), cfg.PublicBaseURL, logrusLogger.WithField(pkgKey, "landing")))
+	if cfg.UnidocLicenseAPIKey != "" {
+		if err = license.SetMeteredKey(cfg.UnidocLicenseAPIKey); err != nil {
+			panic(err)
+		}
+		s.InitCatalog(catalog.NewHandler(collectionManager, tagManager, cfg.PublicBaseURL, logrusLogger.WithField(pkgKey, "catalog")), authM.HTTPMiddleware)
+	} else {
+		logrusLogger.Warn("UNIDOCLICENSEAPIKEY is not set, collection catalog PDF is disabled")
+	}
+	s.InitLabels(labels.NewHandler(qrManager, teaManager, collectionManager, cfg.PublicBaseURL, logrusLogger.WithField(pkgKey, "labels")), authM.HTTPMiddleware)
 	teaManager.Start()
 	tagManager.Start()
+	notificationManager.Start()
 
 	if err = s.Run(); err != nil {
 		panic(err)
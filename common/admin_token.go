@@ -0,0 +1,12 @@
+package common
+
+import "time"
+
+// AdminTokenRevocation records a revoked admin JWT; it is kept until ExpiresAt, after which the
+// token is invalid anyway.
+type AdminTokenRevocation struct {
+	JTI       string
+	Reason    string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
@@ -19,4 +19,5 @@ type CollectionRecord struct {
 	Tea            *Tea
 	BowlingTemp    int
 	ExpirationDate time.Time
+	Owner          *uuid.UUID
 }
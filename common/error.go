@@ -26,4 +26,50 @@ var (
 	ErrUnauthorized = errors.New("unauthenticated")
 	// ErrNotAdmin indicates the authenticated principal lacks admin privileges.
 	ErrNotAdmin = errors.New("forbidden: admin required")
+	// ErrForbidden indicates the authenticated user lacks the role the operation requires.
+	ErrForbidden = errors.New("forbidden: role required")
+	// ErrInvalidRole indicates an unknown role.
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrTagNotFound indicates a requested tag (or tag synonym) does not exist.
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagCycle indicates a tag parent assignment would create a cycle in the tag hierarchy.
+	ErrTagCycle = errors.New("tag hierarchy cycle")
+	// ErrTagMergeSelf indicates an attempt to merge a tag into itself.
+	ErrTagMergeSelf = errors.New("cannot merge tag into itself")
+	// ErrTagSynonymExists indicates a tag synonym name that is already taken. Synonym names are unique
+	// across all categories.
+	ErrTagSynonymExists = errors.New("tag synonym already exists")
+	// ErrTeaNotFound indicates a requested tea does not exist.
+	ErrTeaNotFound = errors.New("tea not found")
+	// ErrTeaMergeSelf indicates an attempt to merge a tea into itself.
+	ErrTeaMergeSelf = errors.New("cannot merge tea into itself")
+	// ErrQRRecordForbidden indicates the caller neither owns the QR record nor is an admin.
+	ErrQRRecordForbidden = errors.New("qr record belongs to another user")
+	// ErrQRCodeNotIssued indicates the QR code was never issued in a printed batch.
+	ErrQRCodeNotIssued = errors.New("qr code not issued")
+	// ErrQRBatchNotFound indicates a requested QR batch does not exist.
+	ErrQRBatchNotFound = errors.New("qr batch not found")
+	// ErrNotificationNotFound indicates the notification does not exist or belongs to another user.
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrInvalidNotificationPreferences indicates an unknown time zone or a malformed time of day.
+	ErrInvalidNotificationPreferences = errors.New("invalid notification preferences")
+	// ErrInvalidNotificationRoute indicates an unknown channel or a target that does not fit it.
+	ErrInvalidNotificationRoute = errors.New("invalid notification route")
+	// ErrInvalidDeviceName indicates an empty or overly long device name.
+	ErrInvalidDeviceName = errors.New("invalid device name")
+	// ErrDeviceInUse indicates a sign-in on a device another user is still signed in on.
+	ErrDeviceInUse = errors.New("device is in use by another user")
+	// ErrSessionRevoked indicates the session's device was revoked or signed in with another user.
+	ErrSessionRevoked = errors.New("session revoked")
+	// ErrAdminTokenRevoked indicates an admin JWT that was revoked or already spent on a one-time-use
+	// operation.
+	ErrAdminTokenRevoked = errors.New("admin token revoked")
+	// ErrInvalidRefreshToken indicates an unknown, expired, revoked or already used refresh token.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrDevModeDisabled indicates a dev login while auth dev mode is off.
+	ErrDevModeDisabled = errors.New("dev mode disabled")
+	// ErrJobNotFound indicates no job with the given name is registered with the scheduler.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrInvalidVerificationCode indicates a wrong, expired or exhausted email verification code.
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
 )
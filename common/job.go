@@ -0,0 +1,39 @@
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobTrigger tells why a job ran.
+type JobTrigger int
+
+// Job triggers.
+const (
+	JobTriggerSchedule JobTrigger = iota
+	JobTriggerManual
+)
+
+// JobRunStatus is the state of a job run.
+type JobRunStatus int
+
+// Job run statuses: manual runs are pending until the leader picks them up.
+const (
+	JobRunStatusPending JobRunStatus = iota
+	JobRunStatusRunning
+	JobRunStatusSucceeded
+	JobRunStatusFailed
+)
+
+// JobRun is one execution of a scheduled job. Error is set for failed runs.
+type JobRun struct {
+	ID         uuid.UUID
+	Job        string
+	Trigger    JobTrigger
+	Status     JobRunStatus
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
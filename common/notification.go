@@ -5,7 +5,14 @@ package common
 
 //revive:enable:var-naming
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // NotificationType enumerates the kinds of notifications that can be sent to users.
 const (
@@ -18,14 +25,177 @@ const (
 // NotificationType is the domain-level enum of notification categories.
 type NotificationType int
 
-// Notification describes a notification event for a user.
+// NotificationEntityType identifies what a notification refers to.
+type NotificationEntityType int
+
+// Entities a notification can refer to.
+const (
+	NotificationEntityNone NotificationEntityType = iota
+	NotificationEntityQR
+	NotificationEntityTea
+)
+
+// NotificationChannel is a way of delivering notifications to a user.
+type NotificationChannel int
+
+// Notification channels.
+const (
+	NotificationChannelAPNs NotificationChannel = iota
+	NotificationChannelWebhook
+	NotificationChannelEmail
+	NotificationChannelInbox
+)
+
+var notificationChannelNames = map[NotificationChannel]string{
+	NotificationChannelAPNs:    "apns",
+	NotificationChannelWebhook: "webhook",
+	NotificationChannelEmail:   "email",
+	NotificationChannelInbox:   "inbox",
+}
+
+func (c NotificationChannel) String() string {
+	if name, ok := notificationChannelNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// ParseNotificationChannel returns the channel with the given name.
+func ParseNotificationChannel(name string) (NotificationChannel, error) {
+	for c, n := range notificationChannelNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationRoute, name)
+}
+
+// NotificationRoute sends a user's notifications over Channel. Target is the webhook URL or the
+// email address; APNs and the inbox need no target.
+type NotificationRoute struct {
+	Channel NotificationChannel
+	Target  string
+	// Verified is false for an email address that was not confirmed with the code mailed to it;
+	// nothing else is mailed to it until then. Routes over other channels are always verified.
+	Verified bool
+}
+
+// Validate checks that the target fits the channel. Webhooks must use https; the webhook channel
+// also refuses to connect to private addresses, as a host name can resolve to one later.
+func (r NotificationRoute) Validate() error {
+	switch r.Channel {
+	case NotificationChannelAPNs, NotificationChannelInbox:
+		if r.Target != "" {
+			return fmt.Errorf("%w: %s takes no target", ErrInvalidNotificationRoute, r.Channel)
+		}
+	case NotificationChannelWebhook:
+		u, err := url.Parse(r.Target)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+			return fmt.Errorf("%w: webhook target must be an https URL", ErrInvalidNotificationRoute)
+		}
+	case NotificationChannelEmail:
+		if _, err := mail.ParseAddress(r.Target); err != nil {
+			return fmt.Errorf("%w: invalid email address %q", ErrInvalidNotificationRoute, r.Target)
+		}
+	default:
+		return fmt.Errorf("%w: unknown channel", ErrInvalidNotificationRoute)
+	}
+
+	return nil
+}
+
+// DeliveryStatus is the push outcome for one device.
+type DeliveryStatus int
+
+// Delivery statuses.
+const (
+	DeliveryStatusSent DeliveryStatus = iota
+	DeliveryStatusFailed
+)
+
+// Notification describes a notification sent to a user. EntityID is set unless EntityType is
+// NotificationEntityNone.
 type Notification struct {
-	UserID uuid.UUID
-	Type   NotificationType
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Type       NotificationType
+	Title      string
+	Body       string
+	EntityType NotificationEntityType
+	EntityID   uuid.UUID
+	CreatedAt  time.Time
+	ReadAt     *time.Time
+	// DeliverAt is set while the push is deferred by the user's preferences.
+	DeliverAt  *time.Time
+	Deliveries []NotificationDelivery
+	// SourceKey names the event the notification is about, e.g. the day of a Tea of the Day push; a
+	// user gets one notification per key, so that a retried send does not add a second one.
+	SourceKey string
+}
+
+// DueNotification is a deferred notification claimed for its push; Attempt counts the claims,
+// starting at 1.
+type DueNotification struct {
+	Notification
+	Attempt int
+}
+
+// NotificationDelivery is the outcome of a notification on one channel target: a device for APNs,
+// the URL or address otherwise. DeviceID is set for APNs only.
+type NotificationDelivery struct {
+	Channel   NotificationChannel
+	Target    string
+	DeviceID  uuid.UUID
+	Status    DeliveryStatus
+	Reason    string
+	CreatedAt time.Time
 }
 
-// Device represents a user device eligible to receive push notifications.
+// DeviceEnvironment is the APNs environment a device token belongs to.
+type DeviceEnvironment int
+
+// Device environments: development builds get sandbox tokens, App Store and TestFlight builds
+// production ones.
+const (
+	DeviceEnvironmentSandbox DeviceEnvironment = iota
+	DeviceEnvironmentProduction
+)
+
+// DeviceInfo is the metadata the app reports about the device; empty fields are unknown.
+type DeviceInfo struct {
+	Platform   string
+	Model      string
+	AppVersion string
+}
+
+// Device represents a device a user signed in on. Token is empty until the app registers one for
+// push notifications.
 type Device struct {
-	UserID uuid.UUID
-	Token  string
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Token       string
+	Environment DeviceEnvironment
+	DeviceInfo
+	// Name is given by the user.
+	Name       string
+	LastSeenAt *time.Time
+	// BoundAt is when the device was signed in with its current user.
+	BoundAt time.Time
+}
+
+// ExpirationAlert records a delivered expiration alert: LeadDays before ExpirationDate of a record.
+type ExpirationAlert struct {
+	RecordID       uuid.UUID
+	LeadDays       int
+	ExpirationDate time.Time
+}
+
+// EmailVerification is the pending confirmation of an email route. CodeHash is the SHA-256 of the
+// code mailed at SentAt; Attempts counts the guesses at it, this one included.
+type EmailVerification struct {
+	CodeHash []byte
+	SentAt   time.Time
+	Attempts int
 }
@@ -0,0 +1,137 @@
+// Package common contains shared domain models used across the application.
+package common
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const minutesPerDay = 24 * 60
+
+// TimeOfDay is a wall-clock time as minutes after midnight.
+type TimeOfDay int
+
+// ParseTimeOfDay parses "HH:MM" (24-hour clock).
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time of day %q", ErrInvalidNotificationPreferences, s)
+	}
+
+	return TimeOfDay(t.Hour()*60 + t.Minute()), nil
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+// QuietHours is a daily window in which pushes are held back. End may be before Start, in which
+// case the window spans midnight.
+type QuietHours struct {
+	Start TimeOfDay
+	End   TimeOfDay
+}
+
+// Contains reports whether t falls inside the window.
+func (q QuietHours) Contains(t TimeOfDay) bool {
+	if q.Start <= q.End {
+		return t >= q.Start && t < q.End
+	}
+
+	return t >= q.Start || t < q.End
+}
+
+// NotificationPreferences controls which notifications a user gets and when they are pushed.
+type NotificationPreferences struct {
+	UserID uuid.UUID
+	// Timezone is an IANA zone name used for QuietHours and DeliveryTime.
+	Timezone string
+	// Disabled notification types are not sent at all.
+	Disabled   []NotificationType
+	QuietHours *QuietHours
+	// DeliveryTime, when set, holds notifications until the next occurrence of this time.
+	DeliveryTime *TimeOfDay
+	// TeaOfTheDayTime opts in to a daily Tea of the Day push at this time.
+	TeaOfTheDayTime *TimeOfDay
+}
+
+// DefaultNotificationPreferences returns the preferences of a user who never changed them:
+// everything enabled and delivered immediately.
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{UserID: userID, Timezone: time.UTC.String()}
+}
+
+// Validate checks the time zone and quiet hours. The time zone must be an IANA name: "" and "Local"
+// would make the schedule follow the server's zone.
+func (p *NotificationPreferences) Validate() error {
+	if p.Timezone == "" || p.Timezone == "Local" {
+		return fmt.Errorf("%w: time zone %q", ErrInvalidNotificationPreferences, p.Timezone)
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("%w: time zone %q", ErrInvalidNotificationPreferences, p.Timezone)
+	}
+
+	if p.QuietHours != nil && p.QuietHours.Start == p.QuietHours.End {
+		return fmt.Errorf("%w: quiet hours must not be empty", ErrInvalidNotificationPreferences)
+	}
+
+	times := []TimeOfDay{}
+	if p.DeliveryTime != nil {
+		times = append(times, *p.DeliveryTime)
+	}
+	if p.TeaOfTheDayTime != nil {
+		times = append(times, *p.TeaOfTheDayTime)
+	}
+	if p.QuietHours != nil {
+		times = append(times, p.QuietHours.Start, p.QuietHours.End)
+	}
+	for _, t := range times {
+		if t < 0 || t >= minutesPerDay {
+			return fmt.Errorf("%w: time of day out of range", ErrInvalidNotificationPreferences)
+		}
+	}
+
+	return nil
+}
+
+// Location returns the user's time zone, UTC if it cannot be loaded.
+func (p *NotificationPreferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Enabled reports whether notifications of type t should be sent.
+func (p *NotificationPreferences) Enabled(t NotificationType) bool {
+	return !slices.Contains(p.Disabled, t)
+}
+
+// Schedule returns when a notification created at now may be pushed: now itself, the next
+// delivery time, or the end of quiet hours, whichever applies.
+func (p *NotificationPreferences) Schedule(now time.Time) time.Time {
+	t := now.In(p.Location())
+	if p.DeliveryTime != nil {
+		t = nextOccurrence(t, *p.DeliveryTime)
+	}
+	if p.QuietHours != nil && p.QuietHours.Contains(TimeOfDay(t.Hour()*60+t.Minute())) {
+		t = nextOccurrence(t, p.QuietHours.End)
+	}
+
+	return t
+}
+
+// nextOccurrence returns the first moment at or after t with wall-clock time tod.
+func nextOccurrence(t time.Time, tod TimeOfDay) time.Time {
+	at := time.Date(t.Year(), t.Month(), t.Day(), int(tod)/60, int(tod)%60, 0, 0, t.Location())
+	if at.Before(t) {
+		at = time.Date(t.Year(), t.Month(), t.Day()+1, int(tod)/60, int(tod)%60, 0, 0, t.Location())
+	}
+
+	return at
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	tod, err := ParseTimeOfDay("07:30")
+	require.NoError(t, err)
+	assert.Equal(t, TimeOfDay(450), tod)
+	assert.Equal(t, "07:30", tod.String())
+
+	_, err = ParseTimeOfDay("25:00")
+	assert.ErrorIs(t, err, ErrInvalidNotificationPreferences)
+}
+
+func TestQuietHours_Contains(t *testing.T) {
+	night := QuietHours{Start: 22 * 60, End: 7 * 60}
+	assert.True(t, night.Contains(23*60))
+	assert.True(t, night.Contains(3*60))
+	assert.False(t, night.Contains(7*60))
+	assert.False(t, night.Contains(12*60))
+
+	lunch := QuietHours{Start: 12 * 60, End: 13 * 60}
+	assert.True(t, lunch.Contains(12*60+30))
+	assert.False(t, lunch.Contains(13*60))
+}
+
+func TestNotificationPreferences_Schedule(t *testing.T) {
+	now := time.Date(2024, 3, 10, 23, 15, 0, 0, time.UTC)
+	nine := TimeOfDay(9 * 60)
+
+	t.Run("immediate", func(t *testing.T) {
+		p := DefaultNotificationPreferences(uuid.New())
+		assert.True(t, p.Schedule(now).Equal(now))
+	})
+	t.Run("quiet hours", func(t *testing.T) {
+		p := DefaultNotificationPreferences(uuid.New())
+		p.QuietHours = &QuietHours{Start: 22 * 60, End: 7 * 60}
+		assert.True(t, p.Schedule(now).Equal(time.Date(2024, 3, 11, 7, 0, 0, 0, time.UTC)))
+	})
+	t.Run("delivery time", func(t *testing.T) {
+		p := DefaultNotificationPreferences(uuid.New())
+		p.DeliveryTime = &nine
+		assert.True(t, p.Schedule(now).Equal(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)))
+	})
+	t.Run("time zone", func(t *testing.T) {
+		p := DefaultNotificationPreferences(uuid.New())
+		p.Timezone = "Asia/Nicosia"
+		p.QuietHours = &QuietHours{Start: 22 * 60, End: 7 * 60}
+		// 23:15 UTC is 01:15 in Nicosia, quiet until 07:00 local (05:00 UTC).
+		assert.True(t, p.Schedule(now).Equal(time.Date(2024, 3, 11, 5, 0, 0, 0, time.UTC)))
+	})
+}
+
+func TestNotificationPreferences_Validate(t *testing.T) {
+	p := DefaultNotificationPreferences(uuid.New())
+	require.NoError(t, p.Validate())
+
+	for _, tz := range []string{"Mars/Olympus", "", "Local"} {
+		p.Timezone = tz
+		assert.ErrorIs(t, p.Validate(), ErrInvalidNotificationPreferences, tz)
+	}
+
+	p.Timezone = "UTC"
+	p.QuietHours = &QuietHours{Start: 60, End: 60}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidNotificationPreferences)
+}
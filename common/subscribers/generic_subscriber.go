@@ -51,13 +51,34 @@ func (s *subscribersImpl[T]) CleanDone() {
 	s.subs = s.subs[:len(s.subs)-len(forRemove)]
 }
 
-// SendAll sends a message to all subscribers
+// SendAll sends a message to all subscribers, skipping those whose context is done
 func (s *subscribersImpl[T]) SendAll(message T) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, el := range s.subs {
-		el.ch <- message
+		select {
+		case el.ch <- message:
+		case <-el.ctx.Done():
+		}
+	}
+}
+
+// offerAll sends a message to all subscribers without blocking: subscribers whose context is done
+// are skipped, and subscribers whose buffer is full miss the message rather than hold up the others
+func (s *subscribersImpl[T]) offerAll(message T) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, el := range s.subs {
+		if el.ctx.Err() != nil {
+			continue
+		}
+
+		select {
+		case el.ch <- message:
+		default:
+		}
 	}
 }
 
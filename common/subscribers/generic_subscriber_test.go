@@ -0,0 +1,46 @@
+package subscribers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribers_SendAll(t *testing.T) {
+	subs := NewSubscribers[int]()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gone, reader := make(chan int), make(chan int)
+	subs.Push(ctx, gone)
+	subs.Push(context.Background(), reader)
+
+	// SendAll waits for every subscriber: the message is not dropped for the slow reader, and the
+	// subscriber that went away only holds it up until its context is done.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	sent := make(chan struct{})
+	go func() {
+		subs.SendAll(1)
+		close(sent)
+	}()
+
+	select {
+	case got := <-reader:
+		assert.Equal(t, 1, got)
+	case <-time.After(time.Second):
+		t.Fatal("message dropped")
+	}
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("SendAll blocked on a finished subscriber")
+	}
+
+	subs.CleanDone()
+	_, open := <-gone
+	assert.False(t, open)
+}
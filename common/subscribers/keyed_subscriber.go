@@ -0,0 +1,87 @@
+package subscribers
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedSubscribers is the interface for managing subscribers of type T grouped by a key, e.g. the
+// sessions of one user
+type KeyedSubscribers[K comparable, T any] interface {
+	// Push adds a new subscriber for key
+	Push(ctx context.Context, key K, ch chan<- T)
+	// Send sends a message to the subscribers of key without blocking: a subscriber whose buffer is
+	// full misses the message, so one slow session can not hold up the others
+	Send(key K, message T)
+	// Has reports whether key has subscribers
+	Has(key K) bool
+	// CleanDone removes subscribers whose context is done
+	CleanDone()
+}
+
+// keyedSubscribersImpl is the implementation of KeyedSubscribers
+type keyedSubscribersImpl[K comparable, T any] struct {
+	mu   sync.Mutex
+	subs map[K]*subscribersImpl[T]
+}
+
+// Push adds a new subscriber for key
+func (s *keyedSubscribersImpl[K, T]) Push(ctx context.Context, key K, ch chan<- T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, ok := s.subs[key]
+	if !ok {
+		subs = &subscribersImpl[T]{subs: make([]Subscriber[T], 0, 1)}
+		s.subs[key] = subs
+	}
+
+	subs.Push(ctx, ch)
+}
+
+// Send sends a message to the subscribers of key
+func (s *keyedSubscribersImpl[K, T]) Send(key K, message T) {
+	s.mu.Lock()
+	subs := s.subs[key]
+	s.mu.Unlock()
+
+	if subs == nil {
+		return
+	}
+
+	subs.offerAll(message)
+	subs.CleanDone()
+}
+
+// Has reports whether key has subscribers
+func (s *keyedSubscribersImpl[K, T]) Has(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.subs[key]
+
+	return ok
+}
+
+// CleanDone removes subscribers whose context is done and keys without subscribers
+func (s *keyedSubscribersImpl[K, T]) CleanDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, subs := range s.subs {
+		subs.CleanDone()
+
+		subs.mu.RLock()
+		empty := len(subs.subs) == 0
+		subs.mu.RUnlock()
+
+		if empty {
+			delete(s.subs, key)
+		}
+	}
+}
+
+// NewKeyedSubscribers creates a new KeyedSubscribers instance
+func NewKeyedSubscribers[K comparable, T any]() KeyedSubscribers[K, T] {
+	return &keyedSubscribersImpl[K, T]{subs: make(map[K]*subscribersImpl[T])}
+}
@@ -0,0 +1,45 @@
+package subscribers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedSubscribers(t *testing.T) {
+	subs := NewKeyedSubscribers[string, int]()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	alice1, alice2, bob := make(chan int, 1), make(chan int, 1), make(chan int, 1)
+	subs.Push(ctx, "alice", alice1)
+	subs.Push(context.Background(), "alice", alice2)
+	subs.Push(context.Background(), "bob", bob)
+	assert.True(t, subs.Has("alice"))
+	assert.False(t, subs.Has("carol"))
+
+	subs.Send("alice", 1)
+	assert.Equal(t, 1, <-alice1)
+	assert.Equal(t, 1, <-alice2)
+	assert.Empty(t, bob)
+
+	subs.Send("carol", 2)
+
+	// Nobody reads alice1 any more: Send skips it once its context is done and removes it.
+	cancel()
+	subs.Send("alice", 3)
+	assert.Equal(t, 3, <-alice2)
+
+	_, open := <-alice1
+	assert.False(t, open, "done subscriber is removed and its channel closed")
+
+	// bob does not read: the second message is dropped instead of blocking Send.
+	subs.Send("bob", 4)
+	subs.Send("bob", 5)
+	assert.Equal(t, 4, <-bob)
+	assert.Empty(t, bob)
+
+	subs.CleanDone()
+	impl := subs.(*keyedSubscribersImpl[string, int]) //nolint:forcetypeassert // test of the implementation
+	assert.Len(t, impl.subs, 2)
+}
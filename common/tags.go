@@ -22,4 +22,18 @@ type TagData struct {
 	Name       string
 	Color      string
 	CategoryID uuid.UUID
+	// ParentID is the parent tag in the tag hierarchy; nil for top-level tags.
+	ParentID *uuid.UUID
+}
+
+// TeaTagsChange describes the tag assignments actually applied to a single tea by a bulk operation.
+type TeaTagsChange struct {
+	TeaID   uuid.UUID
+	Added   []uuid.UUID
+	Removed []uuid.UUID
+}
+
+// Empty reports whether the change did not add or remove any tag.
+func (c *TeaTagsChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
 }
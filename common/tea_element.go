@@ -71,4 +71,15 @@ type QR struct {
 	Tea            uuid.UUID
 	BowlingTemp    int
 	ExpirationDate time.Time
+	// Owner is the user that first wrote the record; nil while unowned.
+	Owner *uuid.UUID
+}
+
+// QRBatch is a printed set of QR codes together with its utilization.
+type QRBatch struct {
+	ID        uuid.UUID
+	PrintedBy string
+	CreatedAt time.Time
+	Total     int
+	Claimed   int
 }
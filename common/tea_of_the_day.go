@@ -0,0 +1,15 @@
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeaOfTheDay is the tea picked for a user on Day, a date in the user's time zone. Record is the
+// user's record of the tea that expires first.
+type TeaOfTheDay struct {
+	UserID uuid.UUID
+	Day    time.Time
+	Record *CollectionRecord
+}
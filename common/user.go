@@ -7,16 +7,37 @@ import (
 	"github.com/google/uuid"
 )
 
+// Role grants a user access beyond their own data; each role includes the ones before it.
+type Role int
+
+const (
+	// RoleUser is the role of every signed in user.
+	RoleUser Role = iota
+	// RoleCurator may edit tags and tag teas.
+	RoleCurator
+	// RoleAdmin may do everything the admin key can.
+	RoleAdmin
+)
+
 // User represents an authenticated TeaElephant user.
 type User struct {
 	ID      uuid.UUID
 	AppleID string
+	Role    Role
 	Session
 }
 
 // Session contains JWT and expiration metadata for a user session.
 type Session struct {
-	JWT       string
-	User      *User
+	// ID identifies the stored session; it is nil for tokens issued before sessions were stored.
+	ID   uuid.UUID
+	JWT  string
+	User *User
+	// DeviceID is the device the session was issued to; it is nil for sessions issued before
+	// devices were tracked.
+	DeviceID  uuid.UUID
 	ExpiredAt time.Time
+	// RefreshToken is only set when the session is issued or refreshed.
+	RefreshToken     string
+	RefreshExpiredAt time.Time
 }
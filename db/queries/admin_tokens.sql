@@ -0,0 +1,18 @@
+-- name: RevokeAdminToken :execrows
+-- Affects no rows if the token was already revoked.
+INSERT INTO admin_token_revocations (jti, reason, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: AdminTokenRevoked :one
+SELECT EXISTS (SELECT 1 FROM admin_token_revocations WHERE jti = $1);
+
+-- name: ListAdminTokenRevocations :many
+SELECT jti, reason, revoked_at, expires_at
+FROM admin_token_revocations
+WHERE expires_at > now()
+ORDER BY revoked_at DESC;
+
+-- name: DeleteExpiredAdminTokenRevocations :execrows
+DELETE FROM admin_token_revocations
+WHERE expires_at <= now();
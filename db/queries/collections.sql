@@ -35,7 +35,8 @@ SELECT
   t.type,
   t.description,
   q.boiling_temp,
-  q.expiration_date
+  q.expiration_date,
+  q.owner_id
 FROM collection_qr_items c
 JOIN qr_records q ON q.id = c.qr_id
 JOIN teas t ON t.id = q.tea_id
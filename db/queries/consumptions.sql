@@ -3,9 +3,9 @@ INSERT INTO consumptions (user_id, ts, tea_id)
 VALUES ($1, $2, $3)
 ON CONFLICT (user_id, ts, tea_id) DO NOTHING;
 
--- name: DeleteConsumptionsBefore :exec
+-- name: DeleteConsumptionsBefore :execrows
 DELETE FROM consumptions
-WHERE user_id = $1 AND ts < $2;
+WHERE ts < $1;
 
 -- name: ListConsumptionsSince :many
 SELECT ts, tea_id
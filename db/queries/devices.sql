@@ -1,15 +1,78 @@
--- name: InsertDevice :exec
-INSERT INTO devices (id, user_id, token)
-VALUES ($1, $2, $3)
-ON CONFLICT (id) DO NOTHING;
+-- name: BindDevice :execrows
+-- A device that signs in with another user is moved to it once the previous user has no live
+-- session on it, i.e. signed out or revoked it, dropping the previous user's token, name and
+-- sessions; otherwise no row is affected. $6 is the bind time on the clock that issues the tokens.
+-- Empty metadata keeps the stored value.
+INSERT INTO devices (id, user_id, platform, model, app_version, last_seen_at, bound_at)
+VALUES ($1, $2, $3, $4, $5, now(), $6)
+ON CONFLICT (id) DO UPDATE
+SET platform = COALESCE(NULLIF(EXCLUDED.platform, ''), devices.platform),
+    model = COALESCE(NULLIF(EXCLUDED.model, ''), devices.model),
+    app_version = COALESCE(NULLIF(EXCLUDED.app_version, ''), devices.app_version),
+    last_seen_at = now(),
+    token = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.token END,
+    name = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.name ELSE '' END,
+    bound_at = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.bound_at ELSE EXCLUDED.bound_at END,
+    user_id = EXCLUDED.user_id
+WHERE devices.user_id = EXCLUDED.user_id OR NOT EXISTS (
+  SELECT 1 FROM sessions s
+  WHERE s.device_id = devices.id AND s.user_id = devices.user_id
+    AND s.revoked_at IS NULL AND s.expires_at > now()
+);
 
--- name: UpdateDeviceToken :exec
+-- name: UpdateDeviceToken :execrows
+-- Empty metadata keeps the stored value.
 UPDATE devices
-SET token = $2
-WHERE id = $1;
+SET token = $3, environment = $4,
+    platform = COALESCE(NULLIF($5, ''), platform),
+    model = COALESCE(NULLIF($6, ''), model),
+    app_version = COALESCE(NULLIF($7, ''), app_version)
+WHERE id = $1 AND user_id = $2;
 
--- name: ListDeviceTokens :many
-SELECT token
+-- name: ClearDeviceToken :exec
+-- Only clears token if the app has not registered a new one meanwhile.
+UPDATE devices
+SET token = NULL
+WHERE id = $1 AND token = $2;
+
+-- name: SetDeviceEnvironment :exec
+-- Only moves the token if the app has not registered a new one meanwhile.
+UPDATE devices
+SET environment = $3
+WHERE id = $1 AND token = $2;
+
+-- name: ListUserDevices :many
+SELECT id, user_id, token, environment
 FROM devices
-WHERE user_id = $1
+WHERE user_id = $1 AND token IS NOT NULL
 ORDER BY created_at DESC;
+
+-- name: ListDevices :many
+SELECT id, user_id, COALESCE(token, '') AS token, environment, name, platform, model, app_version, last_seen_at, bound_at
+FROM devices
+WHERE user_id = $1
+ORDER BY last_seen_at DESC NULLS LAST, bound_at DESC;
+
+-- name: RenameDevice :one
+UPDATE devices
+SET name = $3
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, COALESCE(token, '') AS token, environment, name, platform, model, app_version, last_seen_at, bound_at;
+
+-- name: DeleteDevice :execrows
+DELETE FROM devices
+WHERE id = $1 AND user_id = $2;
+
+-- name: SeeDevice :one
+-- Reports whether a session issued at $3 to the device is still valid and refreshes last_seen_at
+-- at most once a minute.
+WITH seen AS (
+  UPDATE devices
+  SET last_seen_at = now()
+  WHERE id = $1 AND user_id = $2 AND date_trunc('second', bound_at) <= $3
+    AND (last_seen_at IS NULL OR last_seen_at < now() - interval '1 minute')
+)
+SELECT EXISTS (
+  SELECT 1 FROM devices
+  WHERE id = $1 AND user_id = $2 AND date_trunc('second', bound_at) <= $3
+);
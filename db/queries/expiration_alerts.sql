@@ -0,0 +1,17 @@
+-- name: ListExpirationAlerts :many
+SELECT user_id, qr_id, lead_days, expiration_date, sent_at
+FROM expiration_alerts
+WHERE user_id = $1;
+
+-- name: InsertExpirationAlerts :exec
+INSERT INTO expiration_alerts (user_id, qr_id, lead_days, expiration_date)
+SELECT $1, t.qr_id, t.lead_days, t.expiration_date
+FROM unnest($2::uuid[], $3::integer[], $4::timestamptz[]) AS t(qr_id, lead_days, expiration_date)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteStaleExpirationAlerts :execrows
+-- Alerts for an expiration date the record no longer has; the alerts for its current date keep
+-- them from being sent again and go with the record.
+DELETE FROM expiration_alerts a
+USING qr_records q
+WHERE a.qr_id = q.id AND a.expiration_date <> q.expiration_date;
@@ -0,0 +1,47 @@
+-- name: InsertJobRun :one
+INSERT INTO job_runs (id, job, trigger, status, started_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, job, trigger, status, error, created_at, started_at, finished_at;
+
+-- name: StartPendingJobRun :one
+-- Starts the oldest pending run of the job.
+UPDATE job_runs
+SET status = 1, started_at = now()
+WHERE id = (
+  SELECT id FROM job_runs
+  WHERE job = $1 AND status = 0
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, job, trigger, status, error, created_at, started_at, finished_at;
+
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET status = $2, error = $3, finished_at = now()
+WHERE id = $1;
+
+-- name: AbandonRunningJobRuns :execrows
+-- Fails the runs a previous leader left behind.
+UPDATE job_runs
+SET status = 3, error = 'interrupted', finished_at = now()
+WHERE status = 1;
+
+-- name: ListLastJobStarts :many
+SELECT job, max(started_at)::timestamptz AS started_at
+FROM job_runs
+WHERE started_at IS NOT NULL
+GROUP BY job;
+
+-- name: ListJobRuns :many
+SELECT id, job, trigger, status, error, created_at, started_at, finished_at
+FROM job_runs
+WHERE $1::text = '' OR job = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock($1);
@@ -0,0 +1,34 @@
+-- name: GetNotificationPreferences :one
+SELECT user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time
+FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: UpsertNotificationPreferences :exec
+INSERT INTO notification_preferences (user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+SET timezone = EXCLUDED.timezone,
+    quiet_start = EXCLUDED.quiet_start,
+    quiet_end = EXCLUDED.quiet_end,
+    delivery_time = EXCLUDED.delivery_time,
+    tea_of_the_day_time = EXCLUDED.tea_of_the_day_time,
+    updated_at = now();
+
+-- name: ListTeaOfTheDaySubscribers :many
+SELECT user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time
+FROM notification_preferences
+WHERE tea_of_the_day_time IS NOT NULL;
+
+-- name: ListDisabledNotificationTypes :many
+SELECT type
+FROM notification_disabled_types
+WHERE user_id = $1
+ORDER BY type;
+
+-- name: DeleteDisabledNotificationTypes :exec
+DELETE FROM notification_disabled_types WHERE user_id = $1;
+
+-- name: InsertDisabledNotificationTypes :exec
+INSERT INTO notification_disabled_types (user_id, type)
+SELECT $1, unnest($2::smallint[])
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,39 @@
+-- name: ListNotificationRoutes :many
+-- Only email routes need verification (channel 2).
+SELECT r.channel, r.target, (r.channel <> 2 OR v.verified_at IS NOT NULL)::boolean AS verified
+FROM notification_routes r
+LEFT JOIN notification_email_verifications v ON v.user_id = r.user_id AND v.address = r.target
+WHERE r.user_id = $1
+ORDER BY r.channel, r.target;
+
+-- name: DeleteNotificationRoutes :exec
+DELETE FROM notification_routes WHERE user_id = $1;
+
+-- name: InsertNotificationRoutes :exec
+INSERT INTO notification_routes (user_id, channel, target)
+SELECT $1, t.channel, t.target
+FROM unnest($2::smallint[], $3::text[]) AS t(channel, target)
+ON CONFLICT DO NOTHING;
+
+-- name: SetEmailVerificationCode :execrows
+-- Stores a new code for an address that is not verified yet, unless one was sent less than $4
+-- seconds ago.
+INSERT INTO notification_email_verifications (user_id, address, code_hash, code_sent_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (user_id, address) DO UPDATE
+SET code_hash = EXCLUDED.code_hash, code_sent_at = now(), attempts = 0
+WHERE notification_email_verifications.verified_at IS NULL
+  AND (notification_email_verifications.code_sent_at IS NULL
+    OR notification_email_verifications.code_sent_at <= now() - $4::int * interval '1 second');
+
+-- name: ClaimEmailVerificationAttempt :one
+-- Counts a guess at the pending code of an address that is not verified yet.
+UPDATE notification_email_verifications
+SET attempts = attempts + 1
+WHERE user_id = $1 AND address = $2 AND verified_at IS NULL AND code_hash IS NOT NULL
+RETURNING code_hash, code_sent_at, attempts;
+
+-- name: MarkEmailVerified :exec
+UPDATE notification_email_verifications
+SET verified_at = now(), code_hash = NULL
+WHERE user_id = $1 AND address = $2;
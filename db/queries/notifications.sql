@@ -1,5 +1,95 @@
+-- name: InsertNotification :one
+-- Returns no row if the user already has a notification with the source key $9.
+INSERT INTO notifications (id, user_id, type, title, body, entity_type, entity_id, deliver_at, source_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id, source_key) WHERE source_key IS NOT NULL DO NOTHING
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at;
+
+-- name: GetNotificationBySource :one
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM notifications
+WHERE user_id = $1 AND source_key = $2;
+
 -- name: ListNotifications :many
-SELECT id, user_id, type, created_at
+-- Newest first; $2 is the id of the last notification of the previous page, which must be the
+-- user's own. Deferred notifications appear once they are due.
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
 FROM notifications
 WHERE user_id = $1
-ORDER BY created_at DESC;
+  AND (deliver_at IS NULL OR deliver_at <= now())
+  AND ($2::uuid IS NULL OR (created_at, id) < (SELECT n.created_at, n.id FROM notifications n WHERE n.id = $2 AND n.user_id = $1))
+ORDER BY created_at DESC, id DESC
+LIMIT $3;
+
+-- name: ListUnreadNotifications :many
+-- The $2 newest unread notifications that are due, oldest first.
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM (
+  SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+  FROM notifications
+  WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now())
+  ORDER BY created_at DESC, id DESC
+  LIMIT $2
+) n
+ORDER BY created_at, id;
+
+-- name: CountUnreadNotifications :one
+SELECT count(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now());
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now());
+
+-- name: ClaimDueNotifications :many
+-- Claims up to $1 due notifications for $2 seconds so that concurrent dispatchers push each once.
+-- A notification whose push failed is claimed again once its claim ran out.
+UPDATE notifications
+SET push_claimed_until = now() + $2::int * interval '1 second',
+    push_attempts = push_attempts + 1
+WHERE id IN (
+  SELECT d.id FROM notifications d
+  WHERE d.deliver_at <= now()
+    AND (d.push_claimed_until IS NULL OR d.push_claimed_until <= now())
+  ORDER BY d.deliver_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at, push_attempts;
+
+-- name: CompleteDeferredNotification :exec
+-- The deferred push was sent or given up.
+UPDATE notifications
+SET deliver_at = NULL, push_claimed_until = NULL
+WHERE id = $1;
+
+-- name: GetNotification :one
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM notifications
+WHERE id = $1 AND user_id = $2;
+
+-- name: NotifyNotification :exec
+-- Announces a notification to the replicas listening on the channel $1.
+SELECT pg_notify($1, $2);
+
+-- name: InsertNotificationDeliveries :exec
+-- A nil device id is stored as NULL.
+INSERT INTO notification_deliveries (notification_id, channel, target, device_id, status, reason)
+SELECT $1, t.channel, t.target, NULLIF(t.device_id, '00000000-0000-0000-0000-000000000000'::uuid), t.status, t.reason
+FROM unnest($2::smallint[], $3::text[], $4::uuid[], $5::smallint[], $6::text[]) AS t(channel, target, device_id, status, reason)
+ON CONFLICT (notification_id, channel, target) DO UPDATE
+SET status = EXCLUDED.status, reason = EXCLUDED.reason, created_at = now();
+
+-- name: ListNotificationDeliveries :many
+SELECT notification_id, channel, target, device_id, status, reason, created_at
+FROM notification_deliveries
+WHERE notification_id = ANY($1::uuid[])
+ORDER BY created_at;
@@ -1,12 +1,51 @@
--- name: UpsertQR :exec
-INSERT INTO qr_records (id, tea_id, boiling_temp, expiration_date)
-VALUES ($1, $2, $3, $4)
+-- name: UpsertQR :execrows
+-- Inserts the record owned by $5 or rewrites it when it is unowned, owned by $5, or $6 (admin) is set.
+INSERT INTO qr_records (id, tea_id, boiling_temp, expiration_date, owner_id)
+VALUES ($1, $2, $3, $4, $5)
 ON CONFLICT (id) DO UPDATE
 SET tea_id = EXCLUDED.tea_id,
     boiling_temp = EXCLUDED.boiling_temp,
-    expiration_date = EXCLUDED.expiration_date;
+    expiration_date = EXCLUDED.expiration_date,
+    owner_id = COALESCE(qr_records.owner_id, EXCLUDED.owner_id)
+WHERE qr_records.owner_id IS NULL OR qr_records.owner_id = EXCLUDED.owner_id OR $6::boolean;
 
 -- name: GetQR :one
-SELECT id, tea_id, boiling_temp, expiration_date, created_at
+SELECT id, tea_id, boiling_temp, expiration_date, created_at, owner_id
 FROM qr_records
 WHERE id = $1;
+
+-- name: SetQROwner :execrows
+-- Sets owner_id to $2 when the record is owned by $3 or $4 (admin) is set.
+UPDATE qr_records
+SET owner_id = $2
+WHERE id = $1 AND (owner_id = $3 OR $4::boolean);
+
+-- name: InsertQRBatch :one
+INSERT INTO qr_batches (id, printed_by)
+VALUES ($1, $2)
+RETURNING id, printed_by, created_at;
+
+-- name: InsertQRBatchCodes :exec
+INSERT INTO qr_batch_codes (code, batch_id)
+SELECT x, $1
+FROM unnest($2::uuid[]) AS t(x);
+
+-- name: ListQRBatches :many
+SELECT b.id, b.printed_by, b.created_at, count(c.code) AS total, count(r.id) AS claimed
+FROM qr_batches b
+LEFT JOIN qr_batch_codes c ON c.batch_id = b.id
+LEFT JOIN qr_records r ON r.id = c.code
+GROUP BY b.id
+ORDER BY b.created_at DESC;
+
+-- name: GetQRBatch :one
+SELECT b.id, b.printed_by, b.created_at, count(c.code) AS total, count(r.id) AS claimed
+FROM qr_batches b
+LEFT JOIN qr_batch_codes c ON c.batch_id = b.id
+LEFT JOIN qr_records r ON r.id = c.code
+WHERE b.id = $1
+GROUP BY b.id;
+
+-- name: IsQRCodeIssued :one
+SELECT EXISTS (SELECT 1 FROM qr_batch_codes WHERE code = $1)
+    OR EXISTS (SELECT 1 FROM qr_records WHERE id = $1);
@@ -0,0 +1,59 @@
+-- name: InsertSession :exec
+-- $6 is the creation time on the clock that issues the tokens, as the device's bound_at.
+INSERT INTO sessions (id, user_id, device_id, refresh_token_hash, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: RotateSession :one
+-- Replaces the refresh token of a live session on the device it was issued to. Sessions of a device
+-- that was signed in with another user since are not live.
+UPDATE sessions s
+SET previous_token_hash = s.refresh_token_hash,
+    refresh_token_hash = $3,
+    refreshed_at = now(),
+    expires_at = $4
+FROM devices d
+WHERE s.refresh_token_hash = $1 AND s.device_id = $2
+  AND s.revoked_at IS NULL AND s.expires_at > now()
+  AND d.id = s.device_id AND d.user_id = s.user_id AND d.bound_at <= s.created_at
+RETURNING s.id, s.user_id, s.device_id;
+
+-- name: RevokeSessionByPreviousToken :execrows
+-- Spares a session refreshed in the last $2 seconds: concurrent refreshes with one token are not
+-- a replay.
+UPDATE sessions
+SET revoked_at = now()
+WHERE previous_token_hash = $1 AND revoked_at IS NULL
+  AND refreshed_at <= now() - $2::int * interval '1 second';
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: SeeSession :one
+-- Reports whether the session is live and refreshes last_seen_at of its device at most once a
+-- minute.
+WITH live AS (
+  SELECT s.device_id
+  FROM sessions s
+  JOIN devices d ON d.id = s.device_id AND d.user_id = s.user_id
+  WHERE s.id = $1 AND s.user_id = $2 AND s.revoked_at IS NULL AND d.bound_at <= s.created_at
+), seen AS (
+  UPDATE devices
+  SET last_seen_at = now()
+  WHERE id IN (SELECT device_id FROM live)
+    AND (last_seen_at IS NULL OR last_seen_at < now() - interval '1 minute')
+)
+SELECT EXISTS (SELECT 1 FROM live);
+
+-- name: DeleteEndedSessions :execrows
+-- Sessions that expired or were revoked more than $1 seconds ago, so that no access token issued
+-- for them is still valid either.
+DELETE FROM sessions
+WHERE expires_at <= now() - $1::int * interval '1 second'
+   OR revoked_at <= now() - $1::int * interval '1 second';
@@ -29,7 +29,7 @@ WHERE lower(name) LIKE lower($1) || '%'
 ORDER BY name ASC;
 
 -- name: ListTagsByCategory :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE category_id = $1
 ORDER BY name ASC;
@@ -41,50 +41,52 @@ WHERE category_id = $1;
 -- name: InsertTag :one
 INSERT INTO tags (id, name, color, category_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, name, color, category_id;
+RETURNING id, name, color, category_id, parent_id;
 
 -- name: UpdateTag :one
 UPDATE tags
 SET name = $2,
     color = $3
 WHERE id = $1
-RETURNING id, name, color, category_id;
+RETURNING id, name, color, category_id, parent_id;
 
 -- name: ChangeTagCategory :one
 UPDATE tags
 SET category_id = $2
 WHERE id = $1
-RETURNING id, name, color, category_id;
+RETURNING id, name, color, category_id, parent_id;
 
 -- name: DeleteTag :exec
 DELETE FROM tags WHERE id = $1;
 
 -- name: GetTag :one
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE id = $1;
 
 -- name: ListTags :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 ORDER BY name ASC;
 
 -- name: ListTagsByName :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE lower(name) LIKE lower($1) || '%'
+   OR id IN (SELECT tag_id FROM tag_synonyms WHERE lower(tag_synonyms.name) LIKE lower($1) || '%')
 ORDER BY name ASC;
 
 -- name: ListTagsByCategoryFilter :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE category_id = $1
 ORDER BY name ASC;
 
 -- name: ListTagsByNameCategory :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
-WHERE lower(name) LIKE lower($1) || '%'
+WHERE (lower(name) LIKE lower($1) || '%'
+       OR id IN (SELECT tag_id FROM tag_synonyms WHERE lower(tag_synonyms.name) LIKE lower($1) || '%'))
   AND category_id = $2
 ORDER BY name ASC;
 
@@ -97,8 +99,79 @@ ON CONFLICT (tea_id, tag_id) DO NOTHING;
 DELETE FROM tea_tags WHERE tea_id = $1 AND tag_id = $2;
 
 -- name: ListTagsByTea :many
-SELECT t.id, t.name, t.color, t.category_id
+SELECT t.id, t.name, t.color, t.category_id, t.parent_id
 FROM tea_tags tt
 JOIN tags t ON t.id = tt.tag_id
 WHERE tt.tea_id = $1
 ORDER BY t.name ASC;
+
+-- name: SetTagParent :one
+UPDATE tags
+SET parent_id = $2
+WHERE id = $1
+RETURNING id, name, color, category_id, parent_id;
+
+-- name: ListTagChildren :many
+SELECT id, name, color, category_id, parent_id
+FROM tags
+WHERE parent_id = $1
+ORDER BY name ASC;
+
+-- name: ListTagDescendantIDs :many
+WITH RECURSIVE descendants AS (
+  SELECT tags.id FROM tags WHERE tags.id = $1
+  UNION
+  SELECT t.id FROM tags t JOIN descendants d ON t.parent_id = d.id
+)
+SELECT id FROM descendants;
+
+-- name: InsertTagSynonym :execrows
+INSERT INTO tag_synonyms (tag_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteTagSynonym :exec
+DELETE FROM tag_synonyms
+WHERE tag_id = $1 AND lower(name) = lower($2);
+
+-- name: ListTagSynonyms :many
+SELECT name
+FROM tag_synonyms
+WHERE tag_id = $1
+ORDER BY name ASC;
+
+-- name: ResolveTagSynonym :one
+SELECT t.id, t.name, t.color, t.category_id, t.parent_id
+FROM tag_synonyms s
+JOIN tags t ON t.id = s.tag_id
+WHERE lower(s.name) = lower($1)
+  AND t.category_id = $2;
+
+-- name: MoveTeaTags :exec
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT tea_id, $2
+FROM tea_tags
+WHERE tag_id = $1
+ON CONFLICT (tea_id, tag_id) DO NOTHING;
+
+-- name: MoveTagChildren :exec
+UPDATE tags
+SET parent_id = $2
+WHERE parent_id = $1 AND id <> $2;
+
+-- name: MoveTagSynonyms :exec
+UPDATE tag_synonyms
+SET tag_id = $2
+WHERE tag_id = $1;
+
+-- name: InsertTeaTags :many
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT $1, x
+FROM unnest($2::uuid[]) AS t(x)
+ON CONFLICT (tea_id, tag_id) DO NOTHING
+RETURNING tag_id;
+
+-- name: DeleteTeaTags :many
+DELETE FROM tea_tags
+WHERE tea_id = $1 AND tag_id = ANY($2::uuid[])
+RETURNING tag_id;
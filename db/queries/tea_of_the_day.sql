@@ -0,0 +1,21 @@
+-- name: GetTeaOfTheDay :one
+SELECT qr_id
+FROM tea_of_the_day
+WHERE user_id = $1 AND day = $2;
+
+-- name: InsertTeaOfTheDay :one
+-- Keeps the first pick of the day and returns it.
+INSERT INTO tea_of_the_day (user_id, day, qr_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, day) DO UPDATE SET qr_id = tea_of_the_day.qr_id
+RETURNING qr_id;
+
+-- name: ClaimTeaOfTheDayNotification :execrows
+UPDATE tea_of_the_day
+SET notified_at = now()
+WHERE user_id = $1 AND day = $2 AND notified_at IS NULL;
+
+-- name: ReleaseTeaOfTheDayNotification :exec
+UPDATE tea_of_the_day
+SET notified_at = NULL
+WHERE user_id = $1 AND day = $2;
@@ -30,3 +30,42 @@ FROM teas
 WHERE lower(name) LIKE lower($1) || '%'
 ORDER BY name ASC
 LIMIT $2;
+
+-- name: ListTeasByTagTree :many
+WITH RECURSIVE descendants AS (
+  SELECT tags.id FROM tags WHERE tags.id = $1
+  UNION
+  SELECT t.id FROM tags t JOIN descendants d ON t.parent_id = d.id
+)
+SELECT DISTINCT te.id, te.name, te.type, te.description, te.created_at
+FROM teas te
+JOIN tea_tags tt ON tt.tea_id = te.id
+JOIN descendants d ON d.id = tt.tag_id
+WHERE lower(te.name) LIKE lower($2) || '%'
+ORDER BY te.name ASC;
+
+-- name: ListTeaTagPairs :many
+SELECT tea_id, tag_id
+FROM tea_tags;
+
+-- name: MoveQRRecordsToTea :exec
+UPDATE qr_records
+SET tea_id = $1
+WHERE tea_id = ANY($2::uuid[]);
+
+-- name: CopyTeaTagsToTea :exec
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT $1, tag_id
+FROM tea_tags
+WHERE tea_id = ANY($2::uuid[])
+ON CONFLICT (tea_id, tag_id) DO NOTHING;
+
+-- name: CopyConsumptionsToTea :exec
+INSERT INTO consumptions (user_id, ts, tea_id)
+SELECT user_id, ts, $1
+FROM consumptions
+WHERE tea_id = ANY($2::uuid[])
+ON CONFLICT (user_id, ts, tea_id) DO NOTHING;
+
+-- name: DeleteTeas :exec
+DELETE FROM teas WHERE id = ANY($1::uuid[]);
@@ -9,3 +9,9 @@ RETURNING id, apple_id, created_at;
 SELECT id, apple_id, created_at
 FROM users
 ORDER BY created_at DESC;
+
+-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users SET role = $2 WHERE id = $1;
@@ -6,6 +6,8 @@ CREATE TABLE IF NOT EXISTS users (
   apple_id text NOT NULL UNIQUE,
   created_at timestamptz NOT NULL DEFAULT now()
 );
+-- 0 = user, 1 = curator (edits tags), 2 = admin.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role smallint NOT NULL DEFAULT 0;
 
 CREATE TABLE IF NOT EXISTS teas (
   id uuid PRIMARY KEY,
@@ -29,6 +31,17 @@ CREATE TABLE IF NOT EXISTS tags (
 );
 CREATE UNIQUE INDEX IF NOT EXISTS tags_category_name_uq ON tags (category_id, lower(name));
 CREATE INDEX IF NOT EXISTS tags_category_idx ON tags (category_id);
+-- Optional parent for hierarchical tags (e.g. "Smoky" under "Roasted"). Deleting a parent detaches its children.
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS parent_id uuid REFERENCES tags(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS tags_parent_idx ON tags (parent_id);
+
+-- Alternative spellings that resolve to a canonical tag (e.g. "smoked" -> "Smoky").
+CREATE TABLE IF NOT EXISTS tag_synonyms (
+  tag_id uuid NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS tag_synonyms_name_uq ON tag_synonyms (lower(name));
+CREATE INDEX IF NOT EXISTS tag_synonyms_tag_idx ON tag_synonyms (tag_id);
 
 CREATE TABLE IF NOT EXISTS tea_tags (
   tea_id uuid NOT NULL REFERENCES teas(id) ON DELETE CASCADE,
@@ -49,6 +62,19 @@ CREATE INDEX IF NOT EXISTS qr_records_exp_idx ON qr_records (expiration_date);
 -- Likely filter criterion during brewing suggestions/search
 CREATE INDEX IF NOT EXISTS qr_records_boiling_temp_idx ON qr_records (boiling_temp);
 
+-- Printed QR code batches. Only codes issued in a batch (or already written before the registry existed) can be claimed.
+CREATE TABLE IF NOT EXISTS qr_batches (
+  id uuid PRIMARY KEY,
+  printed_by text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS qr_batch_codes (
+  code uuid PRIMARY KEY,
+  batch_id uuid NOT NULL REFERENCES qr_batches(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS qr_batch_codes_batch_idx ON qr_batch_codes (batch_id);
+
 CREATE TABLE IF NOT EXISTS collections (
   id uuid PRIMARY KEY,
   user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -64,6 +90,31 @@ CREATE TABLE IF NOT EXISTS collection_qr_items (
 );
 CREATE INDEX IF NOT EXISTS collection_qr_items_qr_idx ON collection_qr_items (qr_id);
 
+-- User that first wrote a QR record; only the owner (or an admin) may rewrite, release or transfer it.
+-- When the column is first added, existing records go to the user whose collections hold them (records
+-- in the collections of several users stay unowned); later runs must not re-claim released records.
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'qr_records' AND column_name = 'owner_id'
+  ) THEN
+    ALTER TABLE qr_records ADD COLUMN owner_id uuid REFERENCES users(id) ON DELETE SET NULL;
+    UPDATE qr_records q SET owner_id = o.user_id
+    FROM (
+      SELECT i.qr_id, min(c.user_id::text)::uuid AS user_id
+      FROM collection_qr_items i
+      JOIN collections c ON c.id = i.collection_id
+      GROUP BY i.qr_id
+      HAVING count(DISTINCT c.user_id) = 1
+    ) o
+    WHERE q.id = o.qr_id;
+  END IF;
+END $$;
+-- Repeated outside the DO block for sqlc, which does not read it.
+ALTER TABLE qr_records ADD COLUMN IF NOT EXISTS owner_id uuid REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS qr_records_owner_idx ON qr_records (owner_id);
+
 CREATE TABLE IF NOT EXISTS devices (
   id uuid PRIMARY KEY,
   user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -72,6 +123,20 @@ CREATE TABLE IF NOT EXISTS devices (
   UNIQUE (token)
 );
 CREATE INDEX IF NOT EXISTS devices_user_idx ON devices (user_id);
+-- token is NULL until the app registers one and after APNs reported it dead.
+ALTER TABLE devices ALTER COLUMN token DROP NOT NULL;
+-- Old app versions registered the device id as a placeholder token, which APNs rejects in both environments.
+UPDATE devices SET token = NULL WHERE token = id::text;
+-- APNs environment of the token: 0 = sandbox, 1 = production.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS environment smallint NOT NULL DEFAULT 0;
+-- Metadata reported by the app; name is set by the user.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS name text NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS platform text NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS model text NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS app_version text NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+-- Sessions issued to the device before it was bound to its current user are rejected.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS bound_at timestamptz NOT NULL DEFAULT now();
 
 CREATE TABLE IF NOT EXISTS notifications (
   id uuid PRIMARY KEY,
@@ -80,6 +145,82 @@ CREATE TABLE IF NOT EXISTS notifications (
   created_at timestamptz NOT NULL DEFAULT now()
 );
 CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON notifications (user_id, created_at DESC);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS title text NOT NULL DEFAULT '';
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS body text NOT NULL DEFAULT '';
+-- Related entity (1 = QR record, 2 = tea); both columns are NULL for digests.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS entity_type smallint;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS entity_id uuid;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read_at timestamptz;
+CREATE INDEX IF NOT EXISTS notifications_user_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+-- Set while the push is held back by quiet hours or the preferred delivery time.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS deliver_at timestamptz;
+CREATE INDEX IF NOT EXISTS notifications_deliver_at_idx ON notifications (deliver_at) WHERE deliver_at IS NOT NULL;
+-- deliver_at is cleared once the deferred push succeeded; a dispatcher claims it until
+-- push_claimed_until, so that a failed push is retried after the claim ran out.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS push_attempts smallint NOT NULL DEFAULT 0;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS push_claimed_until timestamptz;
+-- Event the notification is about; a retried send finds the notification of its event instead of
+-- adding another.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS source_key text;
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_user_source_idx ON notifications (user_id, source_key) WHERE source_key IS NOT NULL;
+
+-- Times of day are minutes after midnight in the user's time zone.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  timezone text NOT NULL DEFAULT 'UTC',
+  quiet_start smallint,
+  quiet_end smallint,
+  delivery_time smallint,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Opt-in time of the daily Tea of the Day push.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS tea_of_the_day_time smallint;
+
+CREATE TABLE IF NOT EXISTS notification_disabled_types (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type smallint NOT NULL,
+  PRIMARY KEY (user_id, type)
+);
+
+-- Push outcome of a notification per device (status 0 = sent, 1 = failed).
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+  notification_id uuid NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  status smallint NOT NULL,
+  reason text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (notification_id, device_id)
+);
+-- Deliveries are per channel target (channel 0 = APNs, 1 = webhook, 2 = email, 3 = inbox); device_id
+-- is set for APNs only.
+ALTER TABLE notification_deliveries ADD COLUMN IF NOT EXISTS channel smallint NOT NULL DEFAULT 0;
+ALTER TABLE notification_deliveries ADD COLUMN IF NOT EXISTS target text NOT NULL DEFAULT '';
+ALTER TABLE notification_deliveries DROP CONSTRAINT IF EXISTS notification_deliveries_pkey;
+ALTER TABLE notification_deliveries ALTER COLUMN device_id DROP NOT NULL;
+UPDATE notification_deliveries SET target = device_id::text WHERE target = '' AND device_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS notification_deliveries_target_idx ON notification_deliveries (notification_id, channel, target);
+
+-- Channels a user's notifications are delivered over; users without rows get the server default.
+CREATE TABLE IF NOT EXISTS notification_routes (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  channel smallint NOT NULL,
+  target text NOT NULL DEFAULT '',
+  PRIMARY KEY (user_id, channel, target)
+);
+
+-- Email addresses a user routed notifications to; nothing is mailed to an address before it was
+-- confirmed with the code mailed to it. code_hash is the SHA-256 of the pending code and attempts
+-- counts the wrong guesses at it.
+CREATE TABLE IF NOT EXISTS notification_email_verifications (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  address text NOT NULL,
+  code_hash bytea,
+  code_sent_at timestamptz,
+  attempts smallint NOT NULL DEFAULT 0,
+  verified_at timestamptz,
+  PRIMARY KEY (user_id, address)
+);
 
 CREATE TABLE IF NOT EXISTS consumptions (
   user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -88,3 +229,71 @@ CREATE TABLE IF NOT EXISTS consumptions (
   PRIMARY KEY (user_id, ts, tea_id)
 );
 CREATE INDEX IF NOT EXISTS consumptions_user_ts_desc_idx ON consumptions (user_id, ts DESC);
+
+-- Delivered expiration alerts: each lead time is pushed once per user, record and expiration date,
+-- so changing the date re-arms the alerts.
+CREATE TABLE IF NOT EXISTS expiration_alerts (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  qr_id uuid NOT NULL REFERENCES qr_records(id) ON DELETE CASCADE,
+  lead_days integer NOT NULL,
+  expiration_date timestamptz NOT NULL,
+  sent_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, qr_id, lead_days, expiration_date)
+);
+CREATE INDEX IF NOT EXISTS expiration_alerts_qr_idx ON expiration_alerts (qr_id);
+
+-- The tea picked for a user per day in the user's time zone; notified_at is set once it was pushed.
+CREATE TABLE IF NOT EXISTS tea_of_the_day (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day date NOT NULL,
+  qr_id uuid NOT NULL REFERENCES qr_records(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  notified_at timestamptz,
+  PRIMARY KEY (user_id, day)
+);
+
+-- History of scheduled and manually triggered background jobs; manual runs stay pending until the
+-- leader replica starts them.
+CREATE TABLE IF NOT EXISTS job_runs (
+  id uuid PRIMARY KEY,
+  job text NOT NULL,
+  trigger smallint NOT NULL,
+  status smallint NOT NULL,
+  error text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  started_at timestamptz,
+  finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS job_runs_job_created_idx ON job_runs (job, created_at DESC);
+CREATE INDEX IF NOT EXISTS job_runs_created_idx ON job_runs (created_at DESC);
+
+-- Revoking a device deletes it; its delivery history stays.
+ALTER TABLE notification_deliveries DROP CONSTRAINT IF EXISTS notification_deliveries_device_id_fkey;
+ALTER TABLE notification_deliveries ADD CONSTRAINT notification_deliveries_device_id_fkey
+  FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE SET NULL;
+
+-- Sign-ins of a user on a device. Only hashes of refresh tokens are stored; previous_token_hash is
+-- the hash of the token replaced by the last refresh, kept to detect reuse of a stolen token.
+CREATE TABLE IF NOT EXISTS sessions (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  refresh_token_hash bytea NOT NULL UNIQUE,
+  previous_token_hash bytea,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  refreshed_at timestamptz,
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_previous_token_idx ON sessions (previous_token_hash);
+
+-- Revoked admin JWTs by jti, kept until the token would have expired anyway. Tokens spent on a
+-- one-time-use operation are revoked with reason 'used'.
+CREATE TABLE IF NOT EXISTS admin_token_revocations (
+  jti text PRIMARY KEY,
+  reason text NOT NULL DEFAULT '',
+  revoked_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS admin_token_revocations_expires_idx ON admin_token_revocations (expires_at);
@@ -0,0 +1,28 @@
+package apns
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Configuration controls the alert payload and retries of transient APNs errors.
+type Configuration struct {
+	// Badge is set on the app icon; 0 clears it and a negative value leaves it unchanged.
+	Badge    int    `envconfig:"BADGE" default:"1"`
+	Category string `envconfig:"CATEGORY" default:"showCard"`
+	// Sound is the name of the alert sound, e.g. "default"; empty pushes are silent.
+	Sound         string        `envconfig:"SOUND"`
+	RetryAttempts int           `envconfig:"RETRY_ATTEMPTS" default:"3"`
+	RetryBackoff  time.Duration `envconfig:"RETRY_BACKOFF" default:"1s"`
+}
+
+// Config reads the configuration from APNS_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("APNS", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
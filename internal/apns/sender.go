@@ -4,78 +4,218 @@ package apns
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sideshow/apns2"
 	"github.com/sideshow/apns2/payload"
 	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
 )
 
 const apnsIDField = "apns id"
 
-// Sender delivers APNS notifications for a user's devices.
+// Sender delivers APNS notifications to a user's devices.
 type Sender interface {
-	Send(ctx context.Context, userID, itemID uuid.UUID, title, body string) error
+	// Deliver sends n to every device of n.UserID and reports the outcome per device; APNs routes
+	// have no target. A failing device does not stop delivery to the others; an error is returned
+	// only if the devices cannot be listed.
+	Deliver(ctx context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error)
+}
+
+// Client pushes to one APNs environment; *apns2.Client implements it.
+type Client interface {
+	PushWithContext(ctx apns2.Context, n *apns2.Notification) (*apns2.Response, error)
 }
 
-type userIDMapper interface {
-	MapUserIDToDeviceID(ctx context.Context, userID uuid.UUID) ([]string, error)
+type deviceStore interface {
+	UserDevices(ctx context.Context, userID uuid.UUID) ([]common.Device, error)
+	RemoveDeviceToken(ctx context.Context, deviceID uuid.UUID, token string) error
+	SetDeviceEnvironment(ctx context.Context, deviceID uuid.UUID, token string, env common.DeviceEnvironment) error
 }
 
 type sender struct {
-	client *apns2.Client
-	userIDMapper
+	clients map[common.DeviceEnvironment]Client
+	deviceStore
 
+	cfg   *Configuration
 	log   *logrus.Entry
 	topic string
 }
 
-func (s *sender) Send(ctx context.Context, userID, itemID uuid.UUID, title, body string) error {
-	deviceTokens, err := s.MapUserIDToDeviceID(ctx, userID)
+func (s *sender) Deliver(ctx context.Context, n *common.Notification, _ string) ([]common.NotificationDelivery, error) {
+	devices, err := s.UserDevices(ctx, n.UserID)
 	if err != nil {
-		return fmt.Errorf("map user id to device id: %w", err)
-	}
-
-	for _, device := range deviceTokens {
-		notification := &apns2.Notification{
-			DeviceToken: device,
-			Topic:       s.topic,
-			Expiration:  time.Now().Add(time.Minute * 15),
-			Payload: payload.NewPayload().
-				AlertTitle(title).
-				AlertBody(body).
-				Badge(1).
-				Category("showCard").
-				ThreadID(itemID.String()),
+		return nil, fmt.Errorf("list user devices: %w", err)
+	}
+
+	deliveries := make([]common.NotificationDelivery, 0, len(devices))
+	for _, device := range devices {
+		deliveries = append(deliveries, s.deliver(ctx, n, device))
+	}
+
+	return deliveries, nil
+}
+
+func (s *sender) deliver(ctx context.Context, n *common.Notification, device common.Device) common.NotificationDelivery {
+	delivery := common.NotificationDelivery{
+		Channel:   common.NotificationChannelAPNs,
+		Target:    device.ID.String(),
+		DeviceID:  device.ID,
+		Status:    common.DeliveryStatusSent,
+		CreatedAt: time.Now(),
+	}
+	log := s.log.WithField("device", device.ID)
+
+	client, ok := s.clients[device.Environment]
+	if !ok {
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, "no client for the device environment"
+		return delivery
+	}
+
+	notification := s.notification(n, device.Token)
+	res, err := s.push(ctx, client, notification)
+	var rejected bool
+	if err == nil && res.Reason == apns2.ReasonBadDeviceToken {
+		res, rejected = s.retryEnvironment(ctx, log, device, notification, res)
+	}
+
+	switch {
+	case err != nil:
+		log.WithError(err).Warn("push apns")
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, err.Error()
+	case res.Sent():
+		log.WithField(apnsIDField, res.ApnsID).Debug("Sent signal")
+	default:
+		log.WithField("status code", res.StatusCode).
+			WithField(apnsIDField, res.ApnsID).
+			WithField("reason", res.Reason).
+			Warn("Notification not Sent")
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, res.Reason
+
+		if res.Reason == apns2.ReasonUnregistered || rejected {
+			if err = s.RemoveDeviceToken(ctx, device.ID, device.Token); err != nil {
+				log.WithError(err).Error("remove dead device token")
+			}
 		}
+	}
 
-		res, err := s.client.PushWithContext(ctx, notification)
-		if err != nil {
-			return fmt.Errorf("push apns: %w", err)
+	return delivery
+}
+
+// retryEnvironment pushes through the other environment after APNs rejected the token as bad: the
+// app may have been installed from another build channel with a stale environment on record. When
+// the other environment knows the token, the stored environment is corrected and its response is
+// returned; otherwise res is, and rejected reports whether the other environment found the token bad
+// as well, so that it can never be delivered (like the placeholder tokens of old app versions).
+func (s *sender) retryEnvironment(
+	ctx context.Context, log *logrus.Entry, device common.Device, notification *apns2.Notification, res *apns2.Response,
+) (_ *apns2.Response, rejected bool) {
+	env := common.DeviceEnvironmentProduction
+	if device.Environment == common.DeviceEnvironmentProduction {
+		env = common.DeviceEnvironmentSandbox
+	}
+	client, ok := s.clients[env]
+	if !ok {
+		return res, false
+	}
+
+	other, err := s.push(ctx, client, notification)
+	if err != nil {
+		return res, false
+	}
+	if other.Reason == apns2.ReasonBadDeviceToken {
+		return res, true
+	}
+	if err = s.SetDeviceEnvironment(ctx, device.ID, device.Token, env); err != nil {
+		log.WithError(err).Error("update device environment")
+	}
+
+	return other, false
+}
+
+func (s *sender) notification(n *common.Notification, token string) *apns2.Notification {
+	thread := n.ID
+	if n.EntityType != common.NotificationEntityNone {
+		thread = n.EntityID
+	}
+
+	p := payload.NewPayload().
+		AlertTitle(n.Title).
+		AlertBody(n.Body).
+		ThreadID(thread.String()).
+		Custom("notificationID", n.ID.String())
+	switch {
+	case s.cfg.Badge > 0:
+		p.Badge(s.cfg.Badge)
+	case s.cfg.Badge == 0:
+		p.ZeroBadge()
+	}
+	if s.cfg.Category != "" {
+		p.Category(s.cfg.Category)
+	}
+	if s.cfg.Sound != "" {
+		p.SoundName(s.cfg.Sound)
+	}
+
+	return &apns2.Notification{
+		DeviceToken: token,
+		Topic:       s.topic,
+		Expiration:  time.Now().Add(time.Minute * 15),
+		Payload:     p,
+	}
+}
+
+// push sends the notification, retrying with exponential backoff while APNs reports a transient
+// error or cannot be reached.
+func (s *sender) push(ctx context.Context, client Client, notification *apns2.Notification) (*apns2.Response, error) {
+	var (
+		res *apns2.Response
+		err error
+	)
+	for attempt := range max(s.cfg.RetryAttempts, 1) {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(s.cfg.RetryBackoff << (attempt - 1)):
+			}
 		}
 
-		if res.Sent() {
-			s.log.WithField(apnsIDField, res.ApnsID).Debug("Sent signal")
-		} else {
-			s.log.
-				WithField("host", s.client.Host).
-				WithField("status code", res.StatusCode).
-				WithField(apnsIDField, res.ApnsID).
-				WithField("reason", res.Reason).
-				Warn("Notification not Sent")
+		res, err = client.PushWithContext(ctx, notification)
+		if !transient(res, err) {
+			break
 		}
 	}
 
-	return nil
+	return res, err
+}
+
+// transient reports whether a push may succeed when repeated: network errors, throttling and APNs
+// server errors.
+func transient(res *apns2.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+		return true
+	}
+
+	return false
 }
 
-// NewSender constructs a new APNS sender with a client, topic, mapper and logger.
-func NewSender(client *apns2.Client, topic string, mapper userIDMapper, log *logrus.Entry) Sender {
+// NewSender constructs a new APNS sender. Each device is pushed through the client of its
+// environment.
+func NewSender(clients map[common.DeviceEnvironment]Client, topic string, cfg *Configuration, devices deviceStore, log *logrus.Entry) Sender {
 	return &sender{
-		topic:        topic,
-		client:       client,
-		userIDMapper: mapper,
-		log:          log,
+		clients:     clients,
+		topic:       topic,
+		cfg:         cfg,
+		deviceStore: devices,
+		log:         log,
 	}
 }
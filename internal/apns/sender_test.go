@@ -0,0 +1,142 @@
+package apns
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sideshow/apns2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// fakeClient replies with responses in order and repeats the last one.
+type fakeClient struct {
+	responses []*apns2.Response
+	pushed    []*apns2.Notification
+}
+
+func (c *fakeClient) PushWithContext(_ apns2.Context, n *apns2.Notification) (*apns2.Response, error) {
+	c.pushed = append(c.pushed, n)
+	res := c.responses[min(len(c.pushed), len(c.responses))-1]
+
+	return res, nil
+}
+
+type fakeStore struct {
+	devices []common.Device
+	removed []string
+	moved   map[string]common.DeviceEnvironment
+}
+
+func (s *fakeStore) UserDevices(context.Context, uuid.UUID) ([]common.Device, error) {
+	return s.devices, nil
+}
+
+func (s *fakeStore) RemoveDeviceToken(_ context.Context, _ uuid.UUID, token string) error {
+	s.removed = append(s.removed, token)
+	return nil
+}
+
+func (s *fakeStore) SetDeviceEnvironment(_ context.Context, _ uuid.UUID, token string, env common.DeviceEnvironment) error {
+	if s.moved == nil {
+		s.moved = make(map[string]common.DeviceEnvironment)
+	}
+	s.moved[token] = env
+	return nil
+}
+
+func newTestSender(store *fakeStore, clients map[common.DeviceEnvironment]Client) Sender {
+	cfg := &Configuration{Badge: 1, Category: "showCard", RetryAttempts: 3}
+	return NewSender(clients, "topic", cfg, store, logrus.NewEntry(logrus.New()))
+}
+
+func TestSender_Deliver(t *testing.T) {
+	sent := &apns2.Response{StatusCode: http.StatusOK}
+	n := &common.Notification{ID: uuid.New(), UserID: uuid.New(), Title: "Tea expires soon"}
+
+	t.Run("environment", func(t *testing.T) {
+		sandbox, production := &fakeClient{responses: []*apns2.Response{sent}}, &fakeClient{responses: []*apns2.Response{sent}}
+		store := &fakeStore{devices: []common.Device{
+			{ID: uuid.New(), Token: "dev", Environment: common.DeviceEnvironmentSandbox},
+			{ID: uuid.New(), Token: "prod", Environment: common.DeviceEnvironmentProduction},
+		}}
+		s := newTestSender(store, map[common.DeviceEnvironment]Client{
+			common.DeviceEnvironmentSandbox:    sandbox,
+			common.DeviceEnvironmentProduction: production,
+		})
+
+		deliveries, err := s.Deliver(context.Background(), n, "")
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		require.Len(t, sandbox.pushed, 1)
+		require.Len(t, production.pushed, 1)
+		assert.Equal(t, "dev", sandbox.pushed[0].DeviceToken)
+		assert.Equal(t, "prod", production.pushed[0].DeviceToken)
+	})
+
+	t.Run("retry transient", func(t *testing.T) {
+		client := &fakeClient{responses: []*apns2.Response{
+			{StatusCode: http.StatusServiceUnavailable, Reason: apns2.ReasonServiceUnavailable},
+			{StatusCode: http.StatusTooManyRequests, Reason: apns2.ReasonTooManyRequests},
+			sent,
+		}}
+		store := &fakeStore{devices: []common.Device{{ID: uuid.New(), Token: "token"}}}
+		s := newTestSender(store, map[common.DeviceEnvironment]Client{common.DeviceEnvironmentSandbox: client})
+
+		deliveries, err := s.Deliver(context.Background(), n, "")
+		require.NoError(t, err)
+		assert.Len(t, client.pushed, 3)
+		assert.Equal(t, common.DeliveryStatusSent, deliveries[0].Status)
+	})
+
+	t.Run("prune dead token", func(t *testing.T) {
+		client := &fakeClient{responses: []*apns2.Response{{StatusCode: http.StatusGone, Reason: apns2.ReasonUnregistered}}}
+		store := &fakeStore{devices: []common.Device{{ID: uuid.New(), Token: "token"}}}
+		s := newTestSender(store, map[common.DeviceEnvironment]Client{common.DeviceEnvironmentSandbox: client})
+
+		deliveries, err := s.Deliver(context.Background(), n, "")
+		require.NoError(t, err)
+		assert.Len(t, client.pushed, 1)
+		assert.Equal(t, common.DeliveryStatusFailed, deliveries[0].Status)
+		assert.Equal(t, apns2.ReasonUnregistered, deliveries[0].Reason)
+		assert.Equal(t, []string{"token"}, store.removed)
+	})
+	t.Run("bad token in the stored environment", func(t *testing.T) {
+		bad := &apns2.Response{StatusCode: http.StatusBadRequest, Reason: apns2.ReasonBadDeviceToken}
+		sandbox, production := &fakeClient{responses: []*apns2.Response{bad}}, &fakeClient{responses: []*apns2.Response{sent}}
+		store := &fakeStore{devices: []common.Device{{ID: uuid.New(), Token: "token"}}}
+		s := newTestSender(store, map[common.DeviceEnvironment]Client{
+			common.DeviceEnvironmentSandbox:    sandbox,
+			common.DeviceEnvironmentProduction: production,
+		})
+
+		deliveries, err := s.Deliver(context.Background(), n, "")
+		require.NoError(t, err)
+		assert.Equal(t, common.DeliveryStatusSent, deliveries[0].Status)
+		assert.Len(t, production.pushed, 1)
+		assert.Equal(t, map[string]common.DeviceEnvironment{"token": common.DeviceEnvironmentProduction}, store.moved)
+		assert.Empty(t, store.removed)
+	})
+
+	t.Run("bad token in both environments", func(t *testing.T) {
+		bad := &apns2.Response{StatusCode: http.StatusBadRequest, Reason: apns2.ReasonBadDeviceToken}
+		sandbox, production := &fakeClient{responses: []*apns2.Response{bad}}, &fakeClient{responses: []*apns2.Response{bad}}
+		store := &fakeStore{devices: []common.Device{{ID: uuid.New(), Token: "token"}}}
+		s := newTestSender(store, map[common.DeviceEnvironment]Client{
+			common.DeviceEnvironmentSandbox:    sandbox,
+			common.DeviceEnvironmentProduction: production,
+		})
+
+		deliveries, err := s.Deliver(context.Background(), n, "")
+		require.NoError(t, err)
+		assert.Equal(t, common.DeliveryStatusFailed, deliveries[0].Status)
+		assert.Equal(t, apns2.ReasonBadDeviceToken, deliveries[0].Reason)
+		assert.Empty(t, store.moved)
+		assert.Equal(t, []string{"token"}, store.removed)
+	})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// AdminRevocationsPruneJobName is the scheduler job that deletes revocations of expired admin tokens.
+const AdminRevocationsPruneJobName = "adminTokenRevocations"
+
+// usedReason is the revocation reason of admin tokens spent on a one-time-use operation.
+const usedReason = "used"
+
+// RevokeAdminToken revokes the admin token with the jti on every replica, reporting false if it
+// already was. The revocation is kept as long as a token issued now could be valid.
+func (a *auth) RevokeAdminToken(ctx context.Context, jti, reason string) (bool, error) {
+	expiresAt := time.Now().UTC().Add(a.cfg.AdminMaxTokenLifetime + ClockSkewSeconds*time.Second)
+
+	revoked, err := a.storage.RevokeAdminToken(ctx, jti, reason, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("revoke admin token: %w", err)
+	}
+	if revoked {
+		a.log.WithField("jti", jti).WithField("reason", reason).Info("Admin token revoked")
+	}
+
+	return revoked, nil
+}
+
+// ConsumeAdminToken spends the admin token of the request when one-time tokens are enabled, so that
+// a replayed token cannot repeat a sensitive mutation. The token is revoked before the mutation
+// runs and stays spent if it fails. User sessions cannot be spent, so with one-time tokens these
+// mutations need an admin token even for users with the admin role.
+func (a *auth) ConsumeAdminToken(ctx context.Context) error {
+	if !a.cfg.AdminOneTimeTokens {
+		return nil
+	}
+
+	principal, ok := AdminPrincipalFrom(ctx)
+	if !ok {
+		return common.ErrUnauthorized
+	}
+
+	spent, err := a.storage.RevokeAdminToken(ctx, principal.JTI, usedReason,
+		principal.ExpiresAt.Add(ClockSkewSeconds*time.Second))
+	if err != nil {
+		return fmt.Errorf("spend admin token: %w", err)
+	}
+	if !spent {
+		a.log.WithField("jti", principal.JTI).Warn("Admin token replayed")
+		return common.ErrAdminTokenRevoked
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+type revocationStorage struct {
+	storage
+	revoked map[string]string
+}
+
+func (s *revocationStorage) RevokeAdminToken(_ context.Context, jti, reason string, _ time.Time) (bool, error) {
+	if _, ok := s.revoked[jti]; ok {
+		return false, nil
+	}
+	s.revoked[jti] = reason
+	return true, nil
+}
+
+func (s *revocationStorage) AdminTokenRevoked(_ context.Context, jti string) (bool, error) {
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+func newAdminAuth(t *testing.T, oneTime bool) (*auth, *ecdsa.PrivateKey) {
+	t.Helper()
+	key := newKey(t)
+	return &auth{
+		cfg:       &Configuration{AdminMaxTokenLifetime: time.Hour, AdminOneTimeTokens: oneTime},
+		adminKeys: map[string]*ecdsa.PublicKey{"": &key.PublicKey},
+		storage:   &revocationStorage{revoked: map[string]string{}},
+		log:       logrus.NewEntry(logrus.New()),
+	}, key
+}
+
+func adminToken(t *testing.T, key *ecdsa.PrivateKey, jti string, iat, exp time.Time) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"iss":         AdminIssuer,
+		"aud":         AdminAudience,
+		"iat":         iat.Unix(),
+		"exp":         exp.Unix(),
+		AdminClaimKey: true,
+	}
+	if jti != "" {
+		claims["jti"] = jti
+	}
+	signed, err := jwt.NewWithClaims(signingMethod, claims).SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestValidateAdmin(t *testing.T) {
+	ctx := context.Background()
+	a, key := newAdminAuth(t, false)
+	now := time.Now()
+
+	principal, err := a.ValidateAdmin(ctx, adminToken(t, key, "a", now, now.Add(24*time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "a", principal.JTI)
+	assert.WithinDuration(t, now.Add(time.Hour), principal.ExpiresAt, time.Second, "capped at the maximum lifetime")
+
+	_, err = a.ValidateAdmin(ctx, adminToken(t, key, "b", now.Add(-2*time.Hour), now.Add(time.Hour)))
+	require.ErrorIs(t, err, common.ErrExpiredToken)
+
+	_, err = a.ValidateAdmin(ctx, adminToken(t, key, "", now, now.Add(time.Hour)))
+	require.ErrorIs(t, err, common.ErrInvalidToken)
+
+	revoked, err := a.RevokeAdminToken(ctx, "a", "leaked")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = a.ValidateAdmin(ctx, adminToken(t, key, "a", now, now.Add(time.Hour)))
+	require.ErrorIs(t, err, common.ErrAdminTokenRevoked)
+
+	revoked, err = a.RevokeAdminToken(ctx, "a", "leaked")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestConsumeAdminToken(t *testing.T) {
+	now := time.Now()
+	principal := &AdminPrincipal{JTI: "a", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}
+	ctx := context.WithValue(context.Background(), adminCtxKey, principal)
+
+	a, _ := newAdminAuth(t, false)
+	require.NoError(t, a.ConsumeAdminToken(ctx))
+	require.NoError(t, a.ConsumeAdminToken(ctx), "tokens are reusable unless one-time tokens are enabled")
+
+	a, _ = newAdminAuth(t, true)
+	require.NoError(t, a.ConsumeAdminToken(ctx))
+	require.ErrorIs(t, a.ConsumeAdminToken(ctx), common.ErrAdminTokenRevoked)
+	require.ErrorIs(t, a.ConsumeAdminToken(context.Background()), common.ErrUnauthorized)
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAdminKeysEmpty indicates the admin keys source holds no keys.
+	ErrAdminKeysEmpty = errors.New("no admin keys")
+	// ErrAdminKeyInvalid indicates a JWKS entry that is not a P-256 EC key with a kid.
+	ErrAdminKeyInvalid = errors.New("invalid admin key")
+	// ErrAdminKeyDuplicate indicates two admin keys with the same kid.
+	ErrAdminKeyDuplicate = errors.New("duplicate admin key id")
+
+	errCoordinateLength = errors.New("coordinates are not 32 bytes")
+)
+
+const (
+	pemExt  = ".pem"
+	jwksExt = ".json"
+)
+
+// AdminKeyStatus describes the admin keys currently used to verify admin JWTs.
+type AdminKeyStatus struct {
+	Source string
+	// KeyIDs are sorted; the empty kid is the key of a single PEM file, used for tokens without kid
+	// (and listed again under ADMIN_KEY_ID, if set).
+	KeyIDs []string
+	// LoadedAt is when the keys last changed.
+	LoadedAt time.Time
+	// ReloadError is the error of the last reload, if it failed; the previous keys stay in use.
+	ReloadError string
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadAdminKeySet reads admin public keys by kid from path, which is either a single PEM file (kid
+// "" and singleKeyID, if set), a directory of PEM files named <kid>.pem or a JWKS file with a .json
+// extension.
+func loadAdminKeySet(path, singleKeyID string) (map[string]*ecdsa.PublicKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat admin keys: %w", err)
+	}
+
+	var keys map[string]*ecdsa.PublicKey
+	switch {
+	case info.IsDir():
+		keys, err = loadAdminKeyDir(path)
+	case strings.EqualFold(filepath.Ext(path), jwksExt):
+		keys, err = loadJWKSFile(path)
+	default:
+		var key *ecdsa.PublicKey
+		key, err = loadPublicKeyFromFile(path)
+		keys = map[string]*ecdsa.PublicKey{"": key}
+		if singleKeyID != "" {
+			keys[singleKeyID] = key
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrAdminKeysEmpty
+	}
+
+	return keys, nil
+}
+
+func loadAdminKeyDir(dir string) (map[string]*ecdsa.PublicKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read admin keys dir: %w", err)
+	}
+
+	keys := make(map[string]*ecdsa.PublicKey)
+	for _, e := range entries {
+		// Mounted secrets keep their data in hidden directories behind symlinks.
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), pemExt) {
+			continue
+		}
+
+		key, err := loadPublicKeyFromFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		keys[strings.TrimSuffix(name, filepath.Ext(name))] = key
+	}
+
+	return keys, nil
+}
+
+func loadJWKSFile(path string) (map[string]*ecdsa.PublicKey, error) {
+	// #nosec G304 -- path comes from trusted configuration (mounted secret)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read admin jwks: %w", err)
+	}
+
+	return parseJWKS(b)
+}
+
+// parseJWKS returns the signing keys of a JWKS document by kid.
+func parseJWKS(b []byte) (map[string]*ecdsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse admin jwks: %w", err)
+	}
+
+	keys := make(map[string]*ecdsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Kty != "EC" || k.Crv != "P-256" || k.Kid == "" {
+			return nil, fmt.Errorf("%w: kid %q", ErrAdminKeyInvalid, k.Kid)
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrAdminKeyDuplicate, k.Kid)
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: kid %q: %w", ErrAdminKeyInvalid, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("decode x: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("decode y: %w", err)
+	}
+
+	const coordLen = 32
+	if len(x) != coordLen || len(y) != coordLen {
+		return nil, errCoordinateLength
+	}
+
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, fmt.Errorf("parse point: %w", err)
+	}
+
+	return key, nil
+}
+
+// loadPublicKeyFromFile reads an ECDSA public key from a PEM file.
+func loadPublicKeyFromFile(path string) (*ecdsa.PublicKey, error) {
+	// #nosec G304 -- path comes from trusted configuration (mounted secret)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read admin public key: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrEmptyBlockDecode
+	}
+	pk, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse admin public key: %w", err)
+	}
+	ecdsaKey, ok := pk.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrAdminKeyNotECDSA
+	}
+	return ecdsaKey, nil
+}
+
+// sameKeys reports whether both sets hold the same keys under the same kids.
+func sameKeys(a, b map[string]*ecdsa.PublicKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for kid, ka := range a {
+		kb, ok := b[kid]
+		if !ok || !ka.Equal(kb) {
+			return false
+		}
+	}
+	return true
+}
+
+func keyIDs(keys map[string]*ecdsa.PublicKey) []string {
+	ids := make([]string, 0, len(keys))
+	for kid := range keys {
+		ids = append(ids, kid)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func writePEM(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+}
+
+func toJWK(t *testing.T, kid string, key *ecdsa.PrivateKey) jwk {
+	t.Helper()
+	point, err := key.PublicKey.Bytes()
+	require.NoError(t, err)
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(point[1:33]),
+		Y:   base64.RawURLEncoding.EncodeToString(point[33:]),
+	}
+}
+
+func TestLoadAdminKeySet(t *testing.T) {
+	v1, v2 := newKey(t), newKey(t)
+
+	t.Run("single file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "admin_public_key.pem")
+		writePEM(t, path, v1)
+
+		keys, err := loadAdminKeySet(path, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, keyIDs(keys))
+		assert.True(t, keys[""].Equal(&v1.PublicKey))
+
+		keys, err = loadAdminKeySet(path, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "v1"}, keyIDs(keys))
+		assert.True(t, keys["v1"].Equal(&v1.PublicKey))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writePEM(t, filepath.Join(dir, "admin-key-v1.pem"), v1)
+		writePEM(t, filepath.Join(dir, "admin-key-v2.pem"), v2)
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0o600))
+
+		keys, err := loadAdminKeySet(dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admin-key-v1", "admin-key-v2"}, keyIDs(keys))
+		assert.True(t, keys["admin-key-v2"].Equal(&v2.PublicKey))
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		enc := toJWK(t, "v2", v2)
+		enc.Use = "enc"
+		b, err := json.Marshal(jwks{Keys: []jwk{toJWK(t, "v1", v1), toJWK(t, "v2", v2), enc}})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, b, 0o600))
+
+		keys, err := loadAdminKeySet(path, "v3")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1", "v2"}, keyIDs(keys))
+		assert.True(t, keys["v1"].Equal(&v1.PublicKey))
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		_, err := loadAdminKeySet(t.TempDir(), "")
+		require.ErrorIs(t, err, ErrAdminKeysEmpty)
+	})
+}
+
+func TestParseJWKSRejects(t *testing.T) {
+	v1 := newKey(t)
+
+	noKid := toJWK(t, "", v1)
+	b, err := json.Marshal(jwks{Keys: []jwk{noKid}})
+	require.NoError(t, err)
+	_, err = parseJWKS(b)
+	require.ErrorIs(t, err, ErrAdminKeyInvalid)
+
+	b, err = json.Marshal(jwks{Keys: []jwk{toJWK(t, "v1", v1), toJWK(t, "v1", v1)}})
+	require.NoError(t, err)
+	_, err = parseJWKS(b)
+	require.ErrorIs(t, err, ErrAdminKeyDuplicate)
+
+	offCurve := toJWK(t, "v1", v1)
+	offCurve.Y = offCurve.X
+	b, err = json.Marshal(jwks{Keys: []jwk{offCurve}})
+	require.NoError(t, err)
+	_, err = parseJWKS(b)
+	require.ErrorIs(t, err, ErrAdminKeyInvalid)
+}
+
+func TestAdminVerificationKeyRejectsUnknownKid(t *testing.T) {
+	v1, def := newKey(t), newKey(t)
+	a := &auth{
+		adminKeys: map[string]*ecdsa.PublicKey{"": &def.PublicKey, "v1": &v1.PublicKey},
+		log:       logrus.NewEntry(logrus.New()),
+	}
+
+	token := func(kid string) *jwt.Token {
+		tok := jwt.New(signingMethod)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		return tok
+	}
+
+	key, err := a.adminVerificationKey(token("v1"))
+	require.NoError(t, err)
+	assert.Same(t, &v1.PublicKey, key)
+
+	key, err = a.adminVerificationKey(token(""))
+	require.NoError(t, err)
+	assert.Same(t, &def.PublicKey, key)
+
+	_, err = a.adminVerificationKey(token("v2"))
+	require.ErrorIs(t, err, ErrAdminVerificationKeyAbsent)
+}
+
+func TestWatchAdminKeys(t *testing.T) {
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "v1.pem"), newKey(t))
+	newWatchAuth := func(interval time.Duration) Auth {
+		return NewAuth(&Configuration{AdminKeysPath: dir, AdminKeysReloadInterval: interval}, nil, nil,
+			logrus.NewEntry(logrus.New()))
+	}
+
+	t.Run("failed reload keeps the keys", func(t *testing.T) {
+		a := newWatchAuth(10 * time.Millisecond)
+		require.NoError(t, a.Start())
+		defer func() { require.NoError(t, a.Stop()) }()
+		loaded := a.AdminKeys()
+		require.Equal(t, []string{"v1"}, loaded.KeyIDs)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "v2.pem"), []byte("not a key"), 0o600))
+		defer os.Remove(filepath.Join(dir, "v2.pem"))
+		require.Eventually(t, func() bool { return a.AdminKeys().ReloadError != "" }, time.Second, 10*time.Millisecond)
+
+		status := a.AdminKeys()
+		assert.Equal(t, []string{"v1"}, status.KeyIDs)
+		assert.Equal(t, loaded.LoadedAt, status.LoadedAt)
+	})
+
+	t.Run("zero interval disables the reload", func(t *testing.T) {
+		a := newWatchAuth(0)
+		require.NoError(t, a.Start())
+		require.NoError(t, a.Stop())
+		require.NoError(t, a.Stop(), "stop is idempotent")
+	})
+
+	t.Run("negative interval", func(t *testing.T) {
+		require.Error(t, newWatchAuth(-time.Second).Start())
+	})
+}
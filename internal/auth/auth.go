@@ -10,14 +10,13 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
-	"github.com/Timothylock/go-signin-with-apple/apple"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -31,7 +30,7 @@ var (
 	ErrAppleAuth                  = errors.New("apple authentication failed")
 	ErrEmptyBlockDecode           = errors.New("empty block after decoding")
 	ErrAdminKeyNotECDSA           = errors.New("admin public key is not ECDSA")
-	ErrAdminVerificationKeyAbsent = errors.New("admin verification key not loaded")
+	ErrAdminVerificationKeyAbsent = errors.New("unknown admin key id")
 )
 
 type ctxKey string
@@ -64,7 +63,9 @@ const (
 
 // AdminPrincipal represents an authenticated admin session
 type AdminPrincipal struct {
-	JTI       string
+	JTI string
+	// KeyID is the kid of the key the token was verified with.
+	KeyID     string
 	IssuedAt  time.Time
 	ExpiresAt time.Time
 }
@@ -75,31 +76,81 @@ const adminCtxKey ctxKey = "adminPrincipal"
 // Auth defines the authentication operations for issuing and validating JWTs
 // and providing GraphQL middleware support.
 type Auth interface {
-	Auth(ctx context.Context, token string) (*common.Session, error)
+	Auth(ctx context.Context, token string, deviceID uuid.UUID, device common.DeviceInfo) (*common.Session, error)
+	RefreshSession(ctx context.Context, refreshToken string, deviceID uuid.UUID) (*common.Session, error)
+	Logout(ctx context.Context, user *common.User) error
+	LogoutEverywhere(ctx context.Context, userID uuid.UUID) (int, error)
+	PruneSessions(ctx context.Context) (int64, error)
 	Validate(ctx context.Context, jwt string) (*common.User, error)
 	Middleware() graphql.HandlerExtension
 	WsInitFunc(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error)
+	HTTPMiddleware(next http.Handler) http.Handler
+	AdminKeys() AdminKeyStatus
+	RevokeAdminToken(ctx context.Context, jti, reason string) (bool, error)
+	ConsumeAdminToken(ctx context.Context) error
+	AdminTokenRevocations(ctx context.Context) ([]common.AdminTokenRevocation, error)
+	PruneAdminTokenRevocations(ctx context.Context) (int64, error)
+	SetUserRole(ctx context.Context, userID uuid.UUID, role common.Role) error
+	DevLogin(ctx context.Context, name string, deviceID uuid.UUID, role *common.Role, admin bool) (*DevSession, error)
 	Start() error
+	Stop() error
 }
 
 type storage interface {
 	GetOrCreateUser(ctx context.Context, unique string) (uuid.UUID, error)
+	UserRole(ctx context.Context, userID uuid.UUID) (common.Role, error)
+	SetUserRole(ctx context.Context, userID uuid.UUID, role common.Role) error
+	BindDevice(ctx context.Context, userID, deviceID uuid.UUID, info common.DeviceInfo, boundAt time.Time) error
+	DeviceSessionValid(ctx context.Context, userID, deviceID uuid.UUID, issuedAt time.Time) (bool, error)
+	CreateSession(
+		ctx context.Context, id, userID, deviceID uuid.UUID, refreshTokenHash []byte, createdAt, expiresAt time.Time,
+	) error
+	RotateSession(
+		ctx context.Context, deviceID uuid.UUID, refreshTokenHash, newRefreshTokenHash []byte, expiresAt time.Time,
+	) (*common.Session, error)
+	RevokeReusedSession(ctx context.Context, refreshTokenHash []byte, grace time.Duration) (bool, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int, error)
+	SessionValid(ctx context.Context, userID, sessionID uuid.UUID) (bool, error)
+	RevokeAdminToken(ctx context.Context, jti, reason string, expiresAt time.Time) (bool, error)
+	AdminTokenRevoked(ctx context.Context, jti string) (bool, error)
+	AdminTokenRevocations(ctx context.Context) ([]common.AdminTokenRevocation, error)
+	PruneAdminTokenRevocations(ctx context.Context) (int64, error)
+	PruneSessions(ctx context.Context, keep time.Duration) (int64, error)
 }
 
-type auth struct {
-	appleClient *apple.Client
-	cfg         *Configuration
-	secret      string
+// userClaims are the claims of user JWTs; SessionID is empty in tokens issued before sessions were
+// stored and DeviceID in tokens issued before devices were tracked.
+type userClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	DeviceID  string `json:"did,omitempty"`
+}
 
-	// cached admin public keys by kid (empty kid = default)
-	adminKeys      map[string]*ecdsa.PublicKey
-	adminKeysMutex sync.RWMutex
+type auth struct {
+	provider Provider
+	cfg      *Configuration
+
+	// admin public keys by kid (empty kid = key of a single PEM file), reloaded by watchAdminKeys
+	adminKeys         map[string]*ecdsa.PublicKey
+	adminKeysLoadedAt time.Time
+	adminKeysErr      error
+	adminKeysMutex    sync.RWMutex
+	// devAdminKey signs the admin tokens of devLogin in dev mode.
+	devAdminKey *ecdsa.PrivateKey
+	// stop ends watchAdminKeys.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// roles caches the user roles read by Validate for RoleCacheTTL.
+	roles      map[uuid.UUID]cachedRole
+	rolesMutex sync.Mutex
 
 	storage
 	log *logrus.Entry
 }
 
-func (a *auth) Validate(_ context.Context, jwtToken string) (*common.User, error) {
+func (a *auth) Validate(ctx context.Context, jwtToken string) (*common.User, error) {
 	result, err := jwt.Parse(jwtToken, a.verificationKey)
 	if err != nil {
 		return nil, fmt.Errorf("parse jwt: %w", err)
@@ -115,16 +166,94 @@ func (a *auth) Validate(_ context.Context, jwtToken string) (*common.User, error
 		return nil, err
 	}
 
+	sessionID, err := a.validateSession(ctx, userID, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceID, err := a.validateDevice(ctx, userID, sessionID, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// The role is not in the token so that a demotion applies within RoleCacheTTL.
+	role, err := a.userRole(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user role: %w", err)
+	}
+
 	return &common.User{
 		// todo read from storage full user
-		ID: userID,
+		ID:   userID,
+		Role: role,
 		Session: common.Session{
+			ID:        sessionID,
 			JWT:       jwtToken,
+			DeviceID:  deviceID,
 			ExpiredAt: exp,
 		},
 	}, nil
 }
 
+// validateSession checks that the session of the token was not revoked. Tokens without a session
+// are accepted until they expire.
+func (a *auth) validateSession(ctx context.Context, userID uuid.UUID, claims jwt.MapClaims) (uuid.UUID, error) {
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		return uuid.Nil, nil
+	}
+
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse session id: %w", err)
+	}
+
+	valid, err := a.SessionValid(ctx, userID, sessionID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("check session: %w", err)
+	}
+	if !valid {
+		return uuid.Nil, common.ErrSessionRevoked
+	}
+
+	return sessionID, nil
+}
+
+// validateDevice checks that the device the token was issued to was not revoked or signed in with
+// another user since. The session check already covers this for tokens with a session; tokens
+// without a device are accepted until they expire.
+func (a *auth) validateDevice(
+	ctx context.Context, userID, sessionID uuid.UUID, claims jwt.MapClaims,
+) (uuid.UUID, error) {
+	did, _ := claims["did"].(string)
+	if did == "" {
+		return uuid.Nil, nil
+	}
+
+	deviceID, err := uuid.Parse(did)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse device id: %w", err)
+	}
+	if sessionID != uuid.Nil {
+		return deviceID, nil
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return uuid.Nil, common.ErrInvalidToken
+	}
+
+	valid, err := a.DeviceSessionValid(ctx, userID, deviceID, issuedAt.Time)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("check device session: %w", err)
+	}
+	if !valid {
+		return uuid.Nil, common.ErrSessionRevoked
+	}
+
+	return deviceID, nil
+}
+
 // verificationKey validates the signing method and returns the appropriate key for verification
 func (a *auth) verificationKey(token *jwt.Token) (interface{}, error) {
 	if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
@@ -175,83 +304,60 @@ func issuerUUID(claims jwt.MapClaims) (uuid.UUID, error) {
 	return userID, nil
 }
 
-func (a *auth) Start() (err error) {
-	a.secret, err = apple.GenerateClientSecret(a.cfg.Secret, a.cfg.TeamID, a.cfg.ClientID, a.cfg.KeyID)
-	if err != nil {
-		return fmt.Errorf("generate apple client secret: %w", err)
+func (a *auth) Start() error {
+	if a.cfg.DevMode {
+		return a.startDevMode()
 	}
 
-	// preload admin public key(s)
-	if err := a.loadAdminKeys(); err != nil {
+	if a.cfg.AdminKeysReloadInterval < 0 {
+		return fmt.Errorf("negative admin keys reload interval %s", a.cfg.AdminKeysReloadInterval)
+	}
+	if err := a.reloadAdminKeys(); err != nil {
 		return fmt.Errorf("load admin keys: %w", err)
 	}
+	// A zero interval disables the reload.
+	if a.cfg.AdminKeysReloadInterval > 0 {
+		go a.watchAdminKeys()
+	}
 
 	return nil
 }
 
-func (a *auth) Auth(ctx context.Context, token string) (*common.Session, error) {
-	vReq := apple.AppValidationTokenRequest{
-		ClientID:     a.cfg.ClientID,
-		ClientSecret: a.secret,
-		Code:         token,
-	}
-
-	var resp apple.ValidationResponse
-
-	// Do the verification
-	if err := a.appleClient.VerifyAppToken(ctx, vReq, &resp); err != nil {
-		return nil, fmt.Errorf("verify apple app token: %w", err)
-	}
-
-	if resp.Error != "" {
-		return nil, fmt.Errorf("%w: %s", ErrAppleAuth, resp.ErrorDescription)
-	}
+// Stop ends the periodic reload of the admin keys.
+func (a *auth) Stop() error {
+	a.stopOnce.Do(func() { close(a.stop) })
+	return nil
+}
 
-	claims, err := apple.GetClaims(resp.IDToken)
+// Auth signs the user in with a code of the identity provider on the device and starts a session
+// bound to the device.
+func (a *auth) Auth(ctx context.Context, token string, deviceID uuid.UUID, device common.DeviceInfo) (*common.Session, error) {
+	unique, err := a.provider.Identify(ctx, token)
 	if err != nil {
-		return nil, fmt.Errorf("get claims: %w", err)
+		return nil, fmt.Errorf("identify: %w", err)
 	}
 
-	a.log.Info(claims)
-
-	unique, err := apple.GetUniqueID(resp.IDToken)
-	if err != nil {
-		return nil, fmt.Errorf("get unique id: %w", err)
-	}
+	return a.signIn(ctx, unique, deviceID, device)
+}
 
+// signIn starts a session of the user with the unique id of the identity provider on the device.
+func (a *auth) signIn(ctx context.Context, unique string, deviceID uuid.UUID, device common.DeviceInfo) (*common.Session, error) {
 	user, err := a.GetOrCreateUser(ctx, unique)
 	if err != nil {
 		return nil, fmt.Errorf("get or create user: %w", err)
 	}
 
-	exp := time.Now().Add(time.Hour * JwtDurationHour).UTC()
-
-	newClaims := &jwt.RegisteredClaims{
-		Issuer:    user.String(),
-		ExpiresAt: jwt.NewNumericDate(exp),
-		ID:        uuid.New().String(),
+	// The device is bound before the session starts, so the session is not older than the binding.
+	// Both times come from this clock, as does the iat the binding is compared with.
+	if err = a.BindDevice(ctx, user, deviceID, device, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("bind device: %w", err)
 	}
 
-	jwtToken := jwt.NewWithClaims(signingMethod, newClaims)
-
-	privKey, err := a.getSecret()
+	session, err := a.startSession(ctx, user, deviceID)
 	if err != nil {
-		return nil, fmt.Errorf(getSecretWrapF, err)
-	}
-
-	signedJWT, err := jwtToken.SignedString(privKey)
-	if err != nil {
-		return nil, fmt.Errorf("sign jwt: %w", err)
-	}
-
-	session := &common.Session{
-		JWT: signedJWT,
-		User: &common.User{
-			ID:      user,
-			AppleID: unique,
-		},
-		ExpiredAt: exp,
+		return nil, err
 	}
+	session.User.AppleID = unique
 
 	return session, nil
 }
@@ -275,9 +381,10 @@ func (a *auth) Middleware() graphql.HandlerExtension {
 	return &Middleware{auth: a}
 }
 
-// NewAuth constructs the Auth service with provided configuration, storage, and logger.
-func NewAuth(cfg *Configuration, storage storage, logger *logrus.Entry) Auth {
-	return &auth{cfg: cfg, appleClient: apple.New(), storage: storage, log: logger}
+// NewAuth constructs the Auth service with provided configuration, identity provider, storage, and
+// logger.
+func NewAuth(cfg *Configuration, provider Provider, storage storage, logger *logrus.Entry) Auth {
+	return &auth{cfg: cfg, provider: provider, storage: storage, log: logger, stop: make(chan struct{})}
 }
 
 // Middleware implements a GraphQL extension to authenticate requests.
@@ -287,27 +394,50 @@ type Middleware struct {
 
 // WsInitFunc initializes the WebSocket connection by validating Authorization header if provided.
 func (a *auth) WsInitFunc(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
-	authHeader := payload.Authorization()
+	ctx, err := a.authenticate(ctx, payload.Authorization())
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return ctx, nil, nil
+}
+
+// HTTPMiddleware authenticates plain HTTP requests the same way as GraphQL ones: the user or admin
+// from the Authorization header is put in the request context, and an invalid token is rejected.
+func (a *auth) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := a.authenticate(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate adds the user or admin principal for the given Authorization header to ctx. An
+// empty header leaves ctx anonymous.
+func (a *auth) authenticate(ctx context.Context, authHeader string) (context.Context, error) {
 	if authHeader == "" {
-		return ctx, nil, nil
+		return ctx, nil
 	}
 
 	token := strings.Replace(authHeader, bearerPrefix, "", 1)
 
 	// Try user token first
-	if user, err := a.Validate(ctx, token); err == nil {
-		ctx = context.WithValue(ctx, userCtxKey, user)
-	} else {
-		// Try admin token
-		if principal, aerr := a.ValidateAdmin(ctx, token); aerr == nil {
-			ctx = context.WithValue(ctx, adminCtxKey, principal)
-		} else {
-			a.log.WithError(err).WithField("admin_err", aerr).Warn(invalidJWTMsg)
-			return ctx, nil, common.ErrJwtIncorrect
-		}
+	user, err := a.Validate(ctx, token)
+	if err == nil {
+		return context.WithValue(ctx, userCtxKey, user), nil
+	}
+	// Try admin token
+	principal, aerr := a.ValidateAdmin(ctx, token)
+	if aerr == nil {
+		return context.WithValue(ctx, adminCtxKey, principal), nil
 	}
 
-	return ctx, nil, nil
+	a.log.WithError(err).WithField("admin_err", aerr).Warn(invalidJWTMsg)
+	return ctx, common.ErrJwtIncorrect
 }
 
 // InterceptResponse intercepts GraphQL responses to ensure the user is authenticated.
@@ -325,6 +455,11 @@ func (a *Middleware) InterceptResponse(ctx context.Context, next graphql.Respons
 
 	token := strings.Replace(header, bearerPrefix, "", 1)
 
+	// A user already validated for this token (e.g. by the websocket init) is reused.
+	if user, err := GetUser(ctx); err == nil && user.Session.JWT == token {
+		return next(ctx)
+	}
+
 	// Try as user token
 	if user, err := a.auth.Validate(ctx, token); err == nil {
 		return next(context.WithValue(ctx, userCtxKey, user))
@@ -372,52 +507,103 @@ func AdminPrincipalFrom(ctx context.Context) (*AdminPrincipal, bool) {
 	return p, ok
 }
 
-// RequireAdmin ensures an admin principal is present in context.
+// RequireAdmin ensures the caller holds the admin key or is a user with the admin role.
 func RequireAdmin(ctx context.Context) error {
-	if _, ok := AdminPrincipalFrom(ctx); !ok {
-		return common.ErrUnauthorized
-	}
-	return nil
+	return RequireRole(ctx, common.RoleAdmin)
+}
+
+// IsAdmin reports whether the caller holds the admin key or is a user with the admin role.
+func IsAdmin(ctx context.Context) bool {
+	return RequireRole(ctx, common.RoleAdmin) == nil
 }
 
-// loadPublicKeyFromFile reads an ECDSA public key from a PEM file.
-func (a *auth) loadPublicKeyFromFile(path string) (*ecdsa.PublicKey, error) {
-	// #nosec G304 -- path comes from trusted configuration (mounted secret)
-	b, err := os.ReadFile(path)
+// RequireRole ensures the caller holds the admin key or is a user with at least the role. It returns
+// common.ErrUnauthorized for anonymous callers and common.ErrForbidden for users lacking the role.
+func RequireRole(ctx context.Context, role common.Role) error {
+	if _, ok := AdminPrincipalFrom(ctx); ok {
+		return nil
+	}
+	user, err := GetUser(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("read admin public key: %w", err)
+		return common.ErrUnauthorized
 	}
-	block, _ := pem.Decode(b)
-	if block == nil {
-		return nil, ErrEmptyBlockDecode
+	if user.Role < role {
+		return common.ErrForbidden
 	}
-	pk, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("parse admin public key: %w", err)
+	return nil
+}
+
+// SetUserRole changes the role of the user; it applies to the user's next request on this instance
+// and within RoleCacheTTL on the others.
+func (a *auth) SetUserRole(ctx context.Context, userID uuid.UUID, role common.Role) error {
+	if role < common.RoleUser || role > common.RoleAdmin {
+		return common.ErrInvalidRole
 	}
-	ecdsaKey, ok := pk.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, ErrAdminKeyNotECDSA
+	if err := a.storage.SetUserRole(ctx, userID, role); err != nil {
+		return fmt.Errorf("set user role: %w", err)
 	}
-	return ecdsaKey, nil
+	a.forgetRole(userID)
+	a.log.WithField("user", userID).WithField("role", role).Info("User role changed")
+	return nil
 }
 
-// loadAdminKeys preloads the default admin public key into cache.
-func (a *auth) loadAdminKeys() error {
+// reloadAdminKeys loads the admin keys again and swaps them in if they changed. On error the
+// previous keys stay in use.
+func (a *auth) reloadAdminKeys() error {
+	keys, err := loadAdminKeySet(a.cfg.AdminKeysPath, a.cfg.AdminKeyID)
+
 	a.adminKeysMutex.Lock()
 	defer a.adminKeysMutex.Unlock()
-	if a.adminKeys == nil {
-		a.adminKeys = make(map[string]*ecdsa.PublicKey)
-	}
-	key, err := a.loadPublicKeyFromFile(a.cfg.AdminPublicKeyPath)
 	if err != nil {
+		a.adminKeysErr = err
 		return err
 	}
-	// empty kid denotes default key
-	a.adminKeys[""] = key
+	a.adminKeysErr = nil
+	if sameKeys(a.adminKeys, keys) {
+		return nil
+	}
+	a.adminKeys = keys
+	a.adminKeysLoadedAt = time.Now().UTC()
+	a.log.WithField("kids", keyIDs(keys)).Info("Admin keys loaded")
 	return nil
 }
 
+// watchAdminKeys reloads the admin keys periodically so that keys can be added and removed
+// without a restart, until Stop is called.
+func (a *auth) watchAdminKeys() {
+	ticker := time.NewTicker(a.cfg.AdminKeysReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			if err := a.reloadAdminKeys(); err != nil {
+				a.log.WithError(err).Error("Reload admin keys")
+			}
+		}
+	}
+}
+
+// AdminKeys describes the loaded admin keys.
+func (a *auth) AdminKeys() AdminKeyStatus {
+	a.adminKeysMutex.RLock()
+	defer a.adminKeysMutex.RUnlock()
+	source := a.cfg.AdminKeysPath
+	if a.cfg.DevMode {
+		source = devKeyID
+	}
+	status := AdminKeyStatus{
+		Source:   source,
+		KeyIDs:   keyIDs(a.adminKeys),
+		LoadedAt: a.adminKeysLoadedAt,
+	}
+	if a.adminKeysErr != nil {
+		status.ReloadError = a.adminKeysErr.Error()
+	}
+	return status
+}
+
 // adminVerificationKey selects the correct admin public key from cache.
 func (a *auth) adminVerificationKey(token *jwt.Token) (interface{}, error) {
 	// Enforce ES256 algorithm
@@ -429,25 +615,22 @@ func (a *auth) adminVerificationKey(token *jwt.Token) (interface{}, error) {
 	kid, _ := token.Header["kid"].(string)
 	a.log.WithField("kid", kid).Debug("Looking up admin public key")
 
+	// Tokens without kid (or with ADMIN_KEY_ID) use the key of a single PEM file; unknown kids never
+	// fall back to it.
 	a.adminKeysMutex.RLock()
 	key, ok := a.adminKeys[kid]
-	if !ok {
-		// fall back to default if no kid provided
-		key, ok = a.adminKeys[""]
-		a.log.Debug("Using default admin key (no kid match)")
-	}
 	a.adminKeysMutex.RUnlock()
 
 	if !ok || key == nil {
-		a.log.Warn("Admin verification key not found in cache")
-		return nil, ErrAdminVerificationKeyAbsent
+		a.log.WithField("kid", kid).Warn("Unknown admin key id")
+		return nil, fmt.Errorf("%w: kid %q", ErrAdminVerificationKeyAbsent, kid)
 	}
 
 	return key, nil
 }
 
 // ValidateAdmin parses and validates an admin JWT and returns a principal.
-func (a *auth) ValidateAdmin(_ context.Context, jwtToken string) (*AdminPrincipal, error) {
+func (a *auth) ValidateAdmin(ctx context.Context, jwtToken string) (*AdminPrincipal, error) {
 	a.log.WithField("token_prefix", jwtToken[:min(tokenPrefixLogLen, len(jwtToken))]).Debug("ValidateAdmin called")
 
 	parsed, err := jwt.Parse(jwtToken, a.adminVerificationKey,
@@ -455,6 +638,7 @@ func (a *auth) ValidateAdmin(_ context.Context, jwtToken string) (*AdminPrincipa
 		jwt.WithIssuer(AdminIssuer),
 		jwt.WithAudience(AdminAudience),
 		jwt.WithLeeway(ClockSkewSeconds*time.Second),
+		jwt.WithIssuedAt(),
 	)
 	if err != nil {
 		a.log.WithError(err).Warn("Admin JWT parse failed")
@@ -478,22 +662,43 @@ func (a *auth) ValidateAdmin(_ context.Context, jwtToken string) (*AdminPrincipa
 		"iss": claims["iss"],
 		"aud": claims["aud"],
 	}).Debug("Admin JWT validated successfully")
-	// Build principal
-	var jti string
-	if v, ok := claims["jti"].(string); ok {
-		jti = v
+	// Build principal; the jti is required so that the token can be revoked.
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		a.log.Warn("Admin JWT without jti")
+		return nil, common.ErrInvalidToken
 	}
 	issuedAt, err := claims.GetIssuedAt()
-	if err != nil {
-		return nil, fmt.Errorf("missing iat: %w", err)
+	if err != nil || issuedAt == nil {
+		return nil, common.ErrInvalidToken
 	}
 	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, common.ErrInvalidToken
+	}
+	expiresAt := exp.Time
+	if limit := issuedAt.Add(a.cfg.AdminMaxTokenLifetime); limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+	if time.Now().After(expiresAt.Add(ClockSkewSeconds * time.Second)) {
+		a.log.WithField("jti", jti).Warn("Admin JWT older than the maximum token lifetime")
+		return nil, common.ErrExpiredToken
+	}
+
+	revoked, err := a.AdminTokenRevoked(ctx, jti)
 	if err != nil {
-		return nil, fmt.Errorf("missing exp: %w", err)
+		return nil, fmt.Errorf("check admin token revocation: %w", err)
+	}
+	if revoked {
+		a.log.WithField("jti", jti).Warn("Revoked admin JWT")
+		return nil, common.ErrAdminTokenRevoked
 	}
+
+	kid, _ := parsed.Header["kid"].(string)
 	return &AdminPrincipal{
 		JTI:       jti,
+		KeyID:     kid,
 		IssuedAt:  issuedAt.Time,
-		ExpiresAt: exp.Time,
+		ExpiresAt: expiresAt,
 	}, nil
 }
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestRequireRole(t *testing.T) {
+	withUser := func(role common.Role) context.Context {
+		return context.WithValue(context.Background(), userCtxKey, &common.User{Role: role})
+	}
+	admin := context.WithValue(context.Background(), adminCtxKey, &AdminPrincipal{JTI: "a"})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		role common.Role
+		err  error
+	}{
+		{"anonymous", context.Background(), common.RoleUser, common.ErrUnauthorized},
+		{"user", withUser(common.RoleUser), common.RoleUser, nil},
+		{"user as curator", withUser(common.RoleUser), common.RoleCurator, common.ErrForbidden},
+		{"curator", withUser(common.RoleCurator), common.RoleCurator, nil},
+		{"curator as admin", withUser(common.RoleCurator), common.RoleAdmin, common.ErrForbidden},
+		{"admin user", withUser(common.RoleAdmin), common.RoleCurator, nil},
+		{"admin key", admin, common.RoleAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, RequireRole(tt.ctx, tt.role), tt.err)
+		})
+	}
+}
+
+// roleStorage counts the role reads.
+type roleStorage struct {
+	storage
+	role  common.Role
+	reads int
+}
+
+func (s *roleStorage) UserRole(context.Context, uuid.UUID) (common.Role, error) {
+	s.reads++
+	return s.role, nil
+}
+
+func (s *roleStorage) SetUserRole(_ context.Context, _ uuid.UUID, role common.Role) error {
+	s.role = role
+	return nil
+}
+
+func TestUserRoleCache(t *testing.T) {
+	ctx, userID := context.Background(), uuid.New()
+	st := &roleStorage{role: common.RoleCurator}
+	a := &auth{cfg: &Configuration{RoleCacheTTL: time.Minute}, storage: st, log: logrus.NewEntry(logrus.New())}
+
+	for range 3 {
+		role, err := a.userRole(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, common.RoleCurator, role)
+	}
+	assert.Equal(t, 1, st.reads)
+
+	require.NoError(t, a.SetUserRole(ctx, userID, common.RoleUser))
+	role, err := a.userRole(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, common.RoleUser, role)
+	assert.Equal(t, 2, st.reads)
+
+	a.cfg.RoleCacheTTL = 0
+	_, err = a.userRole(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.reads)
+}
@@ -2,11 +2,17 @@
 package auth
 
 import (
+	"errors"
 	"os"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
+// errDevModeBuild is why a release build refuses to start with AUTH_DEV_MODE: a misplaced variable
+// must not be enough to turn off Sign in with Apple and the admin keys in a deployment.
+var errDevModeBuild = errors.New("AUTH_DEV_MODE needs a server built with -tags devmode")
+
 // Configuration holds Apple authentication configuration and secret key path.
 type Configuration struct {
 	SecretPath string
@@ -15,12 +21,58 @@ type Configuration struct {
 	ClientID   string
 	KeyID      string
 
-	// Path to admin public key (mounted as a file)
-	AdminPublicKeyPath string
+	// AdminKeysPath is a PEM file, a directory of <kid>.pem files or a JWKS .json file holding the
+	// admin public keys (mounted as a secret); it is re-read every AdminKeysReloadInterval, or never
+	// if that is zero.
+	AdminKeysPath           string
+	AdminKeysReloadInterval time.Duration
+	// AdminKeyID is the kid that tokens signed with the key of a single PEM file may send; tokens
+	// without kid are accepted as well.
+	AdminKeyID string
+	// AdminMaxTokenLifetime bounds how long after iat an admin JWT is accepted, whatever its exp.
+	AdminMaxTokenLifetime time.Duration
+	// AdminOneTimeTokens makes sensitive mutations spend the admin JWT they are called with.
+	AdminOneTimeTokens bool
+	// AdminRevocationsPruneSchedule is when revocations of expired admin tokens are deleted.
+	AdminRevocationsPruneSchedule string
+
+	// RefreshTTL is how long a session can go unrefreshed before the user has to sign in again.
+	RefreshTTL time.Duration
+	// RefreshReuseGrace is how long after a refresh the exchanged token is rejected without revoking
+	// the session.
+	RefreshReuseGrace time.Duration
+	// SessionsPruneSchedule is when sessions that expired or were revoked are deleted.
+	SessionsPruneSchedule string
+	// RoleCacheTTL is how long a user role read for a request is reused; zero reads it every time.
+	RoleCacheTTL time.Duration
+
+	// DevMode replaces Sign in with Apple and the admin keys with devLogin and ephemeral keys, for
+	// local development only.
+	DevMode bool
 }
 
-// Config loads configuration from environment and reads the private key from SecretPath.
+// Config loads configuration from environment and reads the private key from SecretPath. In dev
+// mode the Apple variables are not needed and an ephemeral key is used instead; dev mode is only
+// accepted by binaries built with -tags devmode.
 func Config() *Configuration {
+	type sessionCfg struct {
+		RefreshTTL        time.Duration `envconfig:"REFRESH_TTL" default:"1440h"`
+		RefreshReuseGrace time.Duration `envconfig:"REFRESH_REUSE_GRACE" default:"30s"`
+		RoleCacheTTL      time.Duration `envconfig:"ROLE_CACHE_TTL" default:"10s"`
+		DevMode           bool          `envconfig:"DEV_MODE" default:"false"`
+		// Ended sessions are kept for a day at least, so a daily prune is plenty.
+		SessionsPruneSchedule string `envconfig:"SESSIONS_PRUNE_SCHEDULE" default:"50 3 * * *"`
+	}
+
+	var sc sessionCfg
+	if err := envconfig.Process("AUTH", &sc); err != nil {
+		panic(err)
+	}
+
+	if sc.DevMode && !devModeBuild {
+		panic(errDevModeBuild)
+	}
+
 	// Load Apple auth configuration from APPLE_AUTH_* variables only
 	type appleCfg struct {
 		SecretPath string `envconfig:"SECRET_PATH" default:"AuthKey_39D5B439QV.p8"`
@@ -29,32 +81,65 @@ func Config() *Configuration {
 		KeyID      string `envconfig:"KEY_ID" required:"true"`
 	}
 
-	var ac appleCfg
-	if err := envconfig.Process("APPLE_AUTH", &ac); err != nil {
-		panic(err)
+	var (
+		ac     appleCfg
+		secret string
+	)
+	if sc.DevMode {
+		var err error
+		if secret, err = newDevSecret(); err != nil {
+			panic(err)
+		}
+	} else {
+		if err := envconfig.Process("APPLE_AUTH", &ac); err != nil {
+			panic(err)
+		}
+
+		data, err := os.ReadFile(ac.SecretPath)
+		if err != nil {
+			panic(err)
+		}
+		secret = string(data)
 	}
 
 	// Load non-prefixed variables like ADMIN_PUBLIC_KEY_PATH without re-processing Apple fields
 	type adminCfg struct {
-		AdminPublicKeyPath string `envconfig:"ADMIN_PUBLIC_KEY_PATH" default:"/keys/admin/admin_public_key.pem"`
+		AdminPublicKeyPath      string        `envconfig:"ADMIN_PUBLIC_KEY_PATH" default:"/keys/admin/admin_public_key.pem"`
+		AdminKeysPath           string        `envconfig:"ADMIN_KEYS_PATH"`
+		AdminKeyID              string        `envconfig:"ADMIN_KEY_ID"`
+		AdminKeysReloadInterval time.Duration `envconfig:"ADMIN_KEYS_RELOAD_INTERVAL" default:"30s"`
+		AdminMaxTokenLifetime   time.Duration `envconfig:"ADMIN_MAX_TOKEN_LIFETIME" default:"24h"`
+		AdminOneTimeTokens      bool          `envconfig:"ADMIN_ONE_TIME_TOKENS" default:"false"`
+		// Revocations are kept for a day at most, so a daily prune is plenty.
+		AdminRevocationsPruneSchedule string `envconfig:"ADMIN_REVOCATIONS_PRUNE_SCHEDULE" default:"45 3 * * *"`
 	}
 
 	var adc adminCfg
 	if err := envconfig.Process("", &adc); err != nil {
 		panic(err)
 	}
-
-	data, err := os.ReadFile(ac.SecretPath)
-	if err != nil {
-		panic(err)
+	// ADMIN_PUBLIC_KEY_PATH is the single key from before key rotation was supported.
+	if adc.AdminKeysPath == "" {
+		adc.AdminKeysPath = adc.AdminPublicKeyPath
 	}
 
 	return &Configuration{
-		SecretPath:         ac.SecretPath,
-		Secret:             string(data),
-		TeamID:             ac.TeamID,
-		ClientID:           ac.ClientID,
-		KeyID:              ac.KeyID,
-		AdminPublicKeyPath: adc.AdminPublicKeyPath,
+		SecretPath:              ac.SecretPath,
+		Secret:                  secret,
+		TeamID:                  ac.TeamID,
+		ClientID:                ac.ClientID,
+		KeyID:                   ac.KeyID,
+		AdminKeysPath:           adc.AdminKeysPath,
+		AdminKeysReloadInterval: adc.AdminKeysReloadInterval,
+		AdminKeyID:              adc.AdminKeyID,
+		AdminMaxTokenLifetime:   adc.AdminMaxTokenLifetime,
+		AdminOneTimeTokens:      adc.AdminOneTimeTokens,
+
+		AdminRevocationsPruneSchedule: adc.AdminRevocationsPruneSchedule,
+		RefreshTTL:                    sc.RefreshTTL,
+		RefreshReuseGrace:             sc.RefreshReuseGrace,
+		RoleCacheTTL:                  sc.RoleCacheTTL,
+		SessionsPruneSchedule:         sc.SessionsPruneSchedule,
+		DevMode:                       sc.DevMode,
 	}
 }
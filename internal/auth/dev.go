@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+const (
+	// devKeyID is the kid of the admin key of dev mode.
+	devKeyID = "dev"
+	// devUniquePrefix keeps the users of dev mode apart from Apple users in a shared database.
+	devUniquePrefix = "dev:"
+)
+
+// DevSession is the result of a dev login: a user session and, if asked for, an admin token.
+type DevSession struct {
+	Session             *common.Session
+	AdminToken          string
+	AdminTokenExpiredAt time.Time
+}
+
+// newDevSecret returns an ephemeral PKCS8 PEM private key that stands in for the Apple key in dev
+// mode. Tokens signed with it stop working when the server restarts.
+func newDevSecret() (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("marshal pkcs8 private key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// startDevMode replaces the admin keys with an ephemeral key that only devLogin signs with.
+func (a *auth) startDevMode() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate dev admin key: %w", err)
+	}
+
+	a.adminKeysMutex.Lock()
+	a.devAdminKey = key
+	a.adminKeys = map[string]*ecdsa.PublicKey{devKeyID: &key.PublicKey}
+	a.adminKeysLoadedAt = time.Now().UTC()
+	a.adminKeysMutex.Unlock()
+
+	a.log.Warn("Auth dev mode is on: anyone can sign in with devLogin, never enable it in production")
+
+	return nil
+}
+
+// DevLogin signs in as the test user with the name on the device without an identity provider,
+// optionally setting the user's role first and issuing an admin token as well. It only works in dev
+// mode.
+func (a *auth) DevLogin(
+	ctx context.Context, name string, deviceID uuid.UUID, role *common.Role, admin bool,
+) (*DevSession, error) {
+	if !a.cfg.DevMode {
+		return nil, common.ErrDevModeDisabled
+	}
+
+	unique, err := FakeProvider{}.Identify(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("identify: %w", err)
+	}
+
+	session, err := a.signIn(ctx, unique, deviceID, common.DeviceInfo{})
+	if err != nil {
+		return nil, err
+	}
+
+	if role != nil {
+		if err = a.SetUserRole(ctx, session.User.ID, *role); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DevSession{Session: session}
+	if admin {
+		if result.AdminToken, result.AdminTokenExpiredAt, err = a.signDevAdminToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// signDevAdminToken issues an admin token with the ephemeral admin key, valid for the maximum admin
+// token lifetime.
+func (a *auth) signDevAdminToken() (string, time.Time, error) {
+	a.adminKeysMutex.RLock()
+	key := a.devAdminKey
+	a.adminKeysMutex.RUnlock()
+	if key == nil {
+		return "", time.Time{}, common.ErrDevModeDisabled
+	}
+
+	now := time.Now().UTC()
+	exp := now.Add(a.cfg.AdminMaxTokenLifetime)
+
+	token := jwt.NewWithClaims(signingMethod, jwt.MapClaims{
+		"iss":         AdminIssuer,
+		"aud":         AdminAudience,
+		"jti":         uuid.New().String(),
+		"iat":         now.Unix(),
+		"exp":         exp.Unix(),
+		AdminClaimKey: true,
+	})
+	token.Header["kid"] = devKeyID
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign admin jwt: %w", err)
+	}
+
+	return signed, exp, nil
+}
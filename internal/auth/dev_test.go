@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+type devStorage struct {
+	storage
+	users map[string]uuid.UUID
+	roles map[uuid.UUID]common.Role
+}
+
+func (s *devStorage) GetOrCreateUser(_ context.Context, unique string) (uuid.UUID, error) {
+	if id, ok := s.users[unique]; ok {
+		return id, nil
+	}
+	id := uuid.New()
+	s.users[unique] = id
+	return id, nil
+}
+
+func (s *devStorage) BindDevice(context.Context, uuid.UUID, uuid.UUID, common.DeviceInfo, time.Time) error {
+	return nil
+}
+
+func (s *devStorage) CreateSession(context.Context, uuid.UUID, uuid.UUID, uuid.UUID, []byte, time.Time, time.Time) error {
+	return nil
+}
+
+func (s *devStorage) SessionValid(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (s *devStorage) UserRole(_ context.Context, userID uuid.UUID) (common.Role, error) {
+	return s.roles[userID], nil
+}
+
+func (s *devStorage) SetUserRole(_ context.Context, userID uuid.UUID, role common.Role) error {
+	s.roles[userID] = role
+	return nil
+}
+
+func (s *devStorage) AdminTokenRevoked(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func newDevAuth(t *testing.T, devMode bool) *auth {
+	t.Helper()
+	secret, err := newDevSecret()
+	require.NoError(t, err)
+	a := &auth{
+		provider: FakeProvider{},
+		cfg: &Configuration{
+			Secret: secret, DevMode: devMode, RefreshTTL: time.Hour, AdminMaxTokenLifetime: time.Hour,
+		},
+		storage: &devStorage{users: map[string]uuid.UUID{}, roles: map[uuid.UUID]common.Role{}},
+		log:     logrus.NewEntry(logrus.New()),
+	}
+	require.NoError(t, a.Start())
+	return a
+}
+
+func TestDevLogin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		a := &auth{cfg: &Configuration{}}
+		_, err := a.DevLogin(ctx, "alice", uuid.New(), nil, true)
+		assert.ErrorIs(t, err, common.ErrDevModeDisabled)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		a := newDevAuth(t, true)
+		curator := common.RoleCurator
+		res, err := a.DevLogin(ctx, "alice", uuid.New(), &curator, false)
+		require.NoError(t, err)
+		assert.Empty(t, res.AdminToken)
+
+		user, err := a.Validate(ctx, res.Session.JWT)
+		require.NoError(t, err)
+		assert.Equal(t, res.Session.User.ID, user.ID)
+		assert.Equal(t, common.RoleCurator, user.Role)
+
+		again, err := a.Auth(ctx, "alice", uuid.New(), common.DeviceInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, again.User.ID)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		a := newDevAuth(t, true)
+		res, err := a.DevLogin(ctx, "bob", uuid.New(), nil, true)
+		require.NoError(t, err)
+
+		principal, err := a.ValidateAdmin(ctx, res.AdminToken)
+		require.NoError(t, err)
+		assert.Equal(t, devKeyID, principal.KeyID)
+		assert.Equal(t, devKeyID, a.AdminKeys().Source)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		a := newDevAuth(t, true)
+		_, err := a.DevLogin(ctx, "", uuid.New(), nil, false)
+		assert.ErrorIs(t, err, ErrEmptyCode)
+	})
+}
+
+func TestConfig_DevMode(t *testing.T) {
+	t.Setenv("AUTH_DEV_MODE", "true")
+
+	if !devModeBuild {
+		require.PanicsWithValue(t, errDevModeBuild, func() { Config() })
+		return
+	}
+	cfg := Config()
+	assert.True(t, cfg.DevMode)
+	assert.NotEmpty(t, cfg.Secret)
+}
@@ -0,0 +1,7 @@
+//go:build devmode
+
+package auth
+
+// devModeBuild reports whether the binary was built with -tags devmode, without which AUTH_DEV_MODE
+// is refused.
+const devModeBuild = true
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Timothylock/go-signin-with-apple/apple"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrEmptyCode indicates a sign-in code that is empty.
+var ErrEmptyCode = errors.New("empty sign-in code")
+
+// Provider identifies users by the codes apps sign in with.
+type Provider interface {
+	// Identify verifies the code and returns the unique id of the user at the identity provider.
+	Identify(ctx context.Context, code string) (string, error)
+}
+
+type appleProvider struct {
+	client *apple.Client
+	cfg    *Configuration
+	secret string
+	log    *logrus.Entry
+}
+
+// NewAppleProvider returns the Sign in with Apple provider; the code is an Apple authorization code.
+func NewAppleProvider(cfg *Configuration, logger *logrus.Entry) (Provider, error) {
+	secret, err := apple.GenerateClientSecret(cfg.Secret, cfg.TeamID, cfg.ClientID, cfg.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("generate apple client secret: %w", err)
+	}
+
+	return &appleProvider{client: apple.New(), cfg: cfg, secret: secret, log: logger}, nil
+}
+
+func (p *appleProvider) Identify(ctx context.Context, code string) (string, error) {
+	vReq := apple.AppValidationTokenRequest{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.secret,
+		Code:         code,
+	}
+
+	var resp apple.ValidationResponse
+
+	// Do the verification
+	if err := p.client.VerifyAppToken(ctx, vReq, &resp); err != nil {
+		return "", fmt.Errorf("verify apple app token: %w", err)
+	}
+
+	if resp.Error != "" {
+		return "", fmt.Errorf("%w: %s", ErrAppleAuth, resp.ErrorDescription)
+	}
+
+	claims, err := apple.GetClaims(resp.IDToken)
+	if err != nil {
+		return "", fmt.Errorf("get claims: %w", err)
+	}
+
+	p.log.Info(claims)
+
+	unique, err := apple.GetUniqueID(resp.IDToken)
+	if err != nil {
+		return "", fmt.Errorf("get unique id: %w", err)
+	}
+
+	return unique, nil
+}
+
+// FakeProvider trusts any non-empty code and takes it as the name of the user, so that the same
+// name always signs in as the same user. It backs dev mode and tests.
+type FakeProvider struct{}
+
+// Identify returns the unique id of the user named by code.
+func (FakeProvider) Identify(_ context.Context, code string) (string, error) {
+	if code == "" {
+		return "", ErrEmptyCode
+	}
+
+	return devUniquePrefix + code, nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// roleCacheSweepSize is the number of cached roles above which expired entries are dropped.
+const roleCacheSweepSize = 1024
+
+type cachedRole struct {
+	role      common.Role
+	expiresAt time.Time
+}
+
+// userRole returns the role of the user, read from storage at most once per RoleCacheTTL. A role
+// change made on this instance applies right away; one made on another instance within the TTL.
+func (a *auth) userRole(ctx context.Context, userID uuid.UUID) (common.Role, error) {
+	if a.cfg.RoleCacheTTL <= 0 {
+		return a.UserRole(ctx, userID)
+	}
+
+	now := time.Now()
+	a.rolesMutex.Lock()
+	cached, ok := a.roles[userID]
+	a.rolesMutex.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.role, nil
+	}
+
+	role, err := a.UserRole(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	a.rolesMutex.Lock()
+	defer a.rolesMutex.Unlock()
+	if a.roles == nil {
+		a.roles = make(map[uuid.UUID]cachedRole)
+	}
+	if len(a.roles) >= roleCacheSweepSize {
+		for id, c := range a.roles {
+			if !now.Before(c.expiresAt) {
+				delete(a.roles, id)
+			}
+		}
+	}
+	a.roles[userID] = cachedRole{role: role, expiresAt: now.Add(a.cfg.RoleCacheTTL)}
+
+	return role, nil
+}
+
+// forgetRole drops the cached role of the user.
+func (a *auth) forgetRole(userID uuid.UUID) {
+	a.rolesMutex.Lock()
+	delete(a.roles, userID)
+	a.rolesMutex.Unlock()
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// refreshTokenBytes is the number of random bytes in a refresh token.
+const refreshTokenBytes = 32
+
+// SessionsPruneJobName is the scheduler job that deletes sessions that expired or were revoked.
+const SessionsPruneJobName = "sessions"
+
+// newRefreshToken returns a random refresh token and the hash it is stored as.
+func newRefreshToken() (string, []byte, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("read random: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// startSession stores a new session of the user on the device and issues its tokens.
+func (a *auth) startSession(ctx context.Context, userID, deviceID uuid.UUID) (*common.Session, error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &common.Session{
+		ID:               uuid.New(),
+		User:             &common.User{ID: userID},
+		DeviceID:         deviceID,
+		RefreshToken:     refreshToken,
+		RefreshExpiredAt: now.Add(a.cfg.RefreshTTL),
+	}
+
+	if err = a.CreateSession(ctx, session.ID, userID, deviceID, hash, now, session.RefreshExpiredAt); err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+
+	if err = a.sign(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// RefreshSession exchanges the refresh token of a session on the device for a new access token and
+// a new refresh token. Presenting the refresh token that was exchanged last revokes its session, as
+// either the app or whoever stole the token is replaying it; within RefreshReuseGrace of the
+// exchange it is only rejected, as the app may have sent concurrent refreshes.
+func (a *auth) RefreshSession(ctx context.Context, refreshToken string, deviceID uuid.UUID) (*common.Session, error) {
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashRefreshToken(refreshToken)
+
+	session, err := a.RotateSession(ctx, deviceID, hash, newHash, time.Now().UTC().Add(a.cfg.RefreshTTL))
+	if errors.Is(err, common.ErrInvalidRefreshToken) {
+		reused, rerr := a.RevokeReusedSession(ctx, hash, a.cfg.RefreshReuseGrace)
+		if rerr != nil {
+			return nil, fmt.Errorf("revoke reused session: %w", rerr)
+		}
+		if reused {
+			a.log.WithField("device", deviceID).Warn("Refresh token reused, session revoked")
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rotate session: %w", err)
+	}
+
+	session.RefreshToken = newToken
+	if err = a.sign(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Logout revokes the session of the user's access token.
+func (a *auth) Logout(ctx context.Context, user *common.User) error {
+	if user.Session.ID == uuid.Nil {
+		return nil
+	}
+
+	if err := a.RevokeSession(ctx, user.ID, user.Session.ID); err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// LogoutEverywhere revokes all sessions of the user and returns how many there were.
+func (a *auth) LogoutEverywhere(ctx context.Context, userID uuid.UUID) (int, error) {
+	n, err := a.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke user sessions: %w", err)
+	}
+
+	return n, nil
+}
+
+// PruneSessions deletes the sessions that ended longer ago than an access token lives, so that no
+// token that is still valid loses its session, and returns how many there were.
+func (a *auth) PruneSessions(ctx context.Context) (int64, error) {
+	n, err := a.storage.PruneSessions(ctx, JwtDurationHour*time.Hour)
+	if err != nil {
+		return 0, fmt.Errorf("prune sessions: %w", err)
+	}
+
+	return n, nil
+}
+
+// sign issues the access token of the session.
+func (a *auth) sign(session *common.Session) error {
+	now := time.Now().UTC()
+	exp := now.Add(time.Hour * JwtDurationHour)
+
+	claims := &userClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    session.User.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+			ID:        uuid.New().String(),
+		},
+		SessionID: session.ID.String(),
+		DeviceID:  session.DeviceID.String(),
+	}
+
+	privKey, err := a.getSecret()
+	if err != nil {
+		return fmt.Errorf(getSecretWrapF, err)
+	}
+
+	signedJWT, err := jwt.NewWithClaims(signingMethod, claims).SignedString(privKey)
+	if err != nil {
+		return fmt.Errorf("sign jwt: %w", err)
+	}
+
+	session.JWT = signedJWT
+	session.ExpiredAt = exp
+
+	return nil
+}
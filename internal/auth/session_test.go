@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+type storedSession struct {
+	id, userID, deviceID uuid.UUID
+	hash, previousHash   []byte
+	refreshedAt          time.Time
+	revoked              bool
+}
+
+// sessionStorage keeps sessions in memory the way the sessions table does.
+type sessionStorage struct {
+	storage
+	sessions []*storedSession
+}
+
+func (s *sessionStorage) CreateSession(
+	_ context.Context, id, userID, deviceID uuid.UUID, hash []byte, _, _ time.Time,
+) error {
+	s.sessions = append(s.sessions, &storedSession{id: id, userID: userID, deviceID: deviceID, hash: hash})
+	return nil
+}
+
+func (s *sessionStorage) RotateSession(
+	_ context.Context, deviceID uuid.UUID, hash, newHash []byte, expiresAt time.Time,
+) (*common.Session, error) {
+	for _, session := range s.sessions {
+		if bytes.Equal(session.hash, hash) && session.deviceID == deviceID && !session.revoked {
+			session.previousHash, session.hash, session.refreshedAt = session.hash, newHash, time.Now()
+			return &common.Session{
+				ID: session.id, User: &common.User{ID: session.userID}, DeviceID: deviceID, RefreshExpiredAt: expiresAt,
+			}, nil
+		}
+	}
+	return nil, common.ErrInvalidRefreshToken
+}
+
+func (s *sessionStorage) RevokeReusedSession(_ context.Context, hash []byte, grace time.Duration) (bool, error) {
+	for _, session := range s.sessions {
+		if bytes.Equal(session.previousHash, hash) && !session.revoked && time.Since(session.refreshedAt) >= grace {
+			session.revoked = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *sessionStorage) RevokeSession(_ context.Context, userID, sessionID uuid.UUID) error {
+	for _, session := range s.sessions {
+		if session.id == sessionID && session.userID == userID {
+			session.revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *sessionStorage) SessionValid(_ context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	for _, session := range s.sessions {
+		if session.id == sessionID && session.userID == userID {
+			return !session.revoked, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *sessionStorage) UserRole(context.Context, uuid.UUID) (common.Role, error) {
+	return common.RoleUser, nil
+}
+
+func newSessionAuth(t *testing.T, grace time.Duration) *auth {
+	t.Helper()
+	secret, err := newDevSecret()
+	require.NoError(t, err)
+	return &auth{
+		cfg:     &Configuration{Secret: secret, RefreshTTL: time.Hour, RefreshReuseGrace: grace},
+		storage: &sessionStorage{},
+		log:     logrus.NewEntry(logrus.New()),
+	}
+}
+
+func TestRefreshSession(t *testing.T) {
+	ctx := context.Background()
+	userID, deviceID := uuid.New(), uuid.New()
+
+	t.Run("rotation", func(t *testing.T) {
+		a := newSessionAuth(t, 0)
+		session, err := a.startSession(ctx, userID, deviceID)
+		require.NoError(t, err)
+
+		refreshed, err := a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.NoError(t, err)
+		assert.Equal(t, session.ID, refreshed.ID)
+		assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+		user, err := a.Validate(ctx, refreshed.JWT)
+		require.NoError(t, err)
+		assert.Equal(t, userID, user.ID)
+
+		_, err = a.RefreshSession(ctx, refreshed.RefreshToken, deviceID)
+		require.NoError(t, err)
+	})
+
+	t.Run("reuse revokes the session", func(t *testing.T) {
+		a := newSessionAuth(t, 0)
+		session, err := a.startSession(ctx, userID, deviceID)
+		require.NoError(t, err)
+		refreshed, err := a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.NoError(t, err)
+
+		_, err = a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+		_, err = a.RefreshSession(ctx, refreshed.RefreshToken, deviceID)
+		require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+		_, err = a.Validate(ctx, refreshed.JWT)
+		require.ErrorIs(t, err, common.ErrSessionRevoked)
+	})
+
+	t.Run("concurrent refresh within the grace window", func(t *testing.T) {
+		a := newSessionAuth(t, time.Minute)
+		session, err := a.startSession(ctx, userID, deviceID)
+		require.NoError(t, err)
+		refreshed, err := a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.NoError(t, err)
+
+		_, err = a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+		_, err = a.RefreshSession(ctx, refreshed.RefreshToken, deviceID)
+		require.NoError(t, err)
+	})
+
+	t.Run("device mismatch", func(t *testing.T) {
+		a := newSessionAuth(t, 0)
+		session, err := a.startSession(ctx, userID, deviceID)
+		require.NoError(t, err)
+
+		_, err = a.RefreshSession(ctx, session.RefreshToken, uuid.New())
+		require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+		_, err = a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.NoError(t, err)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		a := newSessionAuth(t, 0)
+		session, err := a.startSession(ctx, userID, deviceID)
+		require.NoError(t, err)
+		require.NoError(t, a.RevokeSession(ctx, userID, session.ID))
+
+		_, err = a.RefreshSession(ctx, session.RefreshToken, deviceID)
+		require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	a := newSessionAuth(t, 0)
+	session, err := a.startSession(ctx, uuid.New(), uuid.New())
+	require.NoError(t, err)
+	user, err := a.Validate(ctx, session.JWT)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Logout(ctx, user))
+	_, err = a.Validate(ctx, session.JWT)
+	require.ErrorIs(t, err, common.ErrSessionRevoked)
+	_, err = a.RefreshSession(ctx, session.RefreshToken, session.DeviceID)
+	require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+}
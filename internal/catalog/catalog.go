@@ -0,0 +1,233 @@
+// Package catalog renders a printable tea menu of a collection: its teas grouped by beverage type,
+// each with tags, a short description, the brewing temperature and a QR code to the public page.
+package catalog
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/unidoc/unipdf/v3/creator"
+	pdfModel "github.com/unidoc/unipdf/v3/model"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
+	"github.com/teaelephant/TeaElephantMemory/printqr"
+)
+
+const (
+	descriptionMaxLength = 240
+	qrSize               = 64
+	margin               = 48
+	titleSize            = 22
+	sectionSize          = 15
+	nameSize             = 12
+	textSize             = 9
+	mutedGray            = 0x6b
+)
+
+// typeOrder is the order of the menu sections.
+var typeOrder = []common.BeverageType{
+	common.TeaBeverageType,
+	common.HerbBeverageType,
+	common.CoffeeBeverageType,
+	common.OtherBeverageType,
+}
+
+// Entry is one tea in the menu, taken from a collection record.
+type Entry struct {
+	RecordID    uuid.UUID
+	Name        string
+	Type        common.BeverageType
+	Description string
+	Tags        []common.Tag
+	BrewingTemp int
+}
+
+// Section is a group of entries of the same beverage type.
+type Section struct {
+	Type    common.BeverageType
+	Entries []Entry
+}
+
+// Group splits entries into sections in menu order, sorting each section by name.
+func Group(entries []Entry) []Section {
+	var sections []Section
+	for _, t := range typeOrder {
+		var list []Entry
+		for _, e := range entries {
+			if e.Type == t || (t == common.OtherBeverageType && !slices.Contains(typeOrder, e.Type)) {
+				list = append(list, e)
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+
+		slices.SortStableFunc(list, func(a, b Entry) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		sections = append(sections, Section{Type: t, Entries: list})
+	}
+
+	return sections
+}
+
+// Write renders the menu as an A4 PDF; each QR code links to the record page under baseURL.
+func Write(w io.Writer, title string, sections []Section, baseURL string) error {
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.SetPageMargins(margin, margin, margin, margin)
+
+	regular, err := pdfModel.NewStandard14Font(pdfModel.HelveticaName)
+	if err != nil {
+		return errors.Wrap(err, "failed to load font")
+	}
+	bold, err := pdfModel.NewStandard14Font(pdfModel.HelveticaBoldName)
+	if err != nil {
+		return errors.Wrap(err, "failed to load font")
+	}
+
+	r := &renderer{c: c, regular: regular, bold: bold, baseURL: baseURL}
+
+	if err = c.Draw(r.text(title, bold, titleSize, creator.ColorBlack, 0, sectionSize)); err != nil {
+		return errors.Wrap(err, "failed to draw title")
+	}
+
+	for _, s := range sections {
+		if err = r.section(s); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(c.Write(w), "failed to write catalog")
+}
+
+type renderer struct {
+	c       *creator.Creator
+	regular *pdfModel.PdfFont
+	bold    *pdfModel.PdfFont
+	baseURL string
+}
+
+func (r *renderer) section(s Section) error {
+	heading := r.text(sectionTitle(s.Type), r.bold, sectionSize, creator.ColorBlack, sectionSize, textSize)
+	if err := r.c.Draw(heading); err != nil {
+		return errors.Wrap(err, "failed to draw section")
+	}
+
+	table := r.c.NewTable(2)
+	table.EnablePageWrap(true)
+	if err := table.SetColumnWidths(0.15, 0.85); err != nil {
+		return errors.Wrap(err, "failed to set column widths")
+	}
+
+	for _, e := range s.Entries {
+		if err := r.entry(table, e); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(r.c.Draw(table), "failed to draw entries")
+}
+
+func (r *renderer) entry(table *creator.Table, e Entry) error {
+	code, err := printqr.NewPNG(qrlink.URL(r.baseURL, e.RecordID), false)
+	if err != nil {
+		return err
+	}
+
+	img, err := r.c.NewImageFromData(code)
+	if err != nil {
+		return errors.Wrap(err, "failed to create image")
+	}
+	img.ScaleToWidth(qrSize)
+	img.SetMargins(0, 0, textSize/2, textSize)
+
+	cell := table.NewCell()
+	cell.SetVerticalAlignment(creator.CellVerticalAlignmentTop)
+	if err = cell.SetContent(img); err != nil {
+		return errors.Wrap(err, "failed to set cell content")
+	}
+
+	p := r.c.NewStyledParagraph()
+	p.SetMargins(0, 0, textSize/2, textSize)
+	r.chunk(p, e.Name+"\n", r.bold, nameSize, creator.ColorBlack)
+	for i, tag := range e.Tags {
+		if i > 0 {
+			r.chunk(p, "  ", r.regular, textSize, creator.ColorBlack)
+		}
+		r.chunk(p, "• "+tag.Name, r.regular, textSize, tagColor(tag.Color))
+	}
+	if len(e.Tags) > 0 {
+		r.chunk(p, "\n", r.regular, textSize, creator.ColorBlack)
+	}
+	if d := summary(e.Description); d != "" {
+		r.chunk(p, d+"\n", r.regular, textSize, creator.ColorBlack)
+	}
+	if e.BrewingTemp > 0 {
+		r.chunk(p, fmt.Sprintf("Brew at %d °C", e.BrewingTemp), r.regular, textSize,
+			creator.ColorRGBFrom8bit(mutedGray, mutedGray, mutedGray))
+	}
+
+	cell = table.NewCell()
+	cell.SetVerticalAlignment(creator.CellVerticalAlignmentTop)
+
+	return errors.Wrap(cell.SetContent(p), "failed to set cell content")
+}
+
+func (r *renderer) text(text string, font *pdfModel.PdfFont, size float64, color creator.Color, top, bottom float64) *creator.StyledParagraph {
+	p := r.c.NewStyledParagraph()
+	p.SetMargins(0, 0, top, bottom)
+	r.chunk(p, text, font, size, color)
+
+	return p
+}
+
+func (r *renderer) chunk(p *creator.StyledParagraph, text string, font *pdfModel.PdfFont, size float64, color creator.Color) {
+	chunk := p.Append(text)
+	chunk.Style.Font = font
+	chunk.Style.FontSize = size
+	chunk.Style.Color = color
+}
+
+func sectionTitle(t common.BeverageType) string {
+	switch t {
+	case common.TeaBeverageType:
+		return "Teas"
+	case common.HerbBeverageType:
+		return "Herbal infusions"
+	case common.CoffeeBeverageType:
+		return "Coffee"
+	default:
+		return "Other"
+	}
+}
+
+func summary(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > descriptionMaxLength {
+		text = string(r[:descriptionMaxLength-1]) + "…"
+	}
+
+	return text
+}
+
+// tagColor parses #rgb / #rrggbb tag colors, with or without #, falling back to gray.
+func tagColor(color string) creator.Color {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 3 && len(hex) != 6 {
+		return creator.ColorRGBFrom8bit(mutedGray, mutedGray, mutedGray)
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return creator.ColorRGBFrom8bit(mutedGray, mutedGray, mutedGray)
+		}
+	}
+
+	return creator.ColorRGBFromHex("#" + hex)
+}
@@ -0,0 +1,32 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestGroup(t *testing.T) {
+	sections := Group([]Entry{
+		{Name: "sencha", Type: common.TeaBeverageType},
+		{Name: "Chamomile", Type: common.HerbBeverageType},
+		{Name: "Assam", Type: common.TeaBeverageType},
+		{Name: "Mystery", Type: common.BeverageType(42)},
+	})
+
+	if assert.Len(t, sections, 3) {
+		assert.Equal(t, common.TeaBeverageType, sections[0].Type)
+		assert.Equal(t, "Assam", sections[0].Entries[0].Name)
+		assert.Equal(t, "sencha", sections[0].Entries[1].Name)
+		assert.Equal(t, common.HerbBeverageType, sections[1].Type)
+		assert.Equal(t, common.OtherBeverageType, sections[2].Type)
+		assert.Equal(t, "Mystery", sections[2].Entries[0].Name)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	assert.Equal(t, "a b", summary("  a\n\tb "))
+	assert.Len(t, []rune(summary(string(make([]rune, 500)))), descriptionMaxLength)
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/auth"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+// IDVar is the mux route variable holding the collection id.
+const IDVar = "id"
+
+const defaultTitle = "Tea menu"
+
+type collectionManager interface {
+	List(ctx context.Context, userID uuid.UUID) ([]*model.Collection, error)
+	ListRecords(ctx context.Context, id, userID uuid.UUID) ([]*model.QRRecord, error)
+}
+
+type tagManager interface {
+	ListByTea(ctx context.Context, id uuid.UUID) ([]common.Tag, error)
+}
+
+// Handler serves /v2/collections/{id}/catalog.pdf to the collection owner. Requests must be
+// authenticated by auth.HTTPMiddleware.
+type Handler struct {
+	collections collectionManager
+	tags        tagManager
+	baseURL     string
+	log         *logrus.Entry
+}
+
+// NewHandler creates the catalog handler. baseURL is the public origin the QR codes link to.
+func NewHandler(collections collectionManager, tags tagManager, baseURL string, log *logrus.Entry) *Handler {
+	return &Handler{collections: collections, tags: tags, baseURL: baseURL, log: log}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)[IDVar])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, err := auth.GetUser(r.Context())
+	if err != nil {
+		http.Error(w, common.ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	title, entries, err := h.load(r.Context(), id, user.ID)
+	if errors.Is(err, common.ErrCollectionNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("collection", id).Error("load catalog")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = Write(&buf, title, Group(entries), h.baseURL); err != nil {
+		h.log.WithError(err).WithField("collection", id).Error("render catalog")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "catalog-"+id.String()+".pdf"))
+	_, _ = w.Write(buf.Bytes()) //nolint:errcheck // nothing to do if the client went away
+}
+
+func (h *Handler) load(ctx context.Context, id, userID uuid.UUID) (string, []Entry, error) {
+	records, err := h.collections.ListRecords(ctx, id, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	collections, err := h.collections.List(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	title := defaultTitle
+	for _, c := range collections {
+		if uuid.UUID(c.ID) == id && c.Name != "" {
+			title = c.Name
+		}
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for _, rec := range records {
+		// Records owned by someone else are left off the menu rather than printed redacted.
+		if rec.Tea == nil || len(rec.Hidden) > 0 {
+			continue
+		}
+
+		tags, err := h.tags.ListByTea(ctx, uuid.UUID(rec.Tea.ID))
+		if err != nil {
+			return "", nil, err
+		}
+
+		entries = append(entries, Entry{
+			RecordID:    uuid.UUID(rec.ID),
+			Name:        rec.Tea.Name,
+			Type:        rec.Tea.Type.ToBeverageType(),
+			Description: rec.Tea.Description,
+			Tags:        tags,
+			BrewingTemp: rec.BowlingTemp,
+		})
+	}
+
+	return title, entries, nil
+}
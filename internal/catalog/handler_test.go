@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+type stubCollections struct {
+	records []*model.QRRecord
+}
+
+func (s stubCollections) List(context.Context, uuid.UUID) ([]*model.Collection, error) {
+	return nil, nil
+}
+
+func (s stubCollections) ListRecords(context.Context, uuid.UUID, uuid.UUID) ([]*model.QRRecord, error) {
+	return s.records, nil
+}
+
+type stubTags struct{}
+
+func (stubTags) ListByTea(context.Context, uuid.UUID) ([]common.Tag, error) {
+	return nil, nil
+}
+
+func TestLoad(t *testing.T) {
+	record := func(name string, hidden ...model.QRRecordField) *model.QRRecord {
+		return &model.QRRecord{
+			ID:     gqlCommon.ID(uuid.New()),
+			Tea:    &model.Tea{ID: gqlCommon.ID(uuid.New()), Name: name, Type: model.TypeTea},
+			Hidden: hidden,
+		}
+	}
+	h := NewHandler(stubCollections{records: []*model.QRRecord{
+		record("Sencha"),
+		record("Foreign", model.QRRecordFieldBowlingTemp, model.QRRecordFieldExpirationDate),
+	}}, stubTags{}, "https://example.com", nil)
+
+	title, entries, err := h.load(context.Background(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, defaultTitle, title)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Sencha", entries[0].Name)
+	}
+}
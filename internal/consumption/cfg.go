@@ -0,0 +1,27 @@
+package consumption
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// PruneJobName is the scheduler job that removes events older than the retention window.
+const PruneJobName = "consumptionRetention"
+
+// Configuration controls how long consumption events are kept.
+type Configuration struct {
+	Retention time.Duration `envconfig:"RETENTION" default:"720h"`
+	// PruneSchedule is when old events are removed, see scheduler.Parse.
+	PruneSchedule string `envconfig:"PRUNE_SCHEDULE" default:"30 3 * * *"`
+}
+
+// Config reads the configuration from CONSUMPTION_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("CONSUMPTION", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -37,8 +37,8 @@ func NewPGStore(pg *sql.DB, retention time.Duration) *PGStore {
 	return &PGStore{queries: pgstore.New(pg), retention: retention}
 }
 
-// Record stores a consumption event for a user at a given timestamp, enforcing
-// the retention window by pruning older entries for that user.
+// Record stores a consumption event for a user at a given timestamp. Entries older than the
+// retention window are removed by Prune.
 func (s *PGStore) Record(ctx context.Context, userID uuid.UUID, teaID uuid.UUID, ts time.Time) error {
 	if s.queries == nil {
 		return ErrNilDB
@@ -52,12 +52,22 @@ func (s *PGStore) Record(ctx context.Context, userID uuid.UUID, teaID uuid.UUID,
 		return fmt.Errorf("pg consumption.Record: insert: %w", err)
 	}
 
-	cutoff := ts.Add(-s.retention)
-	if err := s.queries.DeleteConsumptionsBefore(ctx, userID, cutoff); err != nil {
-		return fmt.Errorf("pg consumption.Record: retention delete: %w", err)
+	return nil
+}
+
+// Prune deletes the events of all users that are older than the retention window and returns
+// how many were deleted.
+func (s *PGStore) Prune(ctx context.Context) (int64, error) {
+	if s.queries == nil {
+		return 0, ErrNilDB
 	}
 
-	return nil
+	deleted, err := s.queries.DeleteConsumptionsBefore(ctx, time.Now().UTC().Add(-s.retention))
+	if err != nil {
+		return 0, fmt.Errorf("pg consumption.Prune: %w", err)
+	}
+
+	return deleted, nil
 }
 
 // Recent returns consumption events since the given time for the user, ordered by time descending.
@@ -0,0 +1,173 @@
+// Package dedup finds likely duplicate teas in the catalog.
+package dedup
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// Signal weights; a signal that has no data on either side is left out and the
+// remaining weights are renormalized.
+const (
+	nameWeight        = 0.6
+	tagWeight         = 0.25
+	descriptionWeight = 0.15
+
+	// DefaultThreshold is the minimal combined score for a pair to be reported.
+	DefaultThreshold = 0.75
+)
+
+// Item is a tea together with its tag assignments.
+type Item struct {
+	Tea  common.Tea
+	Tags []uuid.UUID
+}
+
+// Pair is a scored duplicate candidate. All scores are in [0, 1].
+type Pair struct {
+	A                common.Tea
+	B                common.Tea
+	Score            float64
+	NameScore        float64
+	TagScore         float64
+	DescriptionScore float64
+}
+
+// Find scores every pair of items and returns those with Score >= threshold,
+// best matches first.
+func Find(items []Item, threshold float64) []Pair {
+	prepared := make([]prepared, len(items))
+	for i, it := range items {
+		prepared[i] = prepare(it)
+	}
+
+	var res []Pair
+	for i := 0; i < len(prepared); i++ {
+		for j := i + 1; j < len(prepared); j++ {
+			p := score(&prepared[i], &prepared[j])
+			if p.Score >= threshold {
+				res = append(res, p)
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+
+	return res
+}
+
+type prepared struct {
+	tea   common.Tea
+	name  string
+	tags  map[uuid.UUID]struct{}
+	words map[string]struct{}
+}
+
+func prepare(it Item) prepared {
+	p := prepared{tea: it.Tea, tags: make(map[uuid.UUID]struct{}, len(it.Tags))}
+	for _, id := range it.Tags {
+		p.tags[id] = struct{}{}
+	}
+	if it.Tea.TeaData != nil {
+		p.name = Normalize(it.Tea.Name)
+		p.words = words(it.Tea.Description)
+	}
+
+	return p
+}
+
+func score(a, b *prepared) Pair {
+	p := Pair{A: a.tea, B: b.tea}
+
+	var total, weight float64
+	if a.name != "" || b.name != "" {
+		p.NameScore = similarity(a.name, b.name)
+		total += nameWeight * p.NameScore
+		weight += nameWeight
+	}
+	if len(a.tags) > 0 || len(b.tags) > 0 {
+		p.TagScore = jaccard(a.tags, b.tags)
+		total += tagWeight * p.TagScore
+		weight += tagWeight
+	}
+	if len(a.words) > 0 || len(b.words) > 0 {
+		p.DescriptionScore = jaccard(a.words, b.words)
+		total += descriptionWeight * p.DescriptionScore
+		weight += descriptionWeight
+	}
+	if weight > 0 {
+		p.Score = total / weight
+	}
+
+	return p
+}
+
+// Normalize lowercases a name, drops punctuation and collapses whitespace so that
+// "Da Hong Pao (2019)" and "da-hong pao 2019" compare equal.
+func Normalize(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// similarity is 1 - normalized Levenshtein distance.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func words(text string) map[string]struct{} {
+	res := make(map[string]struct{})
+	for _, w := range strings.Fields(Normalize(text)) {
+		res[w] = struct{}{}
+	}
+
+	return res
+}
+
+func jaccard[K comparable](a, b map[K]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if _, ok := b[k]; ok {
+			inter++
+		}
+	}
+
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
@@ -0,0 +1,51 @@
+package dedup
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func tea(name, description string) common.Tea {
+	return common.Tea{ID: uuid.New(), TeaData: &common.TeaData{Name: name, Description: description}}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "da hong pao 2019", Normalize("  Da-Hong  Pao (2019)"))
+	assert.Equal(t, "", Normalize("!!"))
+}
+
+func TestFind(t *testing.T) {
+	smoky, oolong := uuid.New(), uuid.New()
+	a := Item{Tea: tea("Da Hong Pao", "roasted rock oolong"), Tags: []uuid.UUID{smoky, oolong}}
+	b := Item{Tea: tea("Da-Hong Pao!", "rock oolong, roasted"), Tags: []uuid.UUID{oolong, smoky}}
+	c := Item{Tea: tea("Sencha", "steamed green tea"), Tags: []uuid.UUID{uuid.New()}}
+
+	t.Run("near duplicates", func(t *testing.T) {
+		res := Find([]Item{a, b, c}, DefaultThreshold)
+		if assert.Len(t, res, 1) {
+			assert.Equal(t, a.Tea.ID, res[0].A.ID)
+			assert.Equal(t, b.Tea.ID, res[0].B.ID)
+			assert.InDelta(t, 1, res[0].Score, 1e-9)
+			assert.InDelta(t, 1, res[0].TagScore, 1e-9)
+		}
+	})
+	t.Run("missing signals are not penalized", func(t *testing.T) {
+		res := Find([]Item{{Tea: tea("Gyokuro", "")}, {Tea: tea("gyokuro", "")}}, DefaultThreshold)
+		if assert.Len(t, res, 1) {
+			assert.InDelta(t, 1, res[0].Score, 1e-9)
+			assert.Zero(t, res[0].TagScore)
+		}
+	})
+	t.Run("sorted by score", func(t *testing.T) {
+		d := Item{Tea: tea("Da Hong Pa", "roasted rock oolong"), Tags: []uuid.UUID{smoky}}
+		res := Find([]Item{a, d, b}, 0.5)
+		if assert.Len(t, res, 3) {
+			assert.GreaterOrEqual(t, res[0].Score, res[1].Score)
+			assert.GreaterOrEqual(t, res[1].Score, res[2].Score)
+		}
+	})
+}
@@ -2,8 +2,8 @@ package expiration
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,122 +14,136 @@ import (
 
 //go:generate mockgen -source=alerter.go -destination=mocks/alerter.go -package=mocks storage,alerter,sender
 
+const (
+	// JobName is the scheduler job that runs the alerter.
+	JobName = "expirationAlerts"
+	// PruneJobName is the scheduler job that deletes the delivered alerts that are no longer needed.
+	PruneJobName = "expirationAlertsPrune"
+)
+
 type Alerter interface {
-	Start() error
-	Stop() error
 	Run(ctx context.Context) error
+	// Prune deletes the delivered alerts for expiration dates that records no longer have and
+	// returns how many there were.
+	Prune(ctx context.Context) (int64, error)
 }
 
 type sender interface {
-	Send(ctx context.Context, userID, itemID uuid.UUID, title, body string) error
+	Send(ctx context.Context, n *common.Notification) error
 }
 
 type storage interface {
 	GetUsers(ctx context.Context) ([]common.User, error)
 	Collections(ctx context.Context, userID uuid.UUID) ([]*common.Collection, error)
 	CollectionRecords(ctx context.Context, id uuid.UUID) ([]*common.CollectionRecord, error)
+	ExpirationAlerts(ctx context.Context, userID uuid.UUID) ([]common.ExpirationAlert, error)
+	MarkExpirationAlertsSent(ctx context.Context, userID uuid.UUID, alerts []common.ExpirationAlert) error
+	PruneExpirationAlerts(ctx context.Context) (int64, error)
 }
 
 type alerter struct {
 	sender
 	storage
 
-	startSync *sync.Once
-
-	log  *logrus.Entry
-	stop chan struct{}
-}
-
-func (a *alerter) Start() error {
-	a.startSync.Do(func() {
-		go a.loop()
-	})
-
-	return nil
-}
-
-func (a *alerter) Stop() error {
-	a.stop <- struct{}{}
-	return nil
+	cfg *Configuration
+	log *logrus.Entry
 }
 
+// Run sends every user one digest of the records that reached a lead time. A failure for one user
+// is logged and does not stop the others; the joined errors are returned.
 func (a *alerter) Run(ctx context.Context) error {
 	users, err := a.GetUsers(ctx)
 	if err != nil {
 		return err
 	}
 
+	var errs []error
 	for _, user := range users {
-		if err := a.processUserCollections(ctx, user.ID); err != nil {
-			return err
+		if err := a.processUser(ctx, user.ID, time.Now()); err != nil {
+			a.log.WithError(err).WithField("user", user.ID).Error("expiration alert")
+			errs = append(errs, fmt.Errorf("user %s: %w", user.ID, err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (a *alerter) processUserCollections(ctx context.Context, userID uuid.UUID) error {
-	collections, err := a.Collections(ctx, userID)
+func (a *alerter) Prune(ctx context.Context) (int64, error) {
+	n, err := a.PruneExpirationAlerts(ctx)
 	if err != nil {
-		return err
-	}
-
-	for _, col := range collections {
-		if err := a.processCollectionRecords(ctx, userID, col); err != nil {
-			return err
-		}
+		return 0, err
 	}
+	a.log.WithField("alerts", n).Debug("stale expiration alerts pruned")
 
-	return nil
+	return n, nil
 }
 
-func (a *alerter) processCollectionRecords(ctx context.Context, userID uuid.UUID, col *common.Collection) error {
-	records, err := a.CollectionRecords(ctx, col.ID)
+func (a *alerter) processUser(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	items, err := a.userRecords(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	for _, record := range records {
-		if err := a.processRecord(ctx, userID, col, record); err != nil {
-			return err
-		}
+	sent, err := a.ExpirationAlerts(ctx, userID)
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
+	due := Due(now, a.cfg.LeadDays, items, sent)
+	if len(due) == 0 {
+		return nil
+	}
 
-func (a *alerter) processRecord(ctx context.Context, userID uuid.UUID, col *common.Collection, record *common.CollectionRecord) error {
-	a.log.WithField("user", userID).
-		WithField("collection", col.Name).
-		WithField("tea", record.Tea.Name).Debug("expiration date checked")
+	n := &common.Notification{UserID: userID, Type: common.NotificationTypeTeaExpiration, SourceKey: SourceKey(due)}
+	n.Title, n.Body = Digest(due)
+	if len(due) == 1 {
+		n.EntityType, n.EntityID = common.NotificationEntityQR, due[0].Record.ID
+	}
 
-	if !record.ExpirationDate.Before(time.Now()) {
-		return nil
+	if err = a.Send(ctx, n); err != nil {
+		return err
 	}
 
-	body := fmt.Sprintf("tea %s from collection %s expired %s", record.Tea.Name, col.Name, record.ExpirationDate)
+	var alerts []common.ExpirationAlert
+	for _, it := range due {
+		alerts = append(alerts, it.Alerts...)
+	}
 
-	return a.Send(ctx, userID, record.ID, "tea expired", body)
+	return a.MarkExpirationAlertsSent(ctx, userID, alerts)
 }
 
-func (a *alerter) loop() {
-	ticker := time.NewTicker(time.Hour * 24 * 7)
-
-loop:
-	for {
-		select {
-		case <-a.stop:
-			break loop
-		case <-ticker.C:
-			if err := a.Run(context.Background()); err != nil {
-				a.log.WithError(err).Error("alerter run")
+// userRecords lists the records in the user's collections, each once.
+func (a *alerter) userRecords(ctx context.Context, userID uuid.UUID) ([]Item, error) {
+	collections, err := a.Collections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]struct{})
+	var items []Item
+	for _, col := range collections {
+		records, err := a.CollectionRecords(ctx, col.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			if _, ok := seen[record.ID]; ok {
+				continue
 			}
+			seen[record.ID] = struct{}{}
+
+			a.log.WithField("user", userID).
+				WithField("collection", col.Name).
+				WithField("tea", record.Tea.Name).Debug("expiration date checked")
+
+			items = append(items, Item{Record: record, Collection: col.Name})
 		}
 	}
 
-	close(a.stop)
+	return items, nil
 }
 
-func NewAlerter(sender sender, storage storage, log *logrus.Entry) Alerter {
-	return &alerter{sender: sender, storage: storage, log: log, startSync: new(sync.Once), stop: make(chan struct{})}
+func NewAlerter(sender sender, storage storage, cfg *Configuration, log *logrus.Entry) Alerter {
+	return &alerter{sender: sender, storage: storage, cfg: cfg, log: log}
 }
@@ -0,0 +1,25 @@
+package expiration
+
+import "github.com/kelseyhightower/envconfig"
+
+// Configuration controls when expiration alerts are sent.
+type Configuration struct {
+	// LeadDays are the days before the expiration date at which the user is alerted; 0 alerts during
+	// the last day, or once the tea has expired if it was added late.
+	LeadDays []int `envconfig:"LEAD_DAYS" default:"30,7,1,0"`
+	// Schedule is when the alerter runs, see scheduler.Parse; runs must be well below a day apart
+	// for one-day lead times.
+	Schedule string `envconfig:"SCHEDULE" default:"0 */6 * * *"`
+	// PruneSchedule is when the alerts for expiration dates that records no longer have are deleted.
+	PruneSchedule string `envconfig:"PRUNE_SCHEDULE" default:"15 4 * * *"`
+}
+
+// Config reads the configuration from EXPIRATION_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("EXPIRATION", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
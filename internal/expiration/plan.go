@@ -0,0 +1,140 @@
+package expiration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+const (
+	day = 24 * time.Hour
+	// digestMaxLines caps the number of teas listed in one push; the rest are summarized.
+	digestMaxLines = 5
+)
+
+// Item is a record that reached a lead time, with the collection it was found in.
+type Item struct {
+	Record     *common.CollectionRecord
+	Collection string
+	DaysLeft   int
+	// Alerts are the lead times delivered by this item: the most urgent reached one and any less
+	// urgent ones that were skipped, so that they never fire late.
+	Alerts []common.ExpirationAlert
+}
+
+// Due returns the items to alert about at now. Each record is reported at most once per lead time
+// and expiration date; when several lead times were reached since the last run, only the most
+// urgent one is reported.
+func Due(now time.Time, leadDays []int, items []Item, sent []common.ExpirationAlert) []Item {
+	leads := slices.Clone(leadDays)
+	slices.Sort(leads)
+	leads = slices.Compact(leads)
+
+	delivered := make(map[common.ExpirationAlert]struct{}, len(sent))
+	for _, a := range sent {
+		delivered[key(a.RecordID, a.LeadDays, a.ExpirationDate)] = struct{}{}
+	}
+
+	var res []Item
+	for _, it := range items {
+		left := daysLeft(now, it.Record.ExpirationDate)
+
+		var reached []common.ExpirationAlert
+		for _, lead := range leads {
+			if left <= lead {
+				reached = append(reached, key(it.Record.ID, lead, it.Record.ExpirationDate))
+			}
+		}
+		if len(reached) == 0 {
+			continue
+		}
+		if _, ok := delivered[reached[0]]; ok {
+			continue
+		}
+
+		for _, a := range reached {
+			delivered[a] = struct{}{}
+		}
+
+		it.DaysLeft = left
+		it.Alerts = reached
+		res = append(res, it)
+	}
+
+	return res
+}
+
+// Digest builds one push for all due items of a user.
+func Digest(items []Item) (title, body string) {
+	if len(items) == 1 {
+		it := items[0]
+		if it.DaysLeft <= 0 {
+			return "Tea expired", line(it)
+		}
+
+		return "Tea expires soon", line(it)
+	}
+
+	lines := make([]string, 0, digestMaxLines+1)
+	for i, it := range items {
+		if i == digestMaxLines {
+			lines = append(lines, fmt.Sprintf("and %d more", len(items)-digestMaxLines))
+			break
+		}
+		lines = append(lines, line(it))
+	}
+
+	return fmt.Sprintf("%d teas need attention", len(items)), strings.Join(lines, "\n")
+}
+
+// SourceKey identifies the digest of items by the alerts it delivers, so that a digest sent again
+// after a failure is recognised, whatever the order of the items.
+func SourceKey(items []Item) string {
+	var alerts []string
+	for _, it := range items {
+		for _, a := range it.Alerts {
+			alerts = append(alerts, fmt.Sprintf("%s/%d/%s", a.RecordID, a.LeadDays, a.ExpirationDate.UTC().Format(time.RFC3339)))
+		}
+	}
+	slices.Sort(alerts)
+	sum := sha256.Sum256([]byte(strings.Join(alerts, "\n")))
+
+	return "tea-expiration/" + hex.EncodeToString(sum[:])
+}
+
+func line(it Item) string {
+	name := it.Record.Tea.Name
+	if it.Collection != "" {
+		name = fmt.Sprintf("%s from %s", name, it.Collection)
+	}
+
+	switch {
+	case it.DaysLeft < 0:
+		return name + " has expired"
+	case it.DaysLeft == 0:
+		return name + " expires within a day"
+	case it.DaysLeft == 1:
+		return name + " expires tomorrow"
+	default:
+		return fmt.Sprintf("%s expires in %d days", name, it.DaysLeft)
+	}
+}
+
+// daysLeft is the number of whole days until expiration: 0 during the last day and negative once
+// the date has passed.
+func daysLeft(now, expiration time.Time) int {
+	return int(math.Floor(expiration.Sub(now).Hours() / day.Hours()))
+}
+
+// key normalizes the expiration date so that alerts loaded from Postgres compare equal.
+func key(id uuid.UUID, lead int, expiration time.Time) common.ExpirationAlert {
+	return common.ExpirationAlert{RecordID: id, LeadDays: lead, ExpirationDate: expiration.UTC().Truncate(time.Microsecond)}
+}
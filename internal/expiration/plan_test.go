@@ -0,0 +1,97 @@
+package expiration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func item(name string, expiration time.Time) Item {
+	return Item{
+		Record: &common.CollectionRecord{
+			ID:             uuid.New(),
+			Tea:            &common.Tea{TeaData: &common.TeaData{Name: name}},
+			ExpirationDate: expiration,
+		},
+		Collection: "Home",
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	leads := []int{30, 7, 1, 0}
+
+	fresh := item("Sencha", now.Add(60*day))
+	month := item("Assam", now.Add(20*day))
+	week := item("Gyokuro", now.Add(5*day+time.Hour))
+	expired := item("Puer", now.Add(-time.Hour))
+
+	t.Run("most urgent reached lead", func(t *testing.T) {
+		due := Due(now, leads, []Item{fresh, month, week, expired}, nil)
+		if assert.Len(t, due, 3) {
+			assert.Equal(t, 20, due[0].DaysLeft)
+			assert.Len(t, due[0].Alerts, 1)
+			assert.Equal(t, 5, due[1].DaysLeft)
+			assert.Equal(t, []int{7, 30}, leadsOf(due[1].Alerts))
+			assert.Equal(t, []int{0, 1, 7, 30}, leadsOf(due[2].Alerts))
+		}
+	})
+	t.Run("sent once per lead", func(t *testing.T) {
+		sent := Due(now, leads, []Item{month, week}, nil)
+		var alerts []common.ExpirationAlert
+		for _, it := range sent {
+			alerts = append(alerts, it.Alerts...)
+		}
+
+		assert.Empty(t, Due(now.Add(day), leads, []Item{month, week}, alerts))
+		assert.Len(t, Due(now.Add(4*day), leads, []Item{month, week}, alerts), 1)
+	})
+	t.Run("new expiration date re-arms", func(t *testing.T) {
+		alerts := Due(now, leads, []Item{week}, nil)[0].Alerts
+		moved := week
+		rec := *week.Record
+		rec.ExpirationDate = rec.ExpirationDate.Add(time.Hour)
+		moved.Record = &rec
+		assert.Len(t, Due(now, leads, []Item{moved}, alerts), 1)
+	})
+}
+
+func TestDigest(t *testing.T) {
+	now := time.Now()
+	due := Due(now, []int{7}, []Item{item("Sencha", now.Add(3*day+time.Hour))}, nil)
+	title, body := Digest(due)
+	assert.Equal(t, "Tea expires soon", title)
+	assert.Equal(t, "Sencha from Home expires in 3 days", body)
+
+	var many []Item
+	for range 7 {
+		many = append(many, item("Sencha", now.Add(-day)))
+	}
+	title, body = Digest(Due(now, []int{0}, many, nil))
+	assert.Equal(t, "7 teas need attention", title)
+	assert.Contains(t, body, "Sencha from Home has expired\n")
+	assert.Contains(t, body, "\nand 2 more")
+}
+
+func leadsOf(alerts []common.ExpirationAlert) []int {
+	res := make([]int, len(alerts))
+	for i, a := range alerts {
+		res[i] = a.LeadDays
+	}
+
+	return res
+}
+
+func TestSourceKey(t *testing.T) {
+	now := time.Now()
+	a, b := item("Sencha", now.Add(day)), item("Gyokuro", now.Add(2*day))
+	due := Due(now, []int{7}, []Item{a, b}, nil)
+
+	assert.Equal(t, SourceKey(due), SourceKey([]Item{due[1], due[0]}))
+	assert.NotEqual(t, SourceKey(due), SourceKey(due[:1]))
+	assert.NotEqual(t, SourceKey(due), SourceKey(Due(now, []int{3}, []Item{a, b}, nil)))
+}
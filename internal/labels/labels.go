@@ -0,0 +1,223 @@
+// Package labels renders printable PDF labels for QR records: the code together with the tea name
+// and brewing information.
+package labels
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/auth"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+	"github.com/teaelephant/TeaElephantMemory/printqr"
+)
+
+// IDVar is the mux route variable holding the QR record or collection id.
+const IDVar = "id"
+
+const (
+	// DefaultRecordTemplate fits a single label printer roll.
+	DefaultRecordTemplate = "label-89x36"
+	// DefaultCollectionTemplate is a sheet of A4 address labels.
+	DefaultCollectionTemplate = "avery-l7160"
+
+	dateLayout = "2 Jan 2006"
+)
+
+type qrManager interface {
+	Get(ctx context.Context, id uuid.UUID) (*common.QR, error)
+	Hidden(record *common.QR, actor *qr.Actor) []model.QRRecordField
+}
+
+type teaManager interface {
+	Get(ctx context.Context, id uuid.UUID) (*common.Tea, error)
+}
+
+type collectionManager interface {
+	ListRecords(ctx context.Context, id, userID uuid.UUID) ([]*model.QRRecord, error)
+}
+
+// Handler serves label PDFs. Requests must be authenticated by auth.HTTPMiddleware: a record label
+// is available to the record owner and admins, a collection sheet to the collection owner.
+type Handler struct {
+	qr          qrManager
+	tea         teaManager
+	collections collectionManager
+	baseURL     string
+	log         *logrus.Entry
+}
+
+// NewHandler creates the label handler. baseURL is the public origin encoded in the QR codes.
+func NewHandler(qr qrManager, tea teaManager, collections collectionManager, baseURL string, log *logrus.Entry) *Handler {
+	return &Handler{qr: qr, tea: tea, collections: collections, baseURL: baseURL, log: log}
+}
+
+// Record serves /v2/labels/{id}.pdf with the label of a single QR record.
+func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)[IDVar])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	label, err := h.recordLabel(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.write(w, r, DefaultRecordTemplate, []printqr.Label{*label})
+}
+
+// Collection serves /v2/labels/collections/{id}.pdf with a label sheet for every record in a collection
+// that the caller may see in full.
+func (h *Handler) Collection(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)[IDVar])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, err := auth.GetUser(r.Context())
+	if err != nil {
+		h.fail(w, r, common.ErrUnauthorized)
+		return
+	}
+
+	records, err := h.collections.ListRecords(r.Context(), id, user.ID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	list := make([]printqr.Label, 0, len(records))
+	for _, rec := range records {
+		// As for a single label, records owned by someone else are not printed.
+		if len(rec.Hidden) > 0 {
+			continue
+		}
+		var name, kind string
+		if rec.Tea != nil {
+			name, kind = rec.Tea.Name, rec.Tea.Type.String()
+		}
+		list = append(list, newLabel(uuid.UUID(rec.ID), name, kind, rec.BowlingTemp, rec.ExpirationDate))
+	}
+
+	h.write(w, r, DefaultCollectionTemplate, list)
+}
+
+func (h *Handler) recordLabel(ctx context.Context, id uuid.UUID) (*printqr.Label, error) {
+	actor, err := actor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := h.qr.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.qr.Hidden(rec, actor)) > 0 {
+		return nil, common.ErrQRRecordForbidden
+	}
+
+	tea, err := h.tea.Get(ctx, rec.Tea)
+	if err != nil {
+		return nil, err
+	}
+
+	label := newLabel(id, tea.Name, tea.Type.String(), rec.BowlingTemp, rec.ExpirationDate)
+
+	return &label, nil
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, defaultTemplate string, list []printqr.Label) {
+	name := r.URL.Query().Get("template")
+	if name == "" {
+		name = defaultTemplate
+	}
+
+	t, err := printqr.LookupTemplate(name, r.URL.Query().Get("paper"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := printqr.WriteLabels(&buf, list, printqr.Options{Template: t, BaseURL: h.baseURL}); err != nil {
+		h.fail(w, r, fmt.Errorf("write labels: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", mux.Vars(r)[IDVar]+".pdf"))
+	_, _ = w.Write(buf.Bytes()) //nolint:errcheck // nothing to do if the client went away
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, common.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, common.ErrQRRecordForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, common.ErrQRRecordNotExist), errors.Is(err, common.ErrCollectionNotFound),
+		errors.Is(err, common.ErrTeaNotFound):
+		http.NotFound(w, r)
+	default:
+		h.log.WithError(err).WithField("path", r.URL.Path).Error("render labels")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func actor(ctx context.Context) (*qr.Actor, error) {
+	if _, ok := auth.AdminPrincipalFrom(ctx); ok {
+		return &qr.Actor{Admin: true}, nil
+	}
+	user, err := auth.GetUser(ctx)
+	if err != nil {
+		return nil, common.ErrUnauthorized
+	}
+
+	return &qr.Actor{UserID: &user.ID, Admin: user.Role == common.RoleAdmin}, nil
+}
+
+func newLabel(id uuid.UUID, name, kind string, temp int, expiration time.Time) printqr.Label {
+	return printqr.Label{
+		ID:    id,
+		Title: name,
+		Lines: []string{
+			kind,
+			fmt.Sprintf("Brew at %d °C, steep %s", temp, SteepHint(kind, temp)),
+			"Best before " + expiration.Format(dateLayout),
+		},
+	}
+}
+
+// SteepHint suggests a steeping time for a beverage type and water temperature: cooler water is
+// used for delicate green and white teas, which also need less time.
+func SteepHint(kind string, temp int) string {
+	switch strings.ToLower(kind) {
+	case common.HerbBeverageType.String():
+		return "5–7 min"
+	case common.CoffeeBeverageType.String():
+		return "4 min"
+	}
+
+	switch {
+	case temp <= 80:
+		return "2–3 min"
+	case temp <= 90:
+		return "3–4 min"
+	default:
+		return "3–5 min"
+	}
+}
@@ -0,0 +1,22 @@
+package landing
+
+import "github.com/kelseyhightower/envconfig"
+
+// Configuration holds the app links of the landing page.
+type Configuration struct {
+	// AppScheme is the custom URL scheme of the app. The page cannot use its own universal link:
+	// iOS does not open a universal link in the app from a page of the same domain.
+	AppScheme string `envconfig:"APP_SCHEME" default:"teaelephant"`
+	// AppStoreURL is linked for visitors without the app; no link when empty.
+	AppStoreURL string `envconfig:"APP_STORE_URL"`
+}
+
+// Config reads the configuration from LANDING_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("LANDING", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
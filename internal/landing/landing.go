@@ -0,0 +1,212 @@
+// Package landing renders the public web page shown when a QR label is scanned without the app.
+package landing
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
+)
+
+// IDVar is the mux route variable holding the short code or UUID.
+const IDVar = "id"
+
+const (
+	dateLayout       = "2 January 2006"
+	cacheControl     = "public, max-age=300"
+	notFoundTitle    = "Unknown label"
+	summaryMaxLength = 200
+)
+
+//go:embed page.html
+var pageHTML string
+
+var page = template.Must(template.New("page").Parse(pageHTML))
+
+type qrManager interface {
+	Get(ctx context.Context, id uuid.UUID) (*common.QR, error)
+	Hidden(record *common.QR, actor *qr.Actor) []model.QRRecordField
+}
+
+type teaManager interface {
+	Get(ctx context.Context, id uuid.UUID) (*common.Tea, error)
+}
+
+type tagManager interface {
+	ListByTea(ctx context.Context, id uuid.UUID) ([]common.Tag, error)
+}
+
+type view struct {
+	Found       bool
+	Title       string
+	Summary     string
+	URL         string
+	AppURL      template.URL
+	AppStoreURL string
+	Type        string
+	Description string
+	Tags        []tagView
+	BrewingTemp int
+	Expiration  string
+}
+
+type tagView struct {
+	Name  string
+	Color template.CSS
+}
+
+// Handler serves /q/{id}. Visitors are anonymous, so only the QR fields that are public for
+// non-owners are shown.
+type Handler struct {
+	qr      qrManager
+	tea     teaManager
+	tags    tagManager
+	cfg     *Configuration
+	baseURL string
+	log     *logrus.Entry
+}
+
+// NewHandler creates the landing page handler. baseURL is the public origin used for links and
+// Open Graph tags.
+func NewHandler(
+	qr qrManager, tea teaManager, tags tagManager, cfg *Configuration, baseURL string, log *logrus.Entry,
+) *Handler {
+	return &Handler{qr: qr, tea: tea, tags: tags, cfg: cfg, baseURL: strings.TrimRight(baseURL, "/"), log: log}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := qrlink.Parse(mux.Vars(r)[IDVar])
+	if err != nil {
+		h.render(w, http.StatusNotFound, &view{Title: notFoundTitle, URL: h.baseURL, AppURL: h.appURL(uuid.Nil)})
+		return
+	}
+
+	v, err := h.view(r.Context(), id)
+	if errors.Is(err, common.ErrQRRecordNotExist) {
+		h.render(w, http.StatusNotFound, &view{Title: notFoundTitle, URL: v.URL, AppURL: h.appURL(uuid.Nil)})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("qr", id).Error("render landing page")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	h.render(w, http.StatusOK, v)
+}
+
+func (h *Handler) view(ctx context.Context, id uuid.UUID) (*view, error) {
+	v := &view{URL: qrlink.URL(h.baseURL, id), AppURL: h.appURL(id)}
+
+	rec, err := h.qr.Get(ctx, id)
+	if err != nil {
+		return v, err
+	}
+
+	hidden := h.qr.Hidden(rec, nil)
+	v.Found = true
+	v.Title = "Tea Elephant label"
+
+	if !slices.Contains(hidden, model.QRRecordFieldTea) {
+		tea, err := h.tea.Get(ctx, rec.Tea)
+		if err != nil {
+			return v, err
+		}
+
+		tags, err := h.tags.ListByTea(ctx, rec.Tea)
+		if err != nil {
+			return v, err
+		}
+
+		v.Title = tea.Name
+		v.Type = tea.Type.String()
+		v.Description = tea.Description
+		for _, tag := range tags {
+			v.Tags = append(v.Tags, tagView{Name: tag.Name, Color: safeColor(tag.Color)})
+		}
+	}
+	if !slices.Contains(hidden, model.QRRecordFieldBowlingTemp) {
+		v.BrewingTemp = rec.BowlingTemp
+	}
+	if !slices.Contains(hidden, model.QRRecordFieldExpirationDate) {
+		v.Expiration = rec.ExpirationDate.Format(dateLayout)
+	}
+
+	v.Summary = summary(v)
+
+	return v, nil
+}
+
+// appURL links to the record in the app through its custom URL scheme, or just opens the app for
+// uuid.Nil.
+func (h *Handler) appURL(id uuid.UUID) template.URL {
+	link := h.cfg.AppScheme + "://"
+	if id != uuid.Nil {
+		link += strings.TrimPrefix(qrlink.PathPrefix, "/") + qrlink.Encode(id)
+	}
+
+	// html/template only lets http(s) URLs through unless they are typed; the scheme is configured.
+	return template.URL(link) //nolint:gosec // scheme from configuration, code is base62
+}
+
+func (h *Handler) render(w http.ResponseWriter, status int, v *view) {
+	if v.Summary == "" {
+		v.Summary = v.Title
+	}
+	v.AppStoreURL = h.cfg.AppStoreURL
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, v); err != nil {
+		h.log.WithError(err).Error("execute landing template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes()) //nolint:errcheck // nothing to do if the client went away
+}
+
+func summary(v *view) string {
+	text := v.Description
+	if text == "" {
+		text = v.Type
+	}
+	if r := []rune(text); len(r) > summaryMaxLength {
+		text = string(r[:summaryMaxLength-1]) + "…"
+	}
+
+	return text
+}
+
+// safeColor lets hex tag colors (with or without #) through to the style attribute and
+// replaces anything else with a neutral gray.
+func safeColor(color string) template.CSS {
+	const fallback = "#6b6b6b"
+
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 0 || len(hex) > 8 {
+		return fallback
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return fallback
+		}
+	}
+
+	return template.CSS("#" + hex) //nolint:gosec // validated hex color above
+}
@@ -0,0 +1,65 @@
+package landing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
+)
+
+type records map[uuid.UUID]*common.QR
+
+func (r records) Get(_ context.Context, id uuid.UUID) (*common.QR, error) {
+	if rec, ok := r[id]; ok {
+		return rec, nil
+	}
+	return nil, common.ErrQRRecordNotExist
+}
+
+func (records) Hidden(*common.QR, *qr.Actor) []model.QRRecordField {
+	return nil
+}
+
+type teas struct{}
+
+func (teas) Get(_ context.Context, id uuid.UUID) (*common.Tea, error) {
+	return &common.Tea{ID: id, TeaData: &common.TeaData{Name: "Sencha"}}, nil
+}
+
+func (teas) ListByTea(context.Context, uuid.UUID) ([]common.Tag, error) {
+	return nil, nil
+}
+
+func serve(h *Handler, code string) *httptest.ResponseRecorder {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, qrlink.PathPrefix+code, nil), map[string]string{IDVar: code})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAppLinks(t *testing.T) {
+	id := uuid.New()
+	cfg := &Configuration{AppScheme: "teaelephant", AppStoreURL: "https://apps.apple.com/app/id1"}
+	h := NewHandler(records{id: {Tea: uuid.New()}}, teas{}, teas{}, cfg, "https://tea-elephant.com",
+		logrus.NewEntry(logrus.New()))
+
+	res := serve(h, qrlink.Encode(id))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `href="teaelephant://q/`+qrlink.Encode(id)+`"`)
+	assert.Contains(t, res.Body.String(), `href="https://apps.apple.com/app/id1"`)
+	assert.Contains(t, res.Body.String(), `content="https://tea-elephant.com/q/`+qrlink.Encode(id)+`"`)
+
+	res = serve(h, qrlink.Encode(uuid.New()))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), `href="teaelephant://"`)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
 	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
 )
@@ -30,10 +31,17 @@ type storage interface {
 	CollectionRecords(ctx context.Context, id uuid.UUID) ([]*common.CollectionRecord, error)
 }
 
+type qrManager interface {
+	Hidden(record *common.QR, actor *qr.Actor) []model.QRRecordField
+}
+
 type manager struct {
 	storage
+	qr qrManager
 }
 
+// ListRecords lists the records of a collection of userID. Anyone can add any QR code to a
+// collection, so the fields of records owned by someone else are hidden as they are on qrRecord.
 func (m *manager) ListRecords(ctx context.Context, id, userID uuid.UUID) ([]*model.QRRecord, error) {
 	if _, err := m.Collection(ctx, id, userID); err != nil {
 		return nil, err
@@ -45,12 +53,18 @@ func (m *manager) ListRecords(ctx context.Context, id, userID uuid.UUID) ([]*mod
 	}
 
 	list := make([]*model.QRRecord, len(records))
+	actor := &qr.Actor{UserID: &userID}
 	for i, record := range records {
 		list[i] = &model.QRRecord{
 			ID:             gqlCommon.ID(record.ID),
 			Tea:            model.FromCommonTea(record.Tea),
 			BowlingTemp:    record.BowlingTemp,
 			ExpirationDate: record.ExpirationDate,
+			Hidden:         m.qr.Hidden(&common.QR{Owner: record.Owner}, actor),
+		}
+		if record.Owner != nil {
+			owner := gqlCommon.ID(*record.Owner)
+			list[i].Owner = &owner
 		}
 	}
 
@@ -151,6 +165,7 @@ func (m *manager) List(ctx context.Context, userID uuid.UUID) ([]*model.Collecti
 	return result, nil
 }
 
-func NewManager(storage storage) Manager {
-	return &manager{storage: storage}
+// NewManager creates a collection manager; qrs decides which record fields a caller may see.
+func NewManager(storage storage, qrs qrManager) Manager {
+	return &manager{storage: storage, qr: qrs}
 }
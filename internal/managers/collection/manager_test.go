@@ -0,0 +1,52 @@
+package collection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+type recordStorage struct {
+	storage
+	records []*common.CollectionRecord
+}
+
+func (s *recordStorage) Collection(_ context.Context, id, _ uuid.UUID) (*common.Collection, error) {
+	return &common.Collection{ID: id}, nil
+}
+
+func (s *recordStorage) CollectionRecords(context.Context, uuid.UUID) ([]*common.CollectionRecord, error) {
+	return s.records, nil
+}
+
+func TestListRecords(t *testing.T) {
+	user, other := uuid.New(), uuid.New()
+	record := func(owner *uuid.UUID) *common.CollectionRecord {
+		return &common.CollectionRecord{
+			ID:    uuid.New(),
+			Tea:   &common.Tea{ID: uuid.New(), TeaData: &common.TeaData{Name: "Sencha"}},
+			Owner: owner,
+		}
+	}
+	own, foreign, unowned := record(&user), record(&other), record(nil)
+	st := &recordStorage{records: []*common.CollectionRecord{own, foreign, unowned}}
+	m := NewManager(st, qr.NewManager(nil, []string{string(model.QRRecordFieldTea)}))
+
+	list, err := m.ListRecords(context.Background(), uuid.New(), user)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	assert.Empty(t, list[0].Hidden)
+	assert.True(t, list[1].Visible(model.QRRecordFieldTea))
+	assert.False(t, list[1].Visible(model.QRRecordFieldBowlingTemp))
+	assert.False(t, list[1].Visible(model.QRRecordFieldExpirationDate))
+	assert.False(t, list[1].Visible(model.QRRecordFieldOwner))
+	assert.False(t, list[2].Visible(model.QRRecordFieldBowlingTemp))
+}
@@ -0,0 +1,65 @@
+package device
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// maxNameLength is the longest device name in characters.
+const maxNameLength = 64
+
+type Manager interface {
+	List(ctx context.Context, userID uuid.UUID) ([]common.Device, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (*common.Device, error)
+	// Revoke forgets the device; its push token is dropped and its sessions stop working.
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	RegisterToken(
+		ctx context.Context, userID, id uuid.UUID, token string, env common.DeviceEnvironment, info common.DeviceInfo,
+	) error
+}
+
+type storage interface {
+	Devices(ctx context.Context, userID uuid.UUID) ([]common.Device, error)
+	RenameDevice(ctx context.Context, userID, deviceID uuid.UUID, name string) (*common.Device, error)
+	DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+	CreateOrUpdateDeviceToken(
+		ctx context.Context, userID, deviceID uuid.UUID, deviceToken string, env common.DeviceEnvironment, info common.DeviceInfo,
+	) error
+}
+
+type manager struct {
+	storage
+}
+
+func (m *manager) List(ctx context.Context, userID uuid.UUID) ([]common.Device, error) {
+	return m.Devices(ctx, userID)
+}
+
+func (m *manager) Rename(ctx context.Context, userID, id uuid.UUID, name string) (*common.Device, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return nil, common.ErrInvalidDeviceName
+	}
+
+	return m.RenameDevice(ctx, userID, id, name)
+}
+
+func (m *manager) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return m.DeleteDevice(ctx, userID, id)
+}
+
+// RegisterToken stores the push token of a device signed in with the user.
+func (m *manager) RegisterToken(
+	ctx context.Context, userID, id uuid.UUID, token string, env common.DeviceEnvironment, info common.DeviceInfo,
+) error {
+	return m.CreateOrUpdateDeviceToken(ctx, userID, id, token, env, info)
+}
+
+func NewManager(storage storage) Manager {
+	return &manager{storage: storage}
+}
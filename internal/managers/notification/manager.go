@@ -2,36 +2,371 @@ package notification
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/common/subscribers"
+)
+
+const (
+	// DefaultPageSize is the number of notifications returned when the caller does not ask for a size.
+	DefaultPageSize = 50
+	maxPageSize     = 100
+
+	// dispatchInterval is how often deferred notifications are checked for being due.
+	dispatchInterval = time.Minute
+	dispatchBatch    = 100
+	// dispatchClaim is how long a failed push of a deferred notification waits to be retried.
+	dispatchClaim = 5 * time.Minute
+	// dispatchAttempts is how often the push of a deferred notification is tried.
+	dispatchAttempts = 5
+
+	// subscriberBuffer holds the notifications that arrive while a session still receives its backlog
+	// of up to maxPageSize; a session that falls further behind misses live notifications (they stay
+	// in the inbox).
+	subscriberBuffer = maxPageSize
+	// listenRetry is how long the listener waits before it reconnects.
+	listenRetry = 5 * time.Second
 )
 
 type Manager interface {
-	BindDevice(ctx context.Context, userID uuid.UUID, deviceID uuid.UUID) error
-	RegisterDeviceToken(ctx context.Context, deviceID uuid.UUID, deviceToken string) error
-	Notifications(ctx context.Context, userID uuid.UUID) ([]common.Notification, error)
+	Send(ctx context.Context, n *common.Notification) error
+	SendNow(ctx context.Context, n *common.Notification) error
+	Notifications(ctx context.Context, userID uuid.UUID, first *int, after *uuid.UUID) ([]common.Notification, error)
+	UnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error)
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int, error)
+	Preferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error)
+	SetPreferences(ctx context.Context, p *common.NotificationPreferences) error
+	Routes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error)
+	SetRoutes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) ([]common.NotificationRoute, error)
+	VerifyEmail(ctx context.Context, userID uuid.UUID, address, code string) (*common.NotificationRoute, error)
+	Subscribe(ctx context.Context, userID uuid.UUID, backlog bool) (<-chan *common.Notification, error)
+	Start()
 }
 
 type repository interface {
-	CreateOrUpdateDeviceToken(ctx context.Context, deviceID uuid.UUID, deviceToken string) error
-	AddDeviceForUser(ctx context.Context, userID, deviceID uuid.UUID) error
-	Notifications(ctx context.Context, userID uuid.UUID) ([]common.Notification, error)
+	CreateNotification(ctx context.Context, n *common.Notification) (created bool, err error)
+	AddNotificationDeliveries(ctx context.Context, id uuid.UUID, deliveries []common.NotificationDelivery) error
+	Notifications(ctx context.Context, userID uuid.UUID, limit int, after *uuid.UUID) ([]common.Notification, error)
+	UnreadNotificationList(ctx context.Context, userID uuid.UUID, limit int) ([]common.Notification, error)
+	UnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int, error)
+	ClaimDueNotifications(ctx context.Context, limit int, claim time.Duration) ([]common.DueNotification, error)
+	CompleteDeferredNotification(ctx context.Context, id uuid.UUID) error
+	Notification(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error)
+	PublishNotification(ctx context.Context, n *common.Notification) error
+	ListenNotifications(ctx context.Context, fn func(ctx context.Context, userID, id uuid.UUID)) error
+	NotificationPreferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, p *common.NotificationPreferences) error
+	NotificationRoutes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error)
+	SaveNotificationRoutes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) error
+	SetEmailVerificationCode(ctx context.Context, userID uuid.UUID, address string, codeHash []byte, resend time.Duration) (bool, error)
+	ClaimEmailVerificationAttempt(ctx context.Context, userID uuid.UUID, address string) (*common.EmailVerification, error)
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, address string) error
+}
+
+type pusher interface {
+	Push(ctx context.Context, n *common.Notification) ([]common.NotificationDelivery, error)
 }
 
 type manager struct {
 	repository
+	pusher
+	// mailer confirms email routes; nil if the email channel is not configured.
+	mailer mailer
+
+	subscribers subscribers.KeyedSubscribers[uuid.UUID, *common.Notification]
+
+	log *logrus.Entry
+}
+
+// Send records n in the user's inbox and pushes it, unless the user disabled its type. During quiet
+// hours or before the preferred delivery time the push is deferred and sent later by the
+// dispatcher. n is updated with the stored id, creation time and deliveries. Sending n again with
+// the same SourceKey does not add it to the inbox twice.
+func (m *manager) Send(ctx context.Context, n *common.Notification) error {
+	return m.send(ctx, n, true)
+}
+
+// SendNow is Send for pushes the user timed themselves, such as the Tea of the Day: quiet hours and
+// the preferred delivery time do not defer them.
+func (m *manager) SendNow(ctx context.Context, n *common.Notification) error {
+	return m.send(ctx, n, false)
+}
+
+func (m *manager) send(ctx context.Context, n *common.Notification, deferrable bool) error {
+	prefs, err := m.NotificationPreferences(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	if !prefs.Enabled(n.Type) {
+		return nil
+	}
+
+	now := time.Now()
+	if at := prefs.Schedule(now); deferrable && at.After(now) {
+		n.DeliverAt = &at
+	}
+
+	created, err := m.CreateNotification(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	// A retry of a send that reached the inbox only pushes again if no push was recorded; a
+	// deferred push is left to the dispatcher.
+	if !created {
+		if n.DeliverAt != nil || len(n.Deliveries) > 0 {
+			return nil
+		}
+		return m.push(ctx, n)
+	}
+
+	if n.DeliverAt != nil {
+		return nil
+	}
+
+	m.publish(ctx, n)
+
+	return m.push(ctx, n)
+}
+
+// publish announces n to every replica, which stream it to the user's sessions subscribed there.
+// A failure only costs the live update; n is in the inbox either way.
+func (m *manager) publish(ctx context.Context, n *common.Notification) {
+	if err := m.PublishNotification(ctx, n); err != nil {
+		m.log.WithError(err).WithField("notification", n.ID).Warn("publish notification")
+	}
+}
+
+// listen streams the notifications published by any replica to the sessions subscribed here. It
+// reconnects after listenRetry when the connection fails.
+func (m *manager) listen() {
+	for {
+		err := m.ListenNotifications(context.Background(), m.receive)
+		m.log.WithError(err).Warn("listen for notifications, reconnecting")
+		time.Sleep(listenRetry)
+	}
+}
+
+// receive loads a published notification for the user's sessions subscribed to this replica.
+func (m *manager) receive(ctx context.Context, userID, id uuid.UUID) {
+	if !m.subscribers.Has(userID) {
+		return
+	}
+
+	n, err := m.Notification(ctx, userID, id)
+	if err != nil {
+		m.log.WithError(err).WithField("notification", id).Warn("load published notification")
+		return
+	}
+
+	m.subscribers.Send(userID, n)
+}
+
+func (m *manager) push(ctx context.Context, n *common.Notification) error {
+	deliveries, err := m.Push(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	n.Deliveries = deliveries
+
+	return m.AddNotificationDeliveries(ctx, n.ID, deliveries)
+}
+
+// Notifications returns a page of the user's notifications, newest first, starting after the
+// notification with id after.
+func (m *manager) Notifications(ctx context.Context, userID uuid.UUID, first *int, after *uuid.UUID) ([]common.Notification, error) {
+	limit := DefaultPageSize
+	if first != nil {
+		limit = min(max(*first, 1), maxPageSize)
+	}
+
+	return m.repository.Notifications(ctx, userID, limit, after)
 }
 
-func (m *manager) BindDevice(ctx context.Context, userID uuid.UUID, deviceID uuid.UUID) error {
-	return m.AddDeviceForUser(ctx, userID, deviceID)
+func (m *manager) UnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return m.UnreadNotifications(ctx, userID)
 }
 
-func (m *manager) RegisterDeviceToken(ctx context.Context, deviceID uuid.UUID, deviceToken string) error {
-	return m.CreateOrUpdateDeviceToken(ctx, deviceID, deviceToken)
+func (m *manager) MarkRead(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error) {
+	return m.MarkNotificationRead(ctx, userID, id)
+}
+
+func (m *manager) MarkAllRead(ctx context.Context, userID uuid.UUID) (int, error) {
+	return m.MarkAllNotificationsRead(ctx, userID)
+}
+
+func (m *manager) Preferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error) {
+	return m.NotificationPreferences(ctx, userID)
+}
+
+func (m *manager) SetPreferences(ctx context.Context, p *common.NotificationPreferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	return m.SaveNotificationPreferences(ctx, p)
+}
+
+// Routes returns the channels the user routed notifications to; empty means the server default.
+func (m *manager) Routes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error) {
+	return m.NotificationRoutes(ctx, userID)
+}
+
+// SetRoutes replaces the user's routes and returns them as stored. Email addresses that are not
+// verified yet are mailed a code to confirm them with VerifyEmail.
+func (m *manager) SetRoutes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) ([]common.NotificationRoute, error) {
+	for _, r := range routes {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.SaveNotificationRoutes(ctx, userID, routes); err != nil {
+		return nil, err
+	}
+
+	stored, err := m.NotificationRoutes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.sendEmailCodes(ctx, userID, stored)
+
+	return stored, nil
+}
+
+// Subscribe streams the user's notifications as they reach the inbox until ctx is done. With
+// backlog the stream starts with the unread notifications, oldest first. Notifications created by
+// any replica are streamed.
+func (m *manager) Subscribe(ctx context.Context, userID uuid.UUID, backlog bool) (<-chan *common.Notification, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	live := make(chan *common.Notification, subscriberBuffer)
+	m.subscribers.Push(ctx, userID, live)
+
+	var unread []common.Notification
+	if backlog {
+		var err error
+		if unread, err = m.UnreadNotificationList(ctx, userID, maxPageSize); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	res := make(chan *common.Notification)
+
+	go func() {
+		defer cancel()
+		defer close(res)
+
+		// A notification created while the backlog was loaded arrives on live as well.
+		sent := make(map[uuid.UUID]bool, len(unread))
+		for i := range unread {
+			sent[unread[i].ID] = true
+			select {
+			case res <- &unread[i]:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case n, ok := <-live:
+				if !ok {
+					return
+				}
+				if sent[n.ID] {
+					continue
+				}
+				select {
+				case res <- n:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return res, nil
+}
+
+// Start runs the dispatcher that pushes deferred notifications once they are due, and the listener
+// that streams notifications to the subscribed sessions.
+func (m *manager) Start() {
+	go m.loop()
+	go m.listen()
+}
+
+func (m *manager) loop() {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.dispatch(context.Background()); err != nil {
+			m.log.WithError(err).Error("dispatch deferred notifications")
+		}
+
+		// remove closed connections
+		m.subscribers.CleanDone()
+	}
+}
+
+// dispatch pushes the due deferred notifications. A failed push is retried once its claim ran out,
+// up to dispatchAttempts times.
+func (m *manager) dispatch(ctx context.Context) error {
+	for {
+		due, err := m.ClaimDueNotifications(ctx, dispatchBatch, dispatchClaim)
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			n := &due[i].Notification
+			// The notification reached the inbox with the first attempt.
+			if due[i].Attempt == 1 {
+				m.publish(ctx, n)
+			}
+
+			if err = m.push(ctx, n); err != nil {
+				log := m.log.WithError(err).WithField("notification", n.ID).WithField("attempt", due[i].Attempt)
+				if due[i].Attempt < dispatchAttempts {
+					log.Warn("push deferred notification, retrying later")
+					continue
+				}
+				log.Error("push deferred notification, giving up")
+			}
+
+			if err = m.CompleteDeferredNotification(ctx, n.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(due) < dispatchBatch {
+			return nil
+		}
+	}
 }
 
-func NewManager(repository repository) Manager {
-	return &manager{repository: repository}
+// NewManager returns the notification manager. mailer confirms email routes and may be nil if the
+// email channel is not configured.
+func NewManager(repository repository, pusher pusher, mailer mailer, log *logrus.Entry) Manager {
+	return &manager{
+		repository:  repository,
+		pusher:      pusher,
+		mailer:      mailer,
+		subscribers: subscribers.NewKeyedSubscribers[uuid.UUID, *common.Notification](),
+		log:         log,
+	}
 }
@@ -0,0 +1,308 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+var errPush = errors.New("push failed")
+
+type dueRepository struct {
+	repository
+	due       []common.DueNotification
+	completed []uuid.UUID
+}
+
+func (r *dueRepository) ClaimDueNotifications(context.Context, int, time.Duration) ([]common.DueNotification, error) {
+	due := r.due
+	r.due = nil
+	return due, nil
+}
+
+func (r *dueRepository) CompleteDeferredNotification(_ context.Context, id uuid.UUID) error {
+	r.completed = append(r.completed, id)
+	return nil
+}
+
+func (r *dueRepository) PublishNotification(context.Context, *common.Notification) error {
+	return nil
+}
+
+func (r *dueRepository) AddNotificationDeliveries(context.Context, uuid.UUID, []common.NotificationDelivery) error {
+	return nil
+}
+
+type failingPusher map[uuid.UUID]bool
+
+func (p failingPusher) Push(_ context.Context, n *common.Notification) ([]common.NotificationDelivery, error) {
+	if p[n.ID] {
+		return nil, errPush
+	}
+	return nil, nil
+}
+
+func TestDispatch(t *testing.T) {
+	due := func(attempt int) common.DueNotification {
+		return common.DueNotification{Notification: common.Notification{ID: uuid.New()}, Attempt: attempt}
+	}
+	sent, failed, exhausted := due(1), due(1), due(dispatchAttempts)
+	repo := &dueRepository{due: []common.DueNotification{sent, failed, exhausted}}
+	pusher := failingPusher{failed.ID: true, exhausted.ID: true}
+	m := NewManager(repo, pusher, nil, logrus.NewEntry(logrus.New())).(*manager)
+
+	require.NoError(t, m.dispatch(context.Background()))
+
+	// The failed push keeps its deliver_at and is claimed again later; the exhausted one is given up.
+	assert.Equal(t, []uuid.UUID{sent.ID, exhausted.ID}, repo.completed)
+}
+
+type publishedRepository struct {
+	repository
+	stored map[uuid.UUID]common.Notification
+	loads  int
+}
+
+func (r *publishedRepository) Notification(_ context.Context, userID, id uuid.UUID) (*common.Notification, error) {
+	r.loads++
+	n, ok := r.stored[id]
+	if !ok || n.UserID != userID {
+		return nil, common.ErrNotificationNotFound
+	}
+	return &n, nil
+}
+
+func TestReceive(t *testing.T) {
+	user, other := uuid.New(), uuid.New()
+	n := common.Notification{ID: uuid.New(), UserID: user, Title: "Tea expires soon"}
+	repo := &publishedRepository{stored: map[uuid.UUID]common.Notification{n.ID: n}}
+	m := NewManager(repo, failingPusher{}, nil, logrus.NewEntry(logrus.New())).(*manager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := m.Subscribe(ctx, user, false)
+	require.NoError(t, err)
+
+	// A notification of a user without sessions on this replica is not loaded.
+	m.receive(ctx, other, uuid.New())
+	assert.Zero(t, repo.loads)
+
+	m.receive(ctx, user, n.ID)
+	select {
+	case got := <-stream:
+		assert.Equal(t, n.ID, got.ID)
+		assert.Equal(t, n.Title, got.Title)
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+	}
+}
+
+type sendRepository struct {
+	repository
+	prefs     *common.NotificationPreferences
+	created   []common.Notification
+	published int
+}
+
+func (r *sendRepository) NotificationPreferences(context.Context, uuid.UUID) (*common.NotificationPreferences, error) {
+	return r.prefs, nil
+}
+
+func (r *sendRepository) CreateNotification(_ context.Context, n *common.Notification) (bool, error) {
+	for _, c := range r.created {
+		if n.SourceKey != "" && c.SourceKey == n.SourceKey {
+			*n = c
+			return false, nil
+		}
+	}
+	n.ID = uuid.New()
+	r.created = append(r.created, *n)
+	return true, nil
+}
+
+func (r *sendRepository) PublishNotification(context.Context, *common.Notification) error {
+	r.published++
+	return nil
+}
+
+func (r *sendRepository) AddNotificationDeliveries(context.Context, uuid.UUID, []common.NotificationDelivery) error {
+	return nil
+}
+
+func TestSendNow(t *testing.T) {
+	user := uuid.New()
+	prefs := common.DefaultNotificationPreferences(user)
+	later := common.TimeOfDay(time.Now().UTC().Add(2*time.Hour).Hour() * 60)
+	prefs.DeliveryTime = &later
+	repo := &sendRepository{prefs: prefs}
+	m := NewManager(repo, failingPusher{}, nil, logrus.NewEntry(logrus.New())).(*manager)
+
+	require.NoError(t, m.Send(context.Background(), &common.Notification{UserID: user}))
+	require.NoError(t, m.SendNow(context.Background(), &common.Notification{UserID: user}))
+
+	require.Len(t, repo.created, 2)
+	assert.NotNil(t, repo.created[0].DeliverAt)
+	assert.Nil(t, repo.created[1].DeliverAt)
+}
+
+// countingPusher counts the pushes; while fail is set, they fail.
+type countingPusher struct {
+	fail   bool
+	pushed int
+}
+
+func (p *countingPusher) Push(context.Context, *common.Notification) ([]common.NotificationDelivery, error) {
+	if p.fail {
+		return nil, errPush
+	}
+	p.pushed++
+	return nil, nil
+}
+
+func TestSend_Retry(t *testing.T) {
+	user := uuid.New()
+	repo := &sendRepository{prefs: common.DefaultNotificationPreferences(user)}
+	pusher := &countingPusher{fail: true}
+	m := NewManager(repo, pusher, nil, logrus.NewEntry(logrus.New())).(*manager)
+	ctx := context.Background()
+	key := "tea-of-the-day/2026-10-18"
+
+	require.ErrorIs(t, m.SendNow(ctx, &common.Notification{UserID: user, SourceKey: key}), errPush)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, 1, repo.published)
+
+	// The retry neither adds nor announces the notification again, but pushes it.
+	pusher.fail = false
+	require.NoError(t, m.SendNow(ctx, &common.Notification{UserID: user, SourceKey: key}))
+	assert.Len(t, repo.created, 1)
+	assert.Equal(t, 1, repo.published)
+	assert.Equal(t, 1, pusher.pushed)
+
+	// Once a push was recorded, a retry does nothing.
+	repo.created[0].Deliveries = []common.NotificationDelivery{{Channel: common.NotificationChannelAPNs}}
+	require.NoError(t, m.SendNow(ctx, &common.Notification{UserID: user, SourceKey: key}))
+	assert.Equal(t, 1, pusher.pushed)
+
+	// Notifications without a source key are never matched.
+	require.NoError(t, m.SendNow(ctx, &common.Notification{UserID: user}))
+	require.NoError(t, m.SendNow(ctx, &common.Notification{UserID: user}))
+	assert.Len(t, repo.created, 3)
+	assert.Equal(t, 3, pusher.pushed)
+}
+
+// verificationRepository keeps routes and email verifications in memory.
+type verificationRepository struct {
+	repository
+	routes        []common.NotificationRoute
+	verifications map[string]*common.EmailVerification
+	verified      map[string]bool
+}
+
+func (r *verificationRepository) SaveNotificationRoutes(_ context.Context, _ uuid.UUID, routes []common.NotificationRoute) error {
+	r.routes = routes
+	return nil
+}
+
+func (r *verificationRepository) NotificationRoutes(context.Context, uuid.UUID) ([]common.NotificationRoute, error) {
+	res := make([]common.NotificationRoute, len(r.routes))
+	for i, route := range r.routes {
+		route.Verified = route.Channel != common.NotificationChannelEmail || r.verified[route.Target]
+		res[i] = route
+	}
+	return res, nil
+}
+
+func (r *verificationRepository) SetEmailVerificationCode(
+	_ context.Context, _ uuid.UUID, address string, codeHash []byte, _ time.Duration,
+) (bool, error) {
+	if r.verified[address] {
+		return false, nil
+	}
+	r.verifications[address] = &common.EmailVerification{CodeHash: codeHash, SentAt: time.Now()}
+	return true, nil
+}
+
+func (r *verificationRepository) ClaimEmailVerificationAttempt(
+	_ context.Context, _ uuid.UUID, address string,
+) (*common.EmailVerification, error) {
+	v, ok := r.verifications[address]
+	if !ok || r.verified[address] {
+		return nil, common.ErrInvalidVerificationCode
+	}
+	v.Attempts++
+	res := *v
+	return &res, nil
+}
+
+func (r *verificationRepository) MarkEmailVerified(_ context.Context, _ uuid.UUID, address string) error {
+	r.verified[address] = true
+	return nil
+}
+
+var emailCode = regexp.MustCompile(`\b\d{6}\b`)
+
+// codeMailer keeps the last code mailed to each address.
+type codeMailer map[string]string
+
+func (m codeMailer) Deliver(_ context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error) {
+	m[target] = emailCode.FindString(n.Body)
+	return nil, nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	user := uuid.New()
+	ctx := context.Background()
+	repo := &verificationRepository{
+		verifications: map[string]*common.EmailVerification{},
+		verified:      map[string]bool{"old@example.com": true},
+	}
+	mails := codeMailer{}
+	m := NewManager(repo, failingPusher{}, mails, logrus.NewEntry(logrus.New())).(*manager)
+
+	routes, err := m.SetRoutes(ctx, user, []common.NotificationRoute{
+		{Channel: common.NotificationChannelInbox},
+		{Channel: common.NotificationChannelEmail, Target: "old@example.com"},
+		{Channel: common.NotificationChannelEmail, Target: "new@example.com"},
+		{Channel: common.NotificationChannelEmail, Target: "other@example.com"},
+	})
+	require.NoError(t, err)
+	assert.True(t, routes[0].Verified)
+	assert.True(t, routes[1].Verified)
+	assert.False(t, routes[2].Verified)
+	require.Len(t, mails, 2, "codes go to unverified addresses only")
+	assert.Len(t, mails["new@example.com"], 6)
+
+	_, err = m.VerifyEmail(ctx, user, "new@example.com", mails["other@example.com"])
+	require.ErrorIs(t, err, common.ErrInvalidVerificationCode)
+	_, err = m.VerifyEmail(ctx, user, "unknown@example.com", mails["new@example.com"])
+	require.ErrorIs(t, err, common.ErrInvalidVerificationCode)
+
+	route, err := m.VerifyEmail(ctx, user, "new@example.com", mails["new@example.com"])
+	require.NoError(t, err)
+	assert.True(t, route.Verified)
+	routes, err = m.Routes(ctx, user)
+	require.NoError(t, err)
+	assert.True(t, routes[2].Verified)
+
+	t.Run("expired", func(t *testing.T) {
+		repo.verifications["other@example.com"].SentAt = time.Now().Add(-emailCodeTTL - time.Minute)
+		_, err := m.VerifyEmail(ctx, user, "other@example.com", mails["other@example.com"])
+		require.ErrorIs(t, err, common.ErrInvalidVerificationCode)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		repo.verifications["other@example.com"].SentAt = time.Now()
+		repo.verifications["other@example.com"].Attempts = emailCodeAttempts
+		_, err := m.VerifyEmail(ctx, user, "other@example.com", mails["other@example.com"])
+		require.ErrorIs(t, err, common.ErrInvalidVerificationCode)
+	})
+}
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+const (
+	// emailCodeTTL is how long a mailed code can be used.
+	emailCodeTTL = time.Hour
+	// emailCodeResend is how long saving the routes again waits before it mails another code.
+	emailCodeResend = time.Minute
+	// emailCodeAttempts is how often a code can be guessed at before a new one has to be mailed.
+	emailCodeAttempts = 5
+)
+
+// mailer delivers a notification to an email address; it is the email channel of the notifier.
+type mailer interface {
+	Deliver(ctx context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error)
+}
+
+// sendEmailCodes mails a verification code to every email route that is not verified yet. A failure
+// is logged; saving the routes again after emailCodeResend mails another code.
+func (m *manager) sendEmailCodes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) {
+	if m.mailer == nil {
+		return
+	}
+
+	for _, r := range routes {
+		if r.Channel != common.NotificationChannelEmail || r.Verified {
+			continue
+		}
+		if err := m.sendEmailCode(ctx, userID, r.Target); err != nil {
+			m.log.WithError(err).WithField("user", userID).Warn("send email verification code")
+		}
+	}
+}
+
+func (m *manager) sendEmailCode(ctx context.Context, userID uuid.UUID, address string) error {
+	code, err := newEmailCode()
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256([]byte(code))
+	stored, err := m.SetEmailVerificationCode(ctx, userID, address, hash[:], emailCodeResend)
+	if err != nil || !stored {
+		return err
+	}
+
+	_, err = m.mailer.Deliver(ctx, &common.Notification{
+		UserID: userID,
+		Title:  "Confirm your email address",
+		Body: fmt.Sprintf("Your TeaElephant confirmation code is %s. It expires in %d minutes.",
+			code, int(emailCodeTTL/time.Minute)),
+	}, address)
+
+	return err
+}
+
+// VerifyEmail confirms the user's email route to address with the code mailed to it, so that
+// notifications are mailed there from now on.
+func (m *manager) VerifyEmail(ctx context.Context, userID uuid.UUID, address, code string) (*common.NotificationRoute, error) {
+	v, err := m.ClaimEmailVerificationAttempt(ctx, userID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(code))
+	if v.Attempts > emailCodeAttempts || time.Since(v.SentAt) > emailCodeTTL ||
+		subtle.ConstantTimeCompare(hash[:], v.CodeHash) != 1 {
+		return nil, common.ErrInvalidVerificationCode
+	}
+
+	if err = m.MarkEmailVerified(ctx, userID, address); err != nil {
+		return nil, err
+	}
+
+	return &common.NotificationRoute{Channel: common.NotificationChannelEmail, Target: address, Verified: true}, nil
+}
+
+// newEmailCode returns a random six-digit code.
+func newEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", fmt.Errorf("generate email code: %w", err)
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -6,45 +6,112 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
-	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
 )
 
+// Actor is the principal performing a QR operation: either an authenticated user or an admin.
+type Actor struct {
+	UserID *uuid.UUID
+	Admin  bool
+}
+
 type Manager interface {
-	Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData) (err error)
-	Get(ctx context.Context, id uuid.UUID) (*model.QRRecordData, error)
+	Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData, actor Actor) (err error)
+	Get(ctx context.Context, id uuid.UUID) (*common.QR, error)
+	Release(ctx context.Context, id uuid.UUID, actor Actor) error
+	Transfer(ctx context.Context, id uuid.UUID, to uuid.UUID, actor Actor) error
+	Hidden(record *common.QR, actor *Actor) []model.QRRecordField
+	Batches(ctx context.Context) ([]common.QRBatch, error)
+	Batch(ctx context.Context, id uuid.UUID) (*common.QRBatch, error)
 }
 
 type storage interface {
-	WriteQR(ctx context.Context, id uuid.UUID, data *common.QR) (err error)
+	WriteQR(ctx context.Context, id uuid.UUID, data *common.QR, force bool) (err error)
 	ReadQR(ctx context.Context, id uuid.UUID) (record *common.QR, err error)
+	SetQROwner(ctx context.Context, id uuid.UUID, owner, current *uuid.UUID, force bool) error
+	IsQRCodeIssued(ctx context.Context, code uuid.UUID) (bool, error)
+	ListQRBatches(ctx context.Context) ([]common.QRBatch, error)
+	GetQRBatch(ctx context.Context, id uuid.UUID) (*common.QRBatch, error)
 }
 
 type manager struct {
 	storage
+	public map[model.QRRecordField]bool
 }
 
-func (m *manager) Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData) (err error) {
+// Set writes a QR record. Only codes issued in a printed batch are accepted. A user becomes
+// the owner of a new or unowned record; an owned record may only be rewritten by its owner or an admin.
+func (m *manager) Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData, actor Actor) (err error) {
+	issued, err := m.IsQRCodeIssued(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !issued {
+		return common.ErrQRCodeNotIssued
+	}
+
 	return m.WriteQR(ctx, id, &common.QR{
 		Tea:            uuid.UUID(data.Tea),
 		BowlingTemp:    data.BowlingTemp,
 		ExpirationDate: data.ExpirationDate,
-	})
+		Owner:          actor.UserID,
+	}, actor.Admin)
 }
 
-func (m *manager) Get(ctx context.Context, id uuid.UUID) (*model.QRRecordData, error) {
-	data, err := m.ReadQR(ctx, id)
-	if err != nil {
-		return nil, err
+func (m *manager) Get(ctx context.Context, id uuid.UUID) (*common.QR, error) {
+	return m.ReadQR(ctx, id)
+}
+
+// Release drops the owner of a record so the next writer claims it.
+func (m *manager) Release(ctx context.Context, id uuid.UUID, actor Actor) error {
+	return m.SetQROwner(ctx, id, nil, actor.UserID, actor.Admin)
+}
+
+// Transfer hands a record over to another user.
+func (m *manager) Transfer(ctx context.Context, id uuid.UUID, to uuid.UUID, actor Actor) error {
+	return m.SetQROwner(ctx, id, &to, actor.UserID, actor.Admin)
+}
+
+// Batches lists printed QR batches with their utilization, newest first.
+func (m *manager) Batches(ctx context.Context) ([]common.QRBatch, error) {
+	return m.ListQRBatches(ctx)
+}
+
+func (m *manager) Batch(ctx context.Context, id uuid.UUID) (*common.QRBatch, error) {
+	return m.GetQRBatch(ctx, id)
+}
+
+// Hidden returns the fields of record that actor may not see. Owners and admins see everything,
+// everyone else (including anonymous callers, actor == nil) only the configured public fields.
+func (m *manager) Hidden(record *common.QR, actor *Actor) []model.QRRecordField {
+	if actor != nil {
+		if actor.Admin || (record.Owner != nil && actor.UserID != nil && *record.Owner == *actor.UserID) {
+			return nil
+		}
 	}
 
-	return &model.QRRecordData{
-		Tea:            gqlCommon.ID(data.Tea),
-		BowlingTemp:    data.BowlingTemp,
-		ExpirationDate: data.ExpirationDate,
-	}, nil
+	var res []model.QRRecordField
+	for _, field := range []model.QRRecordField{
+		model.QRRecordFieldTea,
+		model.QRRecordFieldBowlingTemp,
+		model.QRRecordFieldExpirationDate,
+		model.QRRecordFieldOwner,
+	} {
+		if !m.public[field] {
+			res = append(res, field)
+		}
+	}
+
+	return res
 }
 
-func NewManager(storage storage) Manager {
-	return &manager{storage: storage}
+// NewManager creates a QR manager. publicFields lists the QRRecord fields visible to callers
+// that do not own a record.
+func NewManager(storage storage, publicFields []string) Manager {
+	public := make(map[model.QRRecordField]bool, len(publicFields))
+	for _, field := range publicFields {
+		public[model.QRRecordField(field)] = true
+	}
+
+	return &manager{storage: storage, public: public}
 }
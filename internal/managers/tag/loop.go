@@ -28,6 +28,10 @@ func (m *manager) loop() {
 			m.handleTeaTagChange(id, true)
 		case id := <-m.deleteTagFromTea:
 			m.handleTeaTagChange(id, false)
+		case change := <-m.teaTagsChanged:
+			// One consolidated event per tea: the payload carries the full tag set, so
+			// a change that added anything is reported as an addition.
+			m.handleTeaTagChange(change.TeaID, len(change.Added) > 0)
 		}
 
 		m.cleanAll()
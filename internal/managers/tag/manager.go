@@ -2,6 +2,7 @@ package tag
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 
@@ -32,12 +33,20 @@ type Manager interface {
 	List(ctx context.Context, name *string, categoryID *uuid.UUID) (list []common.Tag, err error)
 	AddTagToTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
 	DeleteTagFromTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
+	SetTeaTags(ctx context.Context, tea uuid.UUID, tags []uuid.UUID) error
+	BulkTag(ctx context.Context, teas, add, remove []uuid.UUID) error
 	SubscribeOnCreate(ctx context.Context) (<-chan *model.Tag, error)
 	SubscribeOnUpdate(ctx context.Context) (<-chan *model.Tag, error)
 	SubscribeOnDelete(ctx context.Context) (<-chan gqlCommon.ID, error)
 	SubscribeOnAddTagToTea(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnDeleteTagToTea(ctx context.Context) (<-chan *model.Tea, error)
 	ListByTea(ctx context.Context, id uuid.UUID) (list []common.Tag, err error)
+	SetParent(ctx context.Context, id uuid.UUID, parentID *uuid.UUID) (*common.Tag, error)
+	Children(ctx context.Context, id uuid.UUID) (list []common.Tag, err error)
+	AddSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error)
+	DeleteSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error)
+	Synonyms(ctx context.Context, id uuid.UUID) ([]string, error)
+	Merge(ctx context.Context, source, target uuid.UUID) (*common.Tag, error)
 	Start()
 }
 
@@ -55,7 +64,16 @@ type storage interface {
 	ListTags(ctx context.Context, name *string, categoryID *uuid.UUID) (list []common.Tag, err error)
 	AddTagToTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
 	DeleteTagFromTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
+	SetTeaTags(ctx context.Context, tea uuid.UUID, tags []uuid.UUID) (*common.TeaTagsChange, error)
+	BulkTag(ctx context.Context, teas, add, remove []uuid.UUID) ([]common.TeaTagsChange, error)
 	ListByTea(ctx context.Context, id uuid.UUID) ([]common.Tag, error)
+	SetTagParent(ctx context.Context, id uuid.UUID, parentID *uuid.UUID) (*common.Tag, error)
+	ListTagChildren(ctx context.Context, id uuid.UUID) ([]common.Tag, error)
+	AddTagSynonym(ctx context.Context, id uuid.UUID, name string) error
+	DeleteTagSynonym(ctx context.Context, id uuid.UUID, name string) error
+	ListTagSynonyms(ctx context.Context, id uuid.UUID) ([]string, error)
+	ResolveTagSynonym(ctx context.Context, name string, categoryID uuid.UUID) (*common.Tag, error)
+	MergeTags(ctx context.Context, source, target uuid.UUID) (*common.Tag, error)
 }
 
 type logger interface {
@@ -85,6 +103,7 @@ type manager struct {
 
 	addTagToTea               chan uuid.UUID
 	deleteTagFromTea          chan uuid.UUID
+	teaTagsChanged            chan *common.TeaTagsChange
 	addTagToTeaSubscribers    subscribers2.TeaSubscribers
 	deleteTagToTeaSubscribers subscribers2.TeaSubscribers
 
@@ -111,6 +130,34 @@ func (m *manager) DeleteTagFromTea(ctx context.Context, tea uuid.UUID, tag uuid.
 	return nil
 }
 
+// SetTeaTags replaces the tags of a tea atomically and emits at most one event for it.
+func (m *manager) SetTeaTags(ctx context.Context, tea uuid.UUID, tags []uuid.UUID) error {
+	change, err := m.storage.SetTeaTags(ctx, tea, tags)
+	if err != nil {
+		return err
+	}
+
+	if !change.Empty() {
+		m.teaTagsChanged <- change
+	}
+
+	return nil
+}
+
+// BulkTag adds and removes tags on several teas atomically and emits one event per changed tea.
+func (m *manager) BulkTag(ctx context.Context, teas, add, remove []uuid.UUID) error {
+	changes, err := m.storage.BulkTag(ctx, teas, add, remove)
+	if err != nil {
+		return err
+	}
+
+	for i := range changes {
+		m.teaTagsChanged <- &changes[i]
+	}
+
+	return nil
+}
+
 func (m *manager) ListByTea(ctx context.Context, id uuid.UUID) (list []common.Tag, err error) {
 	return m.storage.ListByTea(ctx, id)
 }
@@ -150,6 +197,16 @@ func (m *manager) ListCategory(ctx context.Context, search *string) (list []comm
 }
 
 func (m *manager) Create(ctx context.Context, name, color string, categoryID uuid.UUID) (*common.Tag, error) {
+	// A name that is a synonym of an existing tag in the category resolves to that canonical tag.
+	canonical, err := m.ResolveTagSynonym(ctx, name, categoryID)
+	if err == nil {
+		return canonical, nil
+	}
+
+	if !errors.Is(err, common.ErrTagNotFound) {
+		return nil, err
+	}
+
 	tag, err := m.CreateTag(ctx, name, color, categoryID)
 	if err != nil {
 		return nil, err
@@ -200,6 +257,57 @@ func (m *manager) List(ctx context.Context, name *string, categoryID *uuid.UUID)
 	return m.ListTags(ctx, name, categoryID)
 }
 
+func (m *manager) SetParent(ctx context.Context, id uuid.UUID, parentID *uuid.UUID) (*common.Tag, error) {
+	tag, err := m.SetTagParent(ctx, id, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.update <- tag
+
+	return tag, nil
+}
+
+func (m *manager) Children(ctx context.Context, id uuid.UUID) (list []common.Tag, err error) {
+	return m.ListTagChildren(ctx, id)
+}
+
+func (m *manager) AddSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error) {
+	if err := m.AddTagSynonym(ctx, id, name); err != nil {
+		return nil, err
+	}
+
+	return m.GetTag(ctx, id)
+}
+
+func (m *manager) DeleteSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error) {
+	if err := m.DeleteTagSynonym(ctx, id, name); err != nil {
+		return nil, err
+	}
+
+	return m.GetTag(ctx, id)
+}
+
+func (m *manager) Synonyms(ctx context.Context, id uuid.UUID) ([]string, error) {
+	return m.ListTagSynonyms(ctx, id)
+}
+
+func (m *manager) Merge(ctx context.Context, source, target uuid.UUID) (*common.Tag, error) {
+	if source == target {
+		return nil, common.ErrTagMergeSelf
+	}
+
+	tag, err := m.MergeTags(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	m.delete <- source
+	m.update <- tag
+
+	return tag, nil
+}
+
 func (m *manager) UpdateCategory(ctx context.Context, id uuid.UUID, name string) (category *common.TagCategory, err error) {
 	if err = m.UpdateTagCategory(ctx, id, name); err != nil {
 		return nil, err
@@ -215,56 +323,56 @@ func (m *manager) UpdateCategory(ctx context.Context, id uuid.UUID, name string)
 }
 
 func (m *manager) SubscribeOnCreateCategory(ctx context.Context) (<-chan *model.TagCategory, error) {
-	ch := make(chan *model.TagCategory)
+	ch := make(chan *model.TagCategory, defaultChanSize)
 	m.createSubscribersCategory.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnUpdateCategory(ctx context.Context) (<-chan *model.TagCategory, error) {
-	ch := make(chan *model.TagCategory)
+	ch := make(chan *model.TagCategory, defaultChanSize)
 	m.updateSubscribersCategory.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnDeleteCategory(ctx context.Context) (<-chan gqlCommon.ID, error) {
-	ch := make(chan gqlCommon.ID)
+	ch := make(chan gqlCommon.ID, defaultChanSize)
 	m.deleteSubscribersCategory.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnCreate(ctx context.Context) (<-chan *model.Tag, error) {
-	ch := make(chan *model.Tag)
+	ch := make(chan *model.Tag, defaultChanSize)
 	m.createSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnUpdate(ctx context.Context) (<-chan *model.Tag, error) {
-	ch := make(chan *model.Tag)
+	ch := make(chan *model.Tag, defaultChanSize)
 	m.updateSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnDelete(ctx context.Context) (<-chan gqlCommon.ID, error) {
-	ch := make(chan gqlCommon.ID)
+	ch := make(chan gqlCommon.ID, defaultChanSize)
 	m.deleteSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnAddTagToTea(ctx context.Context) (<-chan *model.Tea, error) {
-	ch := make(chan *model.Tea)
+	ch := make(chan *model.Tea, defaultChanSize)
 	m.addTagToTeaSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnDeleteTagToTea(ctx context.Context) (<-chan *model.Tea, error) {
-	ch := make(chan *model.Tea)
+	ch := make(chan *model.Tea, defaultChanSize)
 	m.deleteTagToTeaSubscribers.Push(ctx, ch)
 
 	return ch, nil
@@ -294,6 +402,7 @@ func NewManager(storage storage, teaManager teaManager, log logger) Manager {
 		deleteCategory:            make(chan uuid.UUID, defaultChanSize),
 		addTagToTea:               make(chan uuid.UUID, defaultChanSize),
 		deleteTagFromTea:          make(chan uuid.UUID, defaultChanSize),
+		teaTagsChanged:            make(chan *common.TeaTagsChange, defaultChanSize),
 		log:                       log,
 	}
 }
@@ -0,0 +1,114 @@
+package tag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+// noEventWait is how long a test waits to be sure that no event is sent.
+const noEventWait = 50 * time.Millisecond
+
+type bulkStorage struct {
+	storage
+	changes []common.TeaTagsChange
+}
+
+func (s *bulkStorage) SetTeaTags(_ context.Context, tea uuid.UUID, _ []uuid.UUID) (*common.TeaTagsChange, error) {
+	for i := range s.changes {
+		if s.changes[i].TeaID == tea {
+			return &s.changes[i], nil
+		}
+	}
+	return &common.TeaTagsChange{TeaID: tea}, nil
+}
+
+func (s *bulkStorage) BulkTag(context.Context, []uuid.UUID, []uuid.UUID, []uuid.UUID) ([]common.TeaTagsChange, error) {
+	return s.changes, nil
+}
+
+type teas struct{}
+
+func (teas) Get(_ context.Context, id uuid.UUID) (*common.Tea, error) {
+	return &common.Tea{ID: id, TeaData: &common.TeaData{Name: id.String()}}, nil
+}
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Error(err ...interface{}) {
+	l.t.Error(err...)
+}
+
+func newBulkManager(t *testing.T, changes ...common.TeaTagsChange) (Manager, <-chan *model.Tea, <-chan *model.Tea) {
+	t.Helper()
+	m := NewManager(&bulkStorage{changes: changes}, teas{}, testLogger{t})
+	m.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	added, err := m.SubscribeOnAddTagToTea(ctx)
+	require.NoError(t, err)
+	removed, err := m.SubscribeOnDeleteTagToTea(ctx)
+	require.NoError(t, err)
+
+	return m, added, removed
+}
+
+func receive(t *testing.T, ch <-chan *model.Tea) gqlCommon.ID {
+	t.Helper()
+	select {
+	case tea := <-ch:
+		return tea.ID
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return gqlCommon.ID{}
+	}
+}
+
+func assertNoEvent(t *testing.T, added, removed <-chan *model.Tea) {
+	t.Helper()
+	select {
+	case tea := <-added:
+		t.Errorf("unexpected add event for %v", tea.ID)
+	case tea := <-removed:
+		t.Errorf("unexpected delete event for %v", tea.ID)
+	case <-time.After(noEventWait):
+	}
+}
+
+func TestSetTeaTags(t *testing.T) {
+	changed, unchanged := uuid.New(), uuid.New()
+	m, added, removed := newBulkManager(t, common.TeaTagsChange{
+		TeaID:   changed,
+		Added:   []uuid.UUID{uuid.New()},
+		Removed: []uuid.UUID{uuid.New(), uuid.New()},
+	})
+
+	require.NoError(t, m.SetTeaTags(context.Background(), changed, nil))
+	assert.Equal(t, gqlCommon.ID(changed), receive(t, added))
+	assertNoEvent(t, added, removed)
+
+	require.NoError(t, m.SetTeaTags(context.Background(), unchanged, nil))
+	assertNoEvent(t, added, removed)
+}
+
+func TestBulkTag(t *testing.T) {
+	tagged, untagged := uuid.New(), uuid.New()
+	m, added, removed := newBulkManager(t,
+		common.TeaTagsChange{TeaID: tagged, Added: []uuid.UUID{uuid.New(), uuid.New()}},
+		common.TeaTagsChange{TeaID: untagged, Removed: []uuid.UUID{uuid.New()}},
+	)
+
+	require.NoError(t, m.BulkTag(context.Background(), []uuid.UUID{tagged, untagged}, nil, nil))
+	assert.Equal(t, gqlCommon.ID(tagged), receive(t, added))
+	assert.Equal(t, gqlCommon.ID(untagged), receive(t, removed))
+	assertNoEvent(t, added, removed)
+}
@@ -2,21 +2,30 @@ package tea
 
 import (
 	"context"
+	"slices"
 
 	"github.com/google/uuid"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/dedup"
 	subscribers2 "github.com/teaelephant/TeaElephantMemory/internal/managers/tea/subscribers"
 	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
 )
 
+const (
+	defaultChanSize = 100
+)
+
 type Manager interface {
 	Create(ctx context.Context, data *common.TeaData) (tea *common.Tea, err error)
 	Update(ctx context.Context, id uuid.UUID, rec *common.TeaData) (record *common.Tea, err error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	Get(ctx context.Context, id uuid.UUID) (record *common.Tea, err error)
 	List(ctx context.Context, search *string) ([]common.Tea, error)
+	ListByTag(ctx context.Context, tagID uuid.UUID, search *string) ([]common.Tea, error)
+	DuplicateCandidates(ctx context.Context, threshold *float64) ([]dedup.Pair, error)
+	Merge(ctx context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error)
 	SubscribeOnCreate(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnUpdate(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnDelete(ctx context.Context) (<-chan gqlCommon.ID, error)
@@ -27,8 +36,11 @@ type storage interface {
 	WriteRecord(ctx context.Context, rec *common.TeaData) (record *common.Tea, err error)
 	ReadRecord(ctx context.Context, id uuid.UUID) (record *common.Tea, err error)
 	ReadAllRecords(ctx context.Context, search string) ([]common.Tea, error)
+	ReadRecordsByTag(ctx context.Context, tagID uuid.UUID, search string) ([]common.Tea, error)
 	Update(ctx context.Context, id uuid.UUID, rec *common.TeaData) (record *common.Tea, err error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	ReadAllTeaTags(ctx context.Context) (map[uuid.UUID][]uuid.UUID, error)
+	MergeTeas(ctx context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error)
 }
 
 type manager struct {
@@ -42,21 +54,21 @@ type manager struct {
 }
 
 func (m *manager) SubscribeOnCreate(ctx context.Context) (<-chan *model.Tea, error) {
-	ch := make(chan *model.Tea)
+	ch := make(chan *model.Tea, defaultChanSize)
 	m.createSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnUpdate(ctx context.Context) (<-chan *model.Tea, error) {
-	ch := make(chan *model.Tea)
+	ch := make(chan *model.Tea, defaultChanSize)
 	m.updateSubscribers.Push(ctx, ch)
 
 	return ch, nil
 }
 
 func (m *manager) SubscribeOnDelete(ctx context.Context) (<-chan gqlCommon.ID, error) {
-	ch := make(chan gqlCommon.ID)
+	ch := make(chan gqlCommon.ID, defaultChanSize)
 	m.deleteSubscribers.Push(ctx, ch)
 
 	return ch, nil
@@ -74,6 +86,69 @@ func (m *manager) List(ctx context.Context, search *string) ([]common.Tea, error
 	return m.ReadAllRecords(ctx, *search)
 }
 
+// ListByTag returns teas tagged with tagID or any of its descendant tags.
+func (m *manager) ListByTag(ctx context.Context, tagID uuid.UUID, search *string) ([]common.Tea, error) {
+	if search == nil {
+		return m.ReadRecordsByTag(ctx, tagID, "")
+	}
+
+	return m.ReadRecordsByTag(ctx, tagID, *search)
+}
+
+// DuplicateCandidates scores every pair of teas and returns likely duplicates, best first.
+// A nil threshold falls back to dedup.DefaultThreshold.
+func (m *manager) DuplicateCandidates(ctx context.Context, threshold *float64) ([]dedup.Pair, error) {
+	teas, err := m.ReadAllRecords(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := m.ReadAllTeaTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dedup.Item, len(teas))
+	for i, t := range teas {
+		items[i] = dedup.Item{Tea: t, Tags: tags[t.ID]}
+	}
+
+	limit := dedup.DefaultThreshold
+	if threshold != nil {
+		limit = *threshold
+	}
+
+	return dedup.Find(items, limit), nil
+}
+
+// Merge folds the merge teas into keep and emits a delete event per merged tea and an
+// update event for the kept one.
+func (m *manager) Merge(ctx context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error) {
+	if slices.Contains(merge, keep) {
+		return nil, common.ErrTeaMergeSelf
+	}
+	seen := make(map[uuid.UUID]struct{}, len(merge))
+	ids := make([]uuid.UUID, 0, len(merge))
+	for _, id := range merge {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	res, err := m.MergeTeas(ctx, keep, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		m.delete <- id
+	}
+	m.update <- res
+
+	return res, nil
+}
+
 func (m *manager) Create(ctx context.Context, data *common.TeaData) (*common.Tea, error) {
 	res, err := m.WriteRecord(ctx, data)
 	if err != nil {
@@ -116,8 +191,8 @@ func NewManager(storage storage) Manager {
 		createSubscribers: subscribers2.NewTeaSubscribers(),
 		updateSubscribers: subscribers2.NewTeaSubscribers(),
 		deleteSubscribers: subscribers2.NewIDSubscribers(),
-		create:            make(chan *common.Tea, 100),
-		update:            make(chan *common.Tea, 100),
-		delete:            make(chan uuid.UUID, 100),
+		create:            make(chan *common.Tea, defaultChanSize),
+		update:            make(chan *common.Tea, defaultChanSize),
+		delete:            make(chan uuid.UUID, defaultChanSize),
 	}
 }
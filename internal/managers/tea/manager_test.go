@@ -0,0 +1,140 @@
+package tea
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+// noEventWait is how long a test waits to be sure that no event is sent.
+const noEventWait = 50 * time.Millisecond
+
+// mergeStorage keeps teas and what points at them in memory the way MergeTeas changes the tables.
+type mergeStorage struct {
+	storage
+	teas         map[uuid.UUID]string
+	qrs          map[uuid.UUID]uuid.UUID   // qr id -> tea id
+	tags         map[uuid.UUID][]uuid.UUID // tea id -> tag ids
+	consumptions map[uuid.UUID]int         // tea id -> consumptions
+	merged       [][]uuid.UUID
+}
+
+func (s *mergeStorage) MergeTeas(_ context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error) {
+	s.merged = append(s.merged, merge)
+	for _, id := range append([]uuid.UUID{keep}, merge...) {
+		if _, ok := s.teas[id]; !ok {
+			return nil, common.ErrTeaNotFound
+		}
+	}
+	for qr, tea := range s.qrs {
+		if slices.Contains(merge, tea) {
+			s.qrs[qr] = keep
+		}
+	}
+	for _, id := range merge {
+		for _, tag := range s.tags[id] {
+			if !slices.Contains(s.tags[keep], tag) {
+				s.tags[keep] = append(s.tags[keep], tag)
+			}
+		}
+		s.consumptions[keep] += s.consumptions[id]
+		delete(s.tags, id)
+		delete(s.consumptions, id)
+		delete(s.teas, id)
+	}
+	return &common.Tea{ID: keep, TeaData: &common.TeaData{Name: s.teas[keep]}}, nil
+}
+
+func newMergeManager(t *testing.T, st *mergeStorage) (Manager, <-chan *model.Tea, <-chan gqlCommon.ID) {
+	t.Helper()
+	m := NewManager(st)
+	m.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	updated, err := m.SubscribeOnUpdate(ctx)
+	require.NoError(t, err)
+	deleted, err := m.SubscribeOnDelete(ctx)
+	require.NoError(t, err)
+
+	return m, updated, deleted
+}
+
+func TestMerge(t *testing.T) {
+	keep, dup1, dup2 := uuid.New(), uuid.New(), uuid.New()
+	green, roasted := uuid.New(), uuid.New()
+	qr1, qr2, qr3 := uuid.New(), uuid.New(), uuid.New()
+	newStorage := func() *mergeStorage {
+		return &mergeStorage{
+			teas:         map[uuid.UUID]string{keep: "Sencha", dup1: "sencha", dup2: "Sencha "},
+			qrs:          map[uuid.UUID]uuid.UUID{qr1: keep, qr2: dup1, qr3: dup2},
+			tags:         map[uuid.UUID][]uuid.UUID{keep: {green}, dup1: {green, roasted}},
+			consumptions: map[uuid.UUID]int{keep: 1, dup1: 2, dup2: 3},
+		}
+	}
+
+	t.Run("moves records and deletes the merged teas", func(t *testing.T) {
+		st := newStorage()
+		m, updated, deleted := newMergeManager(t, st)
+
+		tea, err := m.Merge(context.Background(), keep, []uuid.UUID{dup1, dup2, dup1})
+		require.NoError(t, err)
+		assert.Equal(t, keep, tea.ID)
+		assert.Equal(t, [][]uuid.UUID{{dup1, dup2}}, st.merged, "duplicates are merged once")
+
+		assert.Equal(t, map[uuid.UUID]uuid.UUID{qr1: keep, qr2: keep, qr3: keep}, st.qrs)
+		assert.ElementsMatch(t, []uuid.UUID{green, roasted}, st.tags[keep])
+		assert.Equal(t, map[uuid.UUID]int{keep: 6}, st.consumptions)
+		assert.Equal(t, map[uuid.UUID]string{keep: "Sencha"}, st.teas)
+
+		var gone []uuid.UUID
+		for range 2 {
+			select {
+			case id := <-deleted:
+				gone = append(gone, uuid.UUID(id))
+			case <-time.After(time.Second):
+				t.Fatal("no delete event")
+			}
+		}
+		assert.ElementsMatch(t, []uuid.UUID{dup1, dup2}, gone)
+		select {
+		case tea := <-updated:
+			assert.Equal(t, gqlCommon.ID(keep), tea.ID)
+		case <-time.After(time.Second):
+			t.Fatal("no update event")
+		}
+	})
+
+	t.Run("into itself", func(t *testing.T) {
+		st := newStorage()
+		m, _, _ := newMergeManager(t, st)
+
+		_, err := m.Merge(context.Background(), keep, []uuid.UUID{dup1, keep})
+		require.ErrorIs(t, err, common.ErrTeaMergeSelf)
+		assert.Empty(t, st.merged)
+	})
+
+	t.Run("unknown tea", func(t *testing.T) {
+		st := newStorage()
+		m, updated, deleted := newMergeManager(t, st)
+
+		_, err := m.Merge(context.Background(), keep, []uuid.UUID{dup1, uuid.New()})
+		require.ErrorIs(t, err, common.ErrTeaNotFound)
+		select {
+		case id := <-deleted:
+			t.Errorf("unexpected delete event for %v", id)
+		case tea := <-updated:
+			t.Errorf("unexpected update event for %v", tea.ID)
+		case <-time.After(noEventWait):
+		}
+	})
+}
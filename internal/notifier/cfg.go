@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Configuration controls routing, retries and the webhook and email channels.
+type Configuration struct {
+	// DefaultChannels are used for users who did not choose their own routes.
+	DefaultChannels []string `envconfig:"DEFAULT_CHANNELS" default:"apns,inbox"`
+	// RetryAttempts is the number of tries per channel; the backoff doubles after every failure.
+	RetryAttempts int           `envconfig:"RETRY_ATTEMPTS" default:"3"`
+	RetryBackoff  time.Duration `envconfig:"RETRY_BACKOFF" default:"1s"`
+	// WebhookSecret signs webhook requests; the webhook channel is disabled when empty.
+	WebhookSecret  string        `envconfig:"WEBHOOK_SECRET"`
+	WebhookTimeout time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	// SMTPAddr is the host:port of the mail server; the email channel is disabled when empty.
+	SMTPAddr     string        `envconfig:"SMTP_ADDR"`
+	SMTPUsername string        `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string        `envconfig:"SMTP_PASSWORD"`
+	SMTPFrom     string        `envconfig:"SMTP_FROM" default:"TeaElephant <noreply@tea-elephant.com>"`
+	SMTPTimeout  time.Duration `envconfig:"SMTP_TIMEOUT" default:"30s"`
+}
+
+// Config reads the configuration from NOTIFIER_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("NOTIFIER", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// headerReplacer keeps user text from breaking out of a header line.
+var headerReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+type email struct {
+	addr    string
+	auth    smtp.Auth
+	from    string
+	timeout time.Duration
+}
+
+// Deliver mails n to the target address. Permanent SMTP errors (5xx), e.g. an unknown mailbox, are
+// recorded as failed deliveries; anything else is retried.
+func (e *email) Deliver(ctx context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error) {
+	delivery := common.NotificationDelivery{
+		Channel:   common.NotificationChannelEmail,
+		Target:    target,
+		Status:    common.DeliveryStatusSent,
+		CreatedAt: time.Now(),
+	}
+
+	to, err := mail.ParseAddress(target)
+	if err != nil {
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, err.Error()
+		return []common.NotificationDelivery{delivery}, nil
+	}
+
+	err = e.send(ctx, envelope(e.from), to.Address, e.message(n, to.String()))
+
+	var smtpErr *textproto.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &smtpErr) && smtpErr.Code >= 500:
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, smtpErr.Error()
+	default:
+		return nil, err
+	}
+
+	return []common.NotificationDelivery{delivery}, nil
+}
+
+// send runs one SMTP transaction. The whole conversation, dial included, is bound by ctx and the
+// configured timeout.
+func (e *email) send(ctx context.Context, from, to string, msg []byte) error {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(e.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if err := c.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (e *email) message(n *common.Notification, to string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerReplacer.Replace(n.Title)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(n.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
+
+// envelope extracts the bare address from a From header such as "Name <addr>".
+func envelope(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return addr.Address
+	}
+
+	return from
+}
+
+// NewEmail returns a channel that mails notifications through the SMTP server at addr,
+// authenticating when username is set. A zero timeout leaves only the caller's context as a limit.
+func NewEmail(addr, username, password, from string, timeout time.Duration) Channel {
+	e := &email{addr: addr, from: from, timeout: timeout}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return e
+}
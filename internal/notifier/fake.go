@@ -0,0 +1,65 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// ErrFakeFailure is returned by Fake for the calls it was told to fail.
+var ErrFakeFailure = errors.New("fake channel failure")
+
+// FakeMessage is a notification delivered by Fake.
+type FakeMessage struct {
+	Notification common.Notification
+	Target       string
+}
+
+// Fake is an in-memory Channel for tests. It fails the first Failures calls and records the
+// notifications it delivers afterwards.
+type Fake struct {
+	Channel  common.NotificationChannel
+	Failures int
+
+	mu       sync.Mutex
+	calls    int
+	messages []FakeMessage
+}
+
+func (f *Fake) Deliver(_ context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls <= f.Failures {
+		return nil, ErrFakeFailure
+	}
+
+	f.messages = append(f.messages, FakeMessage{Notification: *n, Target: target})
+
+	return []common.NotificationDelivery{{
+		Channel:   f.Channel,
+		Target:    target,
+		Status:    common.DeliveryStatusSent,
+		CreatedAt: time.Now(),
+	}}, nil
+}
+
+// Calls is the number of Deliver calls, failed ones included.
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// Messages returns the delivered notifications.
+func (f *Fake) Messages() []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeMessage(nil), f.messages...)
+}
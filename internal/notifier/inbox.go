@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+type inbox struct{}
+
+// Deliver only records the delivery: notifications are stored in the user's inbox before they are
+// pushed.
+func (inbox) Deliver(_ context.Context, _ *common.Notification, _ string) ([]common.NotificationDelivery, error) {
+	return []common.NotificationDelivery{{
+		Channel:   common.NotificationChannelInbox,
+		Status:    common.DeliveryStatusSent,
+		CreatedAt: time.Now(),
+	}}, nil
+}
+
+// NewInbox returns the in-app channel.
+func NewInbox() Channel {
+	return inbox{}
+}
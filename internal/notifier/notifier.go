@@ -0,0 +1,143 @@
+// Package notifier delivers notifications over the channels each user routed them to.
+package notifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+var (
+	errChannelNotConfigured = errors.New("channel is not configured")
+	errEmailNotVerified     = errors.New("email address is not verified")
+)
+
+// Channel delivers notifications one way, e.g. APNs or email.
+type Channel interface {
+	// Deliver sends n to target and reports the outcome per recipient. An error means delivery could
+	// not be attempted, e.g. the server was unreachable, and the call is retried.
+	Deliver(ctx context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error)
+}
+
+// Notifier fans a notification out to the user's channels.
+type Notifier interface {
+	// Push delivers n over every route of n.UserID and reports the outcome per target. A channel that
+	// keeps failing is recorded as a failed delivery; an error is returned only if the routes cannot
+	// be loaded.
+	Push(ctx context.Context, n *common.Notification) ([]common.NotificationDelivery, error)
+}
+
+type storage interface {
+	NotificationRoutes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error)
+}
+
+type notifier struct {
+	storage
+
+	channels map[common.NotificationChannel]Channel
+	defaults []common.NotificationRoute
+	attempts int
+	backoff  time.Duration
+
+	log *logrus.Entry
+}
+
+func (n *notifier) Push(ctx context.Context, msg *common.Notification) ([]common.NotificationDelivery, error) {
+	routes, err := n.NotificationRoutes(ctx, msg.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(routes) == 0 {
+		routes = n.defaults
+	}
+
+	results := make([][]common.NotificationDelivery, len(routes))
+	wg := sync.WaitGroup{}
+	for i, route := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = n.deliver(ctx, msg, route)
+		}()
+	}
+	wg.Wait()
+
+	var deliveries []common.NotificationDelivery
+	for _, res := range results {
+		deliveries = append(deliveries, res...)
+	}
+
+	return deliveries, nil
+}
+
+// deliver tries route until the channel accepts the notification or the attempts run out.
+func (n *notifier) deliver(ctx context.Context, msg *common.Notification, route common.NotificationRoute) []common.NotificationDelivery {
+	ch, ok := n.channels[route.Channel]
+	if !ok {
+		return []common.NotificationDelivery{failed(route, errChannelNotConfigured)}
+	}
+	if route.Channel == common.NotificationChannelEmail && !route.Verified {
+		return []common.NotificationDelivery{failed(route, errEmailNotVerified)}
+	}
+
+	var err error
+	for attempt := range n.attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return []common.NotificationDelivery{failed(route, ctx.Err())}
+			case <-time.After(n.backoff << (attempt - 1)):
+			}
+		}
+
+		var deliveries []common.NotificationDelivery
+		if deliveries, err = ch.Deliver(ctx, msg, route.Target); err == nil {
+			return deliveries
+		}
+
+		n.log.WithError(err).
+			WithField("channel", route.Channel).
+			WithField("attempt", attempt+1).
+			Warn("deliver notification")
+	}
+
+	return []common.NotificationDelivery{failed(route, err)}
+}
+
+func failed(route common.NotificationRoute, err error) common.NotificationDelivery {
+	return common.NotificationDelivery{
+		Channel:   route.Channel,
+		Target:    route.Target,
+		Status:    common.DeliveryStatusFailed,
+		Reason:    err.Error(),
+		CreatedAt: time.Now(),
+	}
+}
+
+// New returns a notifier over channels. Users without routes get cfg.DefaultChannels.
+func New(cfg *Configuration, storage storage, channels map[common.NotificationChannel]Channel, log *logrus.Entry) (Notifier, error) {
+	defaults := make([]common.NotificationRoute, 0, len(cfg.DefaultChannels))
+	for _, name := range cfg.DefaultChannels {
+		ch, err := common.ParseNotificationChannel(name)
+		if err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, common.NotificationRoute{Channel: ch})
+	}
+
+	return &notifier{
+		storage:  storage,
+		channels: channels,
+		defaults: defaults,
+		attempts: max(cfg.RetryAttempts, 1),
+		backoff:  cfg.RetryBackoff,
+		log:      log,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// Webhook request headers. The signature is "sha256=" and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	SignatureHeader = "X-TeaElephant-Signature"
+	TimestampHeader = "X-TeaElephant-Timestamp"
+)
+
+// errPrivateAddress is returned when a webhook host resolves to an address inside our network.
+var errPrivateAddress = errors.New("webhook target resolves to a private address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which netip does not count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+var notificationTypeNames = map[common.NotificationType]string{
+	common.NotificationTypeTeaExpiration:     "teaExpiration",
+	common.NotificationTypeTeaRecommendation: "teaRecommendation",
+}
+
+var entityTypeNames = map[common.NotificationEntityType]string{
+	common.NotificationEntityQR:  "qr",
+	common.NotificationEntityTea: "tea",
+}
+
+type webhookEntity struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type webhookPayload struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body"`
+	Entity    *webhookEntity `json:"entity,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+type webhook struct {
+	client *http.Client
+	secret []byte
+}
+
+// Deliver posts n as JSON to the target URL. Server errors and 429 are retried; other non-2xx
+// responses, redirects and targets that are not public https URLs are recorded as failed deliveries.
+func (w *webhook) Deliver(ctx context.Context, n *common.Notification, target string) ([]common.NotificationDelivery, error) {
+	delivery := common.NotificationDelivery{
+		Channel:   common.NotificationChannelWebhook,
+		Target:    target,
+		Status:    common.DeliveryStatusSent,
+		CreatedAt: time.Now(),
+	}
+
+	route := common.NotificationRoute{Channel: common.NotificationChannelWebhook, Target: target}
+	if err := route.Validate(); err != nil {
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, err.Error()
+		return []common.NotificationDelivery{delivery}, nil
+	}
+
+	payload := webhookPayload{
+		ID:        n.ID.String(),
+		Type:      notificationTypeNames[n.Type],
+		Title:     n.Title,
+		Body:      n.Body,
+		CreatedAt: n.CreatedAt,
+	}
+	if n.EntityType != common.NotificationEntityNone {
+		payload.Entity = &webhookEntity{Type: entityTypeNames[n.EntityType], ID: n.EntityID.String()}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if errors.Is(err, errPrivateAddress) {
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, errPrivateAddress.Error()
+		return []common.NotificationDelivery{delivery}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		delivery.Status, delivery.Reason = common.DeliveryStatusFailed, resp.Status
+	}
+
+	return []common.NotificationDelivery{delivery}, nil
+}
+
+// Sign returns the signature header value for a webhook body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// refusePrivate is a dialer Control hook. It runs after DNS resolution for every address that is
+// tried, so a host name cannot point the webhook at our own network.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, addrPort.Addr())
+	}
+
+	return nil
+}
+
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// NewWebhook returns a channel that posts signed JSON to the route target. It only connects to
+// public addresses, bypasses proxies and does not follow redirects.
+func NewWebhook(secret string, timeout time.Duration) Channel {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivate}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		ForceAttemptHTTP2:   true,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &webhook{client: client, secret: []byte(secret)}
+}
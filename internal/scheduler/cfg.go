@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Configuration controls how often the scheduler looks for due jobs.
+type Configuration struct {
+	// Interval is the time between checks for due and manually queued runs; schedules are
+	// precise to it.
+	Interval time.Duration `envconfig:"INTERVAL" default:"30s"`
+	// LockKey is the Postgres advisory lock that elects the replica running the jobs.
+	LockKey int64 `envconfig:"LOCK_KEY" default:"7263"`
+}
+
+// Config reads the configuration from SCHEDULER_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("SCHEDULER", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
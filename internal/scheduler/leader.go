@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/teaelephant/TeaElephantMemory/pkg/pgstore"
+)
+
+// PGLeader elects the leader with a session-level Postgres advisory lock. The lock is held on a
+// dedicated connection, so it is released by the server as soon as the replica dies.
+type PGLeader struct {
+	db   *sql.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewPGLeader creates a leader election on the advisory lock key.
+func NewPGLeader(db *sql.DB, key int64) *PGLeader {
+	return &PGLeader{db: db, key: key}
+}
+
+// Acquire reports whether this replica is the leader, taking the lock if it is free. It is not
+// safe for concurrent use.
+func (l *PGLeader) Acquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// The session and the lock with it are gone.
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("leader conn: %w", err)
+	}
+
+	locked, err := pgstore.New(conn).TryAdvisoryLock(ctx, l.key)
+	if err != nil || !locked {
+		_ = conn.Close()
+		if err != nil {
+			return false, fmt.Errorf("try advisory lock: %w", err)
+		}
+		return false, nil
+	}
+
+	l.conn = conn
+
+	return true, nil
+}
+
+// Release gives up the leadership.
+func (l *PGLeader) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+
+	conn := l.conn
+	l.conn = nil
+	defer conn.Close()
+
+	if _, err := pgstore.New(conn).AdvisoryUnlock(ctx, l.key); err != nil {
+		return fmt.Errorf("advisory unlock: %w", err)
+	}
+
+	return nil
+}
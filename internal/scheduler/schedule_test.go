@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	from := time.Date(2026, 10, 18, 12, 34, 56, 0, time.UTC) // Sunday
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 18, 12, 35, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, 10, 19, 3, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"15,45 12 18 10 *", time.Date(2026, 10, 18, 12, 45, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 6h", from.Add(6 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, s.Next(from))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "@every 1s", "@yearly"} {
+		_, err := Parse(spec)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, spec)
+	}
+}
+
+func TestCron_NeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, s.Next(from).After(from.Add(4*365*24*time.Hour)))
+}
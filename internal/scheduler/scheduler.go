@@ -0,0 +1,294 @@
+// Package scheduler runs background jobs on cron-like schedules. Only the replica holding the
+// leader lock runs jobs; every run is recorded in the database, so schedules survive restarts
+// and runs can be queued manually from any replica.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+const (
+	// DefaultRuns is the number of runs returned when the caller does not ask for a number.
+	DefaultRuns = 50
+	maxRuns     = 500
+
+	logKeyJob = "job"
+)
+
+// Job is a unit of background work.
+type Job struct {
+	Name string
+	// Schedule is parsed with Parse.
+	Schedule string
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler interface {
+	Start() error
+	Stop() error
+	// Trigger queues a manual run of the job; the leader starts it on its next check.
+	Trigger(ctx context.Context, name string) (*common.JobRun, error)
+	// Runs returns the latest runs, of all jobs if job is empty, newest first.
+	Runs(ctx context.Context, job string, limit *int) ([]common.JobRun, error)
+}
+
+type storage interface {
+	QueueJobRun(ctx context.Context, job string) (*common.JobRun, error)
+	StartJobRun(ctx context.Context, job string) (*common.JobRun, error)
+	StartPendingJobRun(ctx context.Context, job string) (*common.JobRun, error)
+	FinishJobRun(ctx context.Context, id uuid.UUID, status common.JobRunStatus, message string) error
+	AbandonRunningJobRuns(ctx context.Context) (int64, error)
+	LastJobStarts(ctx context.Context) (map[string]time.Time, error)
+	JobRuns(ctx context.Context, job string, limit int) ([]common.JobRun, error)
+}
+
+type leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+type scheduledJob struct {
+	Job
+	schedule Schedule
+}
+
+type scheduler struct {
+	storage
+	leader leader
+
+	jobs    []scheduledJob
+	started time.Time
+	leading bool
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	cfg       *Configuration
+	startSync *sync.Once
+
+	log  *logrus.Entry
+	stop chan struct{}
+}
+
+func (s *scheduler) Start() error {
+	s.startSync.Do(func() {
+		s.started = time.Now()
+		go s.loop()
+	})
+
+	return nil
+}
+
+func (s *scheduler) Stop() error {
+	s.stop <- struct{}{}
+	return nil
+}
+
+func (s *scheduler) Trigger(ctx context.Context, name string) (*common.JobRun, error) {
+	if s.job(name) == nil {
+		return nil, fmt.Errorf("%w: %s", common.ErrJobNotFound, name)
+	}
+
+	return s.QueueJobRun(ctx, name)
+}
+
+func (s *scheduler) Runs(ctx context.Context, job string, limit *int) ([]common.JobRun, error) {
+	n := DefaultRuns
+	if limit != nil {
+		n = min(max(*limit, 1), maxRuns)
+	}
+
+	return s.JobRuns(ctx, job, n)
+}
+
+func (s *scheduler) job(name string) *scheduledJob {
+	for i := range s.jobs {
+		if s.jobs[i].Name == name {
+			return &s.jobs[i]
+		}
+	}
+
+	return nil
+}
+
+func (s *scheduler) loop() {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	s.tick(time.Now())
+
+loop:
+	for {
+		select {
+		case <-s.stop:
+			break loop
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+
+	s.cancel()
+	s.wg.Wait()
+
+	if err := s.leader.Release(context.Background()); err != nil {
+		s.log.WithError(err).Error("release leadership")
+	}
+
+	close(s.stop)
+}
+
+// tick starts the queued and due runs of jobs that are not running yet, if this replica leads.
+func (s *scheduler) tick(now time.Time) {
+	ctx := s.ctx
+
+	ok, err := s.leader.Acquire(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("acquire leadership")
+	}
+	if !ok {
+		if s.leading {
+			s.log.Warn("leadership lost")
+		}
+		s.leading = false
+
+		return
+	}
+
+	if !s.leading {
+		s.leading = true
+		s.log.Info("leadership acquired")
+
+		abandoned, err := s.AbandonRunningJobRuns(ctx)
+		if err != nil {
+			s.log.WithError(err).Error("abandon running job runs")
+		} else if abandoned > 0 {
+			s.log.WithField("runs", abandoned).Warn("runs of the previous leader marked as interrupted")
+		}
+	}
+
+	last, err := s.LastJobStarts(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("last job starts")
+		return
+	}
+
+	for i := range s.jobs {
+		job := &s.jobs[i]
+		if s.isRunning(job.Name) {
+			continue
+		}
+
+		run, err := s.StartPendingJobRun(ctx, job.Name)
+		if err != nil {
+			s.log.WithError(err).WithField(logKeyJob, job.Name).Error("start queued run")
+			continue
+		}
+
+		if run == nil {
+			from, ok := last[job.Name]
+			if !ok {
+				from = s.started
+			}
+			if now.Before(job.schedule.Next(from)) {
+				continue
+			}
+
+			if run, err = s.StartJobRun(ctx, job.Name); err != nil {
+				s.log.WithError(err).WithField(logKeyJob, job.Name).Error("start scheduled run")
+				continue
+			}
+		}
+
+		s.execute(job, run)
+	}
+}
+
+func (s *scheduler) isRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.running[name]
+}
+
+func (s *scheduler) execute(job *scheduledJob, run *common.JobRun) {
+	s.mu.Lock()
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		log := s.log.WithField(logKeyJob, job.Name).WithField("run", run.ID)
+		log.Info("job started")
+
+		status, message := common.JobRunStatusSucceeded, ""
+		if err := s.runJob(job); err != nil {
+			log.WithError(err).Error("job failed")
+			status, message = common.JobRunStatusFailed, err.Error()
+		} else {
+			log.Info("job finished")
+		}
+
+		if err := s.FinishJobRun(context.Background(), run.ID, status, message); err != nil {
+			log.WithError(err).Error("finish job run")
+		}
+
+		s.mu.Lock()
+		delete(s.running, job.Name)
+		s.mu.Unlock()
+	}()
+}
+
+// runJob runs the job, turning a panic into an error so a broken job does not take the server down.
+func (s *scheduler) runJob(job *scheduledJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return job.Run(s.ctx)
+}
+
+// New creates a scheduler for the jobs; it fails if a schedule cannot be parsed or a name is
+// used twice.
+func New(cfg *Configuration, storage storage, leader leader, jobs []Job, log *logrus.Entry) (Scheduler, error) {
+	s := &scheduler{
+		storage:   storage,
+		leader:    leader,
+		running:   make(map[string]bool),
+		cfg:       cfg,
+		startSync: new(sync.Once),
+		log:       log,
+		stop:      make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for _, job := range jobs {
+		if s.job(job.Name) != nil {
+			return nil, fmt.Errorf("job %s registered twice", job.Name)
+		}
+
+		schedule, err := Parse(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.Name, err)
+		}
+
+		s.jobs = append(s.jobs, scheduledJob{Job: job, schedule: schedule})
+	}
+
+	return s, nil
+}
@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+type storageStub struct {
+	mu   sync.Mutex
+	runs []*common.JobRun
+}
+
+func (s *storageStub) insert(job string, trigger common.JobTrigger, status common.JobRunStatus) *common.JobRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := &common.JobRun{ID: uuid.New(), Job: job, Trigger: trigger, Status: status, CreatedAt: time.Now()}
+	if status == common.JobRunStatusRunning {
+		now := time.Now()
+		run.StartedAt = &now
+	}
+	s.runs = append(s.runs, run)
+
+	return run
+}
+
+func (s *storageStub) QueueJobRun(_ context.Context, job string) (*common.JobRun, error) {
+	return s.insert(job, common.JobTriggerManual, common.JobRunStatusPending), nil
+}
+
+func (s *storageStub) StartJobRun(_ context.Context, job string) (*common.JobRun, error) {
+	return s.insert(job, common.JobTriggerSchedule, common.JobRunStatusRunning), nil
+}
+
+func (s *storageStub) StartPendingJobRun(_ context.Context, job string) (*common.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.runs {
+		if run.Job == job && run.Status == common.JobRunStatusPending {
+			now := time.Now()
+			run.Status, run.StartedAt = common.JobRunStatusRunning, &now
+			return run, nil
+		}
+	}
+
+	return nil, nil //nolint:nilnil // mirrors the database adapter
+}
+
+func (s *storageStub) FinishJobRun(_ context.Context, id uuid.UUID, status common.JobRunStatus, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.runs {
+		if run.ID == id {
+			run.Status, run.Error = status, message
+		}
+	}
+
+	return nil
+}
+
+func (s *storageStub) AbandonRunningJobRuns(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, run := range s.runs {
+		if run.Status == common.JobRunStatusRunning {
+			run.Status, run.Error = common.JobRunStatusFailed, "interrupted"
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (s *storageStub) LastJobStarts(context.Context) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[string]time.Time)
+	for _, run := range s.runs {
+		if run.StartedAt != nil && run.StartedAt.After(res[run.Job]) {
+			res[run.Job] = *run.StartedAt
+		}
+	}
+
+	return res, nil
+}
+
+func (s *storageStub) JobRuns(_ context.Context, job string, _ int) ([]common.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []common.JobRun
+	for _, run := range s.runs {
+		if job == "" || run.Job == job {
+			res = append(res, *run)
+		}
+	}
+
+	return res, nil
+}
+
+type leaderStub struct{ leading bool }
+
+func (l *leaderStub) Acquire(context.Context) (bool, error) { return l.leading, nil }
+func (l *leaderStub) Release(context.Context) error         { return nil }
+
+func newTestScheduler(t *testing.T, st *storageStub, l *leaderStub, jobs ...Job) *scheduler {
+	t.Helper()
+
+	s, err := New(&Configuration{Interval: time.Minute}, st, l, jobs, logrus.NewEntry(logrus.New()))
+	require.NoError(t, err)
+
+	sch := s.(*scheduler) //nolint:forcetypeassert // New returns *scheduler
+	sch.started = time.Now()
+
+	return sch
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	t.Run("runs due jobs once per period", func(t *testing.T) {
+		st := &storageStub{}
+		calls := 0
+		s := newTestScheduler(t, st, &leaderStub{leading: true},
+			Job{Name: "prune", Schedule: "@every 1h", Run: func(context.Context) error { calls++; return nil }})
+
+		s.tick(time.Now())
+		s.wg.Wait()
+		assert.Zero(t, calls)
+
+		later := time.Now().Add(time.Hour)
+		s.tick(later)
+		s.wg.Wait()
+		s.tick(later)
+		s.wg.Wait()
+		assert.Equal(t, 1, calls)
+
+		runs, _ := st.JobRuns(context.Background(), "prune", 10)
+		require.Len(t, runs, 1)
+		assert.Equal(t, common.JobRunStatusSucceeded, runs[0].Status)
+		assert.Equal(t, common.JobTriggerSchedule, runs[0].Trigger)
+	})
+	t.Run("manual run", func(t *testing.T) {
+		st := &storageStub{}
+		s := newTestScheduler(t, st, &leaderStub{leading: true},
+			Job{Name: "alerts", Schedule: "@weekly", Run: func(context.Context) error { return errBoom }})
+
+		run, err := s.Trigger(context.Background(), "alerts")
+		require.NoError(t, err)
+		assert.Equal(t, common.JobRunStatusPending, run.Status)
+
+		s.tick(time.Now())
+		s.wg.Wait()
+
+		runs, _ := st.JobRuns(context.Background(), "", 10)
+		require.Len(t, runs, 1)
+		assert.Equal(t, common.JobRunStatusFailed, runs[0].Status)
+		assert.Equal(t, "boom", runs[0].Error)
+	})
+	t.Run("unknown job", func(t *testing.T) {
+		s := newTestScheduler(t, &storageStub{}, &leaderStub{})
+
+		_, err := s.Trigger(context.Background(), "nope")
+		assert.ErrorIs(t, err, common.ErrJobNotFound)
+	})
+	t.Run("followers do not run jobs", func(t *testing.T) {
+		st := &storageStub{}
+		s := newTestScheduler(t, st, &leaderStub{},
+			Job{Name: "prune", Schedule: "@every 1h", Run: func(context.Context) error { panic("must not run") }})
+
+		_, err := s.Trigger(context.Background(), "prune")
+		require.NoError(t, err)
+		s.tick(time.Now().Add(2 * time.Hour))
+		s.wg.Wait()
+
+		assert.Equal(t, common.JobRunStatusPending, st.runs[0].Status)
+	})
+	t.Run("new leader fails orphaned runs and recovers panics", func(t *testing.T) {
+		st := &storageStub{}
+		orphan := st.insert("prune", common.JobTriggerSchedule, common.JobRunStatusRunning)
+		s := newTestScheduler(t, st, &leaderStub{leading: true},
+			Job{Name: "prune", Schedule: "@every 1h", Run: func(context.Context) error { panic("broken") }})
+
+		s.tick(time.Now().Add(time.Hour))
+		s.wg.Wait()
+
+		assert.Equal(t, "interrupted", orphan.Error)
+		require.Len(t, st.runs, 2)
+		assert.Equal(t, common.JobRunStatusFailed, st.runs[1].Status)
+		assert.Equal(t, "panic: broken", st.runs[1].Error)
+	})
+	t.Run("invalid schedule", func(t *testing.T) {
+		_, err := New(&Configuration{}, &storageStub{}, &leaderStub{}, []Job{{Name: "x", Schedule: "bad"}},
+			logrus.NewEntry(logrus.New()))
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+	})
+}
@@ -20,17 +20,24 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/vektah/gqlparser/v2/ast"
 
+	"github.com/teaelephant/TeaElephantMemory/internal/catalog"
+	"github.com/teaelephant/TeaElephantMemory/internal/labels"
+	"github.com/teaelephant/TeaElephantMemory/internal/landing"
 	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/graphql/generated"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
 )
 
 const (
 	v2QueryPath     = "/v2/query"
 	staticIndexPath = "./static/index.html"
+	labelsPath      = "/v2/labels/"
+	collectionsPath = "/v2/collections/"
 )
 
 // Server aggregates the GraphQL resolvers, router, middlewares and ws init logic.
 type Server struct {
 	resolvers   generated.ResolverRoot
+	directives  generated.DirectiveRoot
 	router      *mux.Router
 	middlewares []graphql.HandlerExtension
 	wsInitFunc  transport.WebsocketInitFunc
@@ -58,7 +65,7 @@ func (s *Server) Run() error {
 func (s *Server) InitV2Api() {
 	srv := handler.New(
 		generated.NewExecutableSchema(
-			generated.Config{Resolvers: s.resolvers}))
+			generated.Config{Resolvers: s.resolvers, Directives: s.directives}))
 
 	srv.AddTransport(&transport.Websocket{
 		InitFunc:              s.wsInitFunc,
@@ -108,7 +115,36 @@ func (s *Server) InitV2Api() {
 	})
 }
 
-// NewServer creates the HTTP server wiring resolvers, middlewares, and websocket init.
-func NewServer(resolvers generated.ResolverRoot, middlewares []graphql.HandlerExtension, wsInitFunc transport.WebsocketInitFunc) *Server {
-	return &Server{resolvers: resolvers, router: mux.NewRouter(), middlewares: middlewares, wsInitFunc: wsInitFunc}
+// InitLanding serves the public QR landing page under the universal link path.
+func (s *Server) InitLanding(h http.Handler) {
+	s.router.Handle(qrlink.PathPrefix+"{"+landing.IDVar+"}", h).Methods(http.MethodGet, http.MethodHead)
+}
+
+// InitLabels serves printable label PDFs for single QR records and whole collections. auth puts the
+// caller from the Authorization header into the request context.
+func (s *Server) InitLabels(h *labels.Handler, auth Middleware) {
+	id := "{" + labels.IDVar + ":[^/.]+}"
+	s.router.Handle(labelsPath+"collections/"+id+".pdf", auth(http.HandlerFunc(h.Collection))).Methods(http.MethodGet)
+	s.router.Handle(labelsPath+id+".pdf", auth(http.HandlerFunc(h.Record))).Methods(http.MethodGet)
+}
+
+// InitCatalog serves the printable tea menu of a collection to its owner.
+func (s *Server) InitCatalog(h http.Handler, auth Middleware) {
+	s.router.Handle(collectionsPath+"{"+catalog.IDVar+"}/catalog.pdf", auth(h)).Methods(http.MethodGet)
+}
+
+// NewServer creates the HTTP server wiring resolvers, schema directives, middlewares, and websocket init.
+func NewServer(
+	resolvers generated.ResolverRoot,
+	directives generated.DirectiveRoot,
+	middlewares []graphql.HandlerExtension,
+	wsInitFunc transport.WebsocketInitFunc,
+) *Server {
+	return &Server{
+		resolvers:   resolvers,
+		directives:  directives,
+		router:      mux.NewRouter(),
+		middlewares: middlewares,
+		wsInitFunc:  wsInitFunc,
+	}
 }
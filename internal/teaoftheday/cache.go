@@ -1,19 +1,20 @@
-package graphql
+package teaoftheday
 
 import (
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
 )
 
 // teaOfTheDayCache is a simple in-memory cache for TeaOfTheDay per user
 // that expires at the next local midnight. It is process-local and not shared.
 //
-// Day boundary is computed using the provided time's location (typically time.Local),
-// which is sufficient given we do not have per-user timezone in the system yet.
-// The cache intentionally does not attempt cross-process or cross-instance sharing.
+// Day boundary is computed using the provided time's location, which callers set to
+// the user's time zone. Picks are stored in the database, so the cache only saves
+// lookups and intentionally does not attempt cross-process or cross-instance sharing.
 // It is safe for concurrent use by multiple goroutines.
 type teaOfTheDayCache struct {
 	mu    sync.RWMutex
@@ -21,7 +22,7 @@ type teaOfTheDayCache struct {
 }
 
 type cachedTeaOfTheDay struct {
-	val       *model.TeaOfTheDay
+	val       *common.TeaOfTheDay
 	expiresAt time.Time
 }
 
@@ -38,7 +39,7 @@ func nextMidnight(t time.Time) time.Time {
 }
 
 // Get returns the cached value for userID if present and not expired at now.
-func (c *teaOfTheDayCache) Get(userID uuid.UUID, now time.Time) (*model.TeaOfTheDay, bool) {
+func (c *teaOfTheDayCache) Get(userID uuid.UUID, now time.Time) (*common.TeaOfTheDay, bool) {
 	c.mu.RLock()
 	entry, ok := c.items[userID]
 	c.mu.RUnlock()
@@ -65,7 +66,7 @@ func (c *teaOfTheDayCache) Get(userID uuid.UUID, now time.Time) (*model.TeaOfThe
 }
 
 // Set stores the value for userID, expiring at the next local midnight based on now.
-func (c *teaOfTheDayCache) Set(userID uuid.UUID, v *model.TeaOfTheDay, now time.Time) {
+func (c *teaOfTheDayCache) Set(userID uuid.UUID, v *common.TeaOfTheDay, now time.Time) {
 	exp := nextMidnight(now)
 
 	c.mu.Lock()
@@ -1,4 +1,4 @@
-package graphql
+package teaoftheday
 
 import (
 	"testing"
@@ -6,7 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
-	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
 )
 
 func TestTeaOfTheDayCache_GetSetAndExpire(t *testing.T) {
@@ -21,7 +22,7 @@ func TestTeaOfTheDayCache_GetSetAndExpire(t *testing.T) {
 		t.Fatalf("expected empty cache")
 	}
 
-	val := &model.TeaOfTheDay{Tea: nil, Date: now}
+	val := &common.TeaOfTheDay{UserID: userID, Day: now}
 	c.Set(userID, val, now)
 
 	// Should hit before midnight
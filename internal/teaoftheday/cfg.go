@@ -0,0 +1,25 @@
+package teaoftheday
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Configuration controls the daily Tea of the Day push.
+type Configuration struct {
+	// Interval is the time between scheduler runs; pushes go out at most this late.
+	Interval time.Duration `envconfig:"INTERVAL" default:"5m"`
+	// Window is how long after the chosen time a missed push is still sent, e.g. after a restart.
+	Window time.Duration `envconfig:"WINDOW" default:"2h"`
+}
+
+// Config reads the configuration from TEA_OF_THE_DAY_* environment variables.
+func Config() *Configuration {
+	cfg := new(Configuration)
+	if err := envconfig.Process("TEA_OF_THE_DAY", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -0,0 +1,388 @@
+// Package teaoftheday picks one tea a day for every user from their collections and pushes it to
+// users who opted in.
+package teaoftheday
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/internal/consumption"
+	"github.com/teaelephant/TeaElephantMemory/internal/scoring"
+)
+
+const (
+	logKeyUser    = "user"
+	logKeyWeekday = "weekday"
+	logKeyWeather = "weather"
+
+	// recentWindow is how far back consumption lowers a tea's score.
+	recentWindow = 96 * time.Hour
+)
+
+var (
+	// ErrNoTeas indicates the user has no teas in their collections to select from.
+	ErrNoTeas = errors.New("you should have more teas")
+	// ErrNoTeaCandidates indicates scoring produced no valid tea candidates.
+	ErrNoTeaCandidates = errors.New("no tea candidates")
+)
+
+type Service interface {
+	// Today returns the user's pick for the current day in their time zone, choosing it on first use.
+	Today(ctx context.Context, userID uuid.UUID) (*common.TeaOfTheDay, error)
+	Start() error
+	Stop() error
+	// Run pushes the pick to every opted-in user whose chosen time has come and who was not notified today.
+	Run(ctx context.Context) error
+}
+
+type storage interface {
+	Collections(ctx context.Context, userID uuid.UUID) ([]*common.Collection, error)
+	CollectionRecords(ctx context.Context, id uuid.UUID) ([]*common.CollectionRecord, error)
+	ReadQR(ctx context.Context, id uuid.UUID) (*common.QR, error)
+	ReadRecord(ctx context.Context, id uuid.UUID) (*common.Tea, error)
+	NotificationPreferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error)
+	TeaOfTheDaySubscribers(ctx context.Context) ([]*common.NotificationPreferences, error)
+	TeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time) (uuid.UUID, error)
+	SaveTeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time, recordID uuid.UUID) (uuid.UUID, error)
+	ClaimTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) (bool, error)
+	ReleaseTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) error
+}
+
+type sender interface {
+	SendNow(ctx context.Context, n *common.Notification) error
+}
+
+type adviser interface {
+	ContextScores(ctx context.Context, teas []string, weather common.Weather, day time.Weekday) (map[string]int, error)
+}
+
+type weather interface {
+	CurrentCyprus(ctx context.Context) (common.Weather, error)
+}
+
+type service struct {
+	storage
+	sender
+	adviser
+	weather
+	consumption consumption.Store
+
+	cache     *teaOfTheDayCache
+	cfg       *Configuration
+	startSync *sync.Once
+
+	log  *logrus.Entry
+	stop chan struct{}
+}
+
+func (s *service) Today(ctx context.Context, userID uuid.UUID) (*common.TeaOfTheDay, error) {
+	prefs, err := s.NotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.today(ctx, userID, time.Now().In(prefs.Location()))
+}
+
+// today returns the pick for the day of now, which is in the user's time zone.
+func (s *service) today(ctx context.Context, userID uuid.UUID, now time.Time) (*common.TeaOfTheDay, error) {
+	if cached, ok := s.cache.Get(userID, now); ok {
+		s.log.WithField(logKeyUser, userID).WithField("tea", cached.Record.Tea.Name).Debug("tea_of_day cache_hit")
+		return cached, nil
+	}
+
+	day := date(now)
+	id, err := s.TeaOfTheDay(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	var record *common.CollectionRecord
+	if id == uuid.Nil {
+		if record, err = s.pick(ctx, userID, now); err != nil {
+			return nil, err
+		}
+
+		if id, err = s.SaveTeaOfTheDay(ctx, userID, day, record.ID); err != nil {
+			return nil, err
+		}
+
+		if id == record.ID {
+			if err = s.consumption.Record(ctx, userID, record.Tea.ID, now); err != nil {
+				s.log.WithField(logKeyUser, userID).WithError(err).Debug("tea_of_day record consumption failed")
+			}
+		} else {
+			// another request picked first
+			record = nil
+		}
+	}
+
+	if record == nil {
+		if record, err = s.record(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &common.TeaOfTheDay{UserID: userID, Day: day, Record: record}
+	s.cache.Set(userID, res, now)
+
+	return res, nil
+}
+
+func (s *service) record(ctx context.Context, id uuid.UUID) (*common.CollectionRecord, error) {
+	qr, err := s.ReadQR(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tea, err := s.ReadRecord(ctx, qr.Tea)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.CollectionRecord{ID: id, Tea: tea, BowlingTemp: qr.BowlingTemp, ExpirationDate: qr.ExpirationDate}, nil
+}
+
+// pick scores the teas in the user's collections by weather, weekday, recent consumption and
+// expiration, and returns the record of the best tea that expires first.
+//
+//nolint:funlen // keeps the whole selection in one place
+func (s *service) pick(ctx context.Context, userID uuid.UUID, now time.Time) (*common.CollectionRecord, error) {
+	cols, err := s.Collections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	earliestRec := make(map[uuid.UUID]*common.CollectionRecord)
+	// Build candidates and name mappings on the fly to avoid extra iteration.
+	candidates := make([]scoring.Candidate, 0, 32)
+	names := make([]string, 0, 32)
+	nameToID := make(map[string]uuid.UUID, 32)
+	idToIdx := make(map[uuid.UUID]int, 32)
+
+	for _, c := range cols {
+		records, err := s.CollectionRecords(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rec := range records {
+			if rec.Tea == nil {
+				continue
+			}
+
+			id, name := rec.Tea.ID, rec.Tea.Name
+
+			if prev, seen := earliestRec[id]; !seen {
+				// first time we see this tea: record earliestRec and add candidate + name mappings
+				earliestRec[id] = rec
+				candidates = append(candidates, scoring.Candidate{ID: id, Name: name, Expiration: rec.ExpirationDate})
+				idToIdx[id] = len(candidates) - 1
+				names = append(names, name)
+				nameToID[strings.ToLower(strings.TrimSpace(name))] = id
+			} else if rec.ExpirationDate.Before(prev.ExpirationDate) {
+				// found an earlier expiration for this tea: update earliestRec and candidate expiration
+				earliestRec[id] = rec
+				candidates[idToIdx[id]].Expiration = rec.ExpirationDate
+			}
+		}
+	}
+
+	if len(earliestRec) == 0 {
+		return nil, ErrNoTeas
+	}
+
+	log := s.log.WithField(logKeyUser, userID.String())
+
+	// Weather and recent consumption (best-effort)
+	w, err := s.CurrentCyprus(ctx)
+	if err != nil {
+		log.WithError(err).Debug("tea_of_day weather fetch failed")
+	}
+
+	recent, err := s.consumption.Recent(ctx, userID, now.Add(-recentWindow))
+	if err != nil {
+		log.WithError(err).Debug("tea_of_day recent fetch failed")
+		recent = nil
+	}
+
+	lastBy := make(map[uuid.UUID]time.Time, len(recent))
+	for _, c := range recent {
+		if t, ok := lastBy[c.TeaID]; !ok || c.Time.After(t) {
+			lastBy[c.TeaID] = c.Time
+		}
+	}
+
+	ctxScores, err := s.ContextScores(ctx, names, w, now.Weekday())
+	if err != nil {
+		log.WithError(err).Debug("tea_of_the_day scoring failed")
+		ctxScores = make(map[string]int)
+	}
+	aiScores := make(map[uuid.UUID]int, len(ctxScores))
+	for name, score := range ctxScores {
+		if id, ok := nameToID[strings.ToLower(strings.TrimSpace(name))]; ok {
+			aiScores[id] = score
+		}
+	}
+
+	log = log.WithField(logKeyWeekday, now.Weekday().String()).WithField(logKeyWeather, w.String())
+	log.WithField("candidates", len(candidates)).Debug("tea_of_day context")
+
+	// Delegate detailed candidate and selection logging to scoring package
+	bestID, _ := scoring.SelectBestWithLogging(aiScores, candidates, lastBy, now, func(fields map[string]interface{}, msg string) {
+		log.WithFields(fields).Debug(msg)
+	})
+
+	best := earliestRec[bestID]
+	if best == nil {
+		return nil, ErrNoTeaCandidates
+	}
+
+	return best, nil
+}
+
+func (s *service) Start() error {
+	s.startSync.Do(func() {
+		go s.loop()
+	})
+
+	return nil
+}
+
+func (s *service) Stop() error {
+	s.stop <- struct{}{}
+	return nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	subscribers, err := s.TeaOfTheDaySubscribers(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	var errs []error
+	for _, prefs := range subscribers {
+		if err := s.notify(ctx, prefs, now); err != nil {
+			s.log.WithError(err).WithField(logKeyUser, prefs.UserID).Error("tea of the day push")
+			errs = append(errs, fmt.Errorf("user %s: %w", prefs.UserID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *service) notify(ctx context.Context, prefs *common.NotificationPreferences, now time.Time) error {
+	local := now.In(prefs.Location())
+	if !due(*prefs.TeaOfTheDayTime, local, s.cfg.Window) {
+		return nil
+	}
+
+	pick, err := s.today(ctx, prefs.UserID, local)
+	if errors.Is(err, ErrNoTeas) || errors.Is(err, ErrNoTeaCandidates) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The claim keeps other replicas from pushing too; it is given back if the push fails so that the
+	// next run within the window tries again.
+	claimed, err := s.ClaimTeaOfTheDayNotification(ctx, prefs.UserID, pick.Day)
+	if err != nil || !claimed {
+		return err
+	}
+
+	// The user chose the time of this push, so quiet hours and the delivery time do not defer it.
+	err = s.SendNow(ctx, &common.Notification{
+		UserID:     prefs.UserID,
+		Type:       common.NotificationTypeTeaRecommendation,
+		Title:      "Tea of the Day",
+		Body:       Message(pick.Record),
+		EntityType: common.NotificationEntityQR,
+		EntityID:   pick.Record.ID,
+		SourceKey:  "tea-of-the-day/" + pick.Day.Format(time.DateOnly),
+	})
+	if err != nil {
+		if releaseErr := s.ReleaseTeaOfTheDayNotification(ctx, prefs.UserID, pick.Day); releaseErr != nil {
+			err = errors.Join(err, fmt.Errorf("release claim: %w", releaseErr))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// due reports whether the push chosen for time of day at is to be sent at local, i.e. at is at
+// most window ago on the same day.
+func due(at common.TimeOfDay, local time.Time, window time.Duration) bool {
+	y, m, d := local.Date()
+	start := time.Date(y, m, d, int(at)/60, int(at)%60, 0, 0, local.Location())
+
+	return !local.Before(start) && local.Sub(start) <= window
+}
+
+// Message is the push body, e.g. "Today: Da Hong Pao, 95°C".
+func Message(record *common.CollectionRecord) string {
+	if record.BowlingTemp == 0 {
+		return "Today: " + record.Tea.Name
+	}
+
+	return fmt.Sprintf("Today: %s, %d°C", record.Tea.Name, record.BowlingTemp)
+}
+
+// date returns the calendar day of t as midnight UTC, the way the database stores dates.
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *service) loop() {
+	ticker := time.NewTicker(s.cfg.Interval)
+
+loop:
+	for {
+		select {
+		case <-s.stop:
+			break loop
+		case <-ticker.C:
+			if err := s.Run(context.Background()); err != nil {
+				s.log.WithError(err).Error("tea of the day run")
+			}
+		}
+	}
+
+	close(s.stop)
+}
+
+func NewService(
+	storage storage,
+	sender sender,
+	adviser adviser,
+	weather weather,
+	cons consumption.Store,
+	cfg *Configuration,
+	log *logrus.Entry,
+) Service {
+	return &service{
+		storage:     storage,
+		sender:      sender,
+		adviser:     adviser,
+		weather:     weather,
+		consumption: cons,
+		cache:       newTeaOfTheDayCache(),
+		cfg:         cfg,
+		startSync:   new(sync.Once),
+		log:         log,
+		stop:        make(chan struct{}),
+	}
+}
@@ -0,0 +1,108 @@
+package teaoftheday
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestDue(t *testing.T) {
+	nicosia, err := time.LoadLocation("Asia/Nicosia")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+
+	eight := common.TimeOfDay(8 * 60)
+	window := 2 * time.Hour
+
+	assert.False(t, due(eight, time.Date(2026, 10, 18, 7, 59, 0, 0, nicosia), window))
+	assert.True(t, due(eight, time.Date(2026, 10, 18, 8, 0, 0, 0, nicosia), window))
+	assert.True(t, due(eight, time.Date(2026, 10, 18, 9, 30, 0, 0, nicosia), window))
+	assert.False(t, due(eight, time.Date(2026, 10, 18, 10, 30, 0, 0, nicosia), window))
+}
+
+func TestMessage(t *testing.T) {
+	record := &common.CollectionRecord{Tea: &common.Tea{TeaData: &common.TeaData{Name: "Da Hong Pao"}}, BowlingTemp: 95}
+	assert.Equal(t, "Today: Da Hong Pao, 95°C", Message(record))
+
+	record.BowlingTemp = 0
+	assert.Equal(t, "Today: Da Hong Pao", Message(record))
+}
+
+func TestDate(t *testing.T) {
+	local := time.Date(2026, 10, 18, 1, 30, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), date(local))
+}
+
+var errPush = errors.New("push failed")
+
+type pickStorage struct {
+	storage
+	pick     uuid.UUID
+	notified bool
+}
+
+func (s *pickStorage) TeaOfTheDay(context.Context, uuid.UUID, time.Time) (uuid.UUID, error) {
+	return s.pick, nil
+}
+
+func (s *pickStorage) ReadQR(context.Context, uuid.UUID) (*common.QR, error) {
+	return &common.QR{Tea: uuid.New(), BowlingTemp: 95}, nil
+}
+
+func (s *pickStorage) ReadRecord(_ context.Context, id uuid.UUID) (*common.Tea, error) {
+	return &common.Tea{ID: id, TeaData: &common.TeaData{Name: "Da Hong Pao"}}, nil
+}
+
+func (s *pickStorage) ClaimTeaOfTheDayNotification(context.Context, uuid.UUID, time.Time) (bool, error) {
+	if s.notified {
+		return false, nil
+	}
+	s.notified = true
+	return true, nil
+}
+
+func (s *pickStorage) ReleaseTeaOfTheDayNotification(context.Context, uuid.UUID, time.Time) error {
+	s.notified = false
+	return nil
+}
+
+type flakySender struct {
+	fail bool
+	sent []*common.Notification
+}
+
+func (s *flakySender) SendNow(_ context.Context, n *common.Notification) error {
+	if s.fail {
+		return errPush
+	}
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	now := time.Now().UTC()
+	at := common.TimeOfDay(now.Hour()*60 + now.Minute())
+	prefs := common.DefaultNotificationPreferences(uuid.New())
+	prefs.TeaOfTheDayTime = &at
+	st, push := &pickStorage{pick: uuid.New()}, &flakySender{fail: true}
+	s := NewService(st, push, nil, nil, nil, &Configuration{Window: time.Hour}, logrus.NewEntry(logrus.New())).(*service)
+
+	// A failed push gives the claim back, so the next run tries again.
+	require.ErrorIs(t, s.notify(context.Background(), prefs, now), errPush)
+	assert.False(t, st.notified)
+
+	push.fail = false
+	require.NoError(t, s.notify(context.Background(), prefs, now))
+	require.NoError(t, s.notify(context.Background(), prefs, now))
+	require.Len(t, push.sent, 1)
+	assert.Equal(t, st.pick, push.sent[0].EntityID)
+}
@@ -0,0 +1,109 @@
+// Package nfc encodes NDEF messages for NFC stickers: the same deep link as the QR label, optionally
+// followed by the tea name as a text record.
+package nfc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+// Record header flags and the well-known type name format (NFC Forum RTD).
+const (
+	flagMessageBegin = 0x80
+	flagMessageEnd   = 0x40
+	flagShortRecord  = 0x10
+	tnfWellKnown     = 0x01
+
+	maxShortPayload = 0xff
+	// DefaultLanguage is the IANA language code of text records.
+	DefaultLanguage = "en"
+)
+
+// uriPrefixes are the URI identifier codes from the NFC Forum URI RTD; the www. variants come
+// first so that they win over the bare schemes.
+var uriPrefixes = []struct {
+	prefix string
+	code   byte
+}{
+	{"http://www.", 0x01},
+	{"https://www.", 0x02},
+	{"http://", 0x03},
+	{"https://", 0x04},
+}
+
+// Record is a single NDEF record.
+type Record struct {
+	TNF     byte
+	Type    []byte
+	Payload []byte
+}
+
+// URIRecord returns a well-known "U" record, abbreviating the scheme as the spec allows.
+func URIRecord(uri string) Record {
+	var code byte
+	for _, p := range uriPrefixes {
+		if strings.HasPrefix(uri, p.prefix) {
+			code, uri = p.code, strings.TrimPrefix(uri, p.prefix)
+			break
+		}
+	}
+
+	return Record{TNF: tnfWellKnown, Type: []byte("U"), Payload: append([]byte{code}, uri...)}
+}
+
+// TextRecord returns a well-known "T" record with UTF-8 text in the given language.
+func TextRecord(text, lang string) Record {
+	payload := make([]byte, 0, 1+len(lang)+len(text))
+	payload = append(payload, byte(len(lang)))
+	payload = append(payload, lang...)
+	payload = append(payload, text...)
+
+	return Record{TNF: tnfWellKnown, Type: []byte("T"), Payload: payload}
+}
+
+// Encode serializes records into one NDEF message.
+func Encode(records ...Record) []byte {
+	var msg []byte
+	for i, r := range records {
+		header := r.TNF
+		if i == 0 {
+			header |= flagMessageBegin
+		}
+		if i == len(records)-1 {
+			header |= flagMessageEnd
+		}
+
+		short := len(r.Payload) <= maxShortPayload
+		if short {
+			header |= flagShortRecord
+		}
+
+		msg = append(msg, header, byte(len(r.Type)))
+		if short {
+			msg = append(msg, byte(len(r.Payload)))
+		} else {
+			msg = binary.BigEndian.AppendUint32(msg, uint32(len(r.Payload))) //nolint:gosec // payloads are far below 4 GiB
+		}
+		msg = append(msg, r.Type...)
+		msg = append(msg, r.Payload...)
+	}
+
+	return msg
+}
+
+// Message returns the NDEF message for a label: a URI record for link and, when name is set, a
+// text record with the tea name.
+func Message(link, name string) []byte {
+	records := []Record{URIRecord(link)}
+	if name != "" {
+		records = append(records, TextRecord(name, DefaultLanguage))
+	}
+
+	return Encode(records...)
+}
+
+// Hex formats a message as upper-case hex, the form NFC writer apps accept.
+func Hex(msg []byte) string {
+	return strings.ToUpper(hex.EncodeToString(msg))
+}
@@ -0,0 +1,29 @@
+package nfc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURIRecord(t *testing.T) {
+	assert.Equal(t, []byte("\x04tea-elephant.com/q/abc"), URIRecord("https://tea-elephant.com/q/abc").Payload)
+	assert.Equal(t, []byte("\x02tea-elephant.com"), URIRecord("https://www.tea-elephant.com").Payload)
+	assert.Equal(t, []byte("\x00urn:uuid:1"), URIRecord("urn:uuid:1").Payload)
+}
+
+func TestMessage(t *testing.T) {
+	t.Run("uri only", func(t *testing.T) {
+		assert.Equal(t, "D1010855046578616D706C65", Hex(Message("https://example", "")))
+	})
+	t.Run("uri and text", func(t *testing.T) {
+		msg := Message("https://example", "Sencha")
+		assert.Equal(t, "91010855046578616D706C65"+"5101095402656E53656E636861", Hex(msg))
+	})
+	t.Run("long record", func(t *testing.T) {
+		msg := Encode(TextRecord(strings.Repeat("a", 300), DefaultLanguage))
+		assert.Equal(t, []byte{0xC1, 0x01, 0x00, 0x00, 0x01, 0x2F, 'T'}, msg[:7])
+		assert.Len(t, msg, 7+303)
+	})
+}
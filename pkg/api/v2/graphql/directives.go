@@ -0,0 +1,33 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	authPkg "github.com/teaelephant/TeaElephantMemory/internal/auth"
+	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/graphql/generated"
+	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+)
+
+// NewDirectives returns the handlers of the @auth and @hasRole schema directives. Anonymous callers
+// get UNAUTHENTICATED and users lacking the role FORBIDDEN.
+func NewDirectives() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		Auth: func(ctx context.Context, _ any, next graphql.Resolver) (any, error) {
+			if err := authPkg.RequireRole(ctx, common.RoleUser); err != nil {
+				return nil, castGQLError(ctx, err)
+			}
+
+			return next(ctx)
+		},
+		HasRole: func(ctx context.Context, _ any, next graphql.Resolver, role model.Role) (any, error) {
+			if err := authPkg.RequireRole(ctx, role.ToCommon()); err != nil {
+				return nil, castGQLError(ctx, err)
+			}
+
+			return next(ctx)
+		},
+	}
+}
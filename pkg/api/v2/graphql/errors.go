@@ -22,26 +22,64 @@ const (
 	ErrUserNotFound
 	ErrCollectionNotFound
 	ErrDeviceNotFound
+	ErrTagNotFound
+	ErrTagCycle
+	ErrTagMergeSelf
+	ErrTeaNotFound
+	ErrTeaMergeSelf
+	ErrQRRecordForbidden
+	ErrQRCodeNotIssued
+	ErrQRBatchNotFound
+	ErrNotificationNotFound
+	ErrInvalidNotificationPreferences
+	ErrInvalidNotificationRoute
+	ErrJobNotFound
+	ErrInvalidDeviceName
+	ErrInvalidRefreshToken
+	ErrInvalidRole
+	ErrDevModeDisabled
+	ErrDeviceInUse
+	ErrTagSynonymExists
+	ErrInvalidVerificationCode
 )
 
 var errorsMap = map[error]GQLErrorCode{
-	common.ErrQRRecordNotExist:   ErrQRRecordNotExist,
-	common.ErrExpiredToken:       ErrExpiredToken,
-	common.ErrInvalidToken:       ErrInvalidToken,
-	common.ErrUserNotFound:       ErrUserNotFound,
-	common.ErrCollectionNotFound: ErrCollectionNotFound,
-	common.ErrDeviceNotFound:     ErrDeviceNotFound,
+	common.ErrQRRecordNotExist:               ErrQRRecordNotExist,
+	common.ErrExpiredToken:                   ErrExpiredToken,
+	common.ErrInvalidToken:                   ErrInvalidToken,
+	common.ErrUserNotFound:                   ErrUserNotFound,
+	common.ErrCollectionNotFound:             ErrCollectionNotFound,
+	common.ErrDeviceNotFound:                 ErrDeviceNotFound,
+	common.ErrTagNotFound:                    ErrTagNotFound,
+	common.ErrTagCycle:                       ErrTagCycle,
+	common.ErrTagMergeSelf:                   ErrTagMergeSelf,
+	common.ErrTeaNotFound:                    ErrTeaNotFound,
+	common.ErrTeaMergeSelf:                   ErrTeaMergeSelf,
+	common.ErrQRRecordForbidden:              ErrQRRecordForbidden,
+	common.ErrQRCodeNotIssued:                ErrQRCodeNotIssued,
+	common.ErrQRBatchNotFound:                ErrQRBatchNotFound,
+	common.ErrNotificationNotFound:           ErrNotificationNotFound,
+	common.ErrInvalidNotificationPreferences: ErrInvalidNotificationPreferences,
+	common.ErrInvalidNotificationRoute:       ErrInvalidNotificationRoute,
+	common.ErrJobNotFound:                    ErrJobNotFound,
+	common.ErrInvalidDeviceName:              ErrInvalidDeviceName,
+	common.ErrInvalidRefreshToken:            ErrInvalidRefreshToken,
+	common.ErrInvalidRole:                    ErrInvalidRole,
+	common.ErrDevModeDisabled:                ErrDevModeDisabled,
+	common.ErrDeviceInUse:                    ErrDeviceInUse,
+	common.ErrTagSynonymExists:               ErrTagSynonymExists,
+	common.ErrInvalidVerificationCode:        ErrInvalidVerificationCode,
 }
 
 func castGQLError(ctx context.Context, err error) error {
 	extensions := map[string]interface{}{}
 
 	// Standardize auth-related error codes for clients
-	if errors.Is(err, common.ErrUnauthorized) {
+	if errors.Is(err, common.ErrUnauthorized) || errors.Is(err, common.ErrAdminTokenRevoked) {
 		extensions["code"] = "UNAUTHENTICATED"
-	} else if errors.Is(err, common.ErrNotAdmin) {
+	} else if errors.Is(err, common.ErrNotAdmin) || errors.Is(err, common.ErrForbidden) {
 		extensions["code"] = "FORBIDDEN"
-	} else if code, ok := errorsMap[err]; ok {
+	} else if code, ok := lookupCode(err); ok {
 		extensions["code"] = code
 	}
 
@@ -51,3 +89,18 @@ func castGQLError(ctx context.Context, err error) error {
 		Extensions: extensions,
 	}
 }
+
+// lookupCode finds the code of err, also when a sentinel was wrapped with details.
+func lookupCode(err error) (GQLErrorCode, bool) {
+	if code, ok := errorsMap[err]; ok {
+		return code, true
+	}
+
+	for sentinel, code := range errorsMap {
+		if errors.Is(err, sentinel) {
+			return code, true
+		}
+	}
+
+	return 0, false
+}
@@ -2,14 +2,15 @@ package graphql
 
 import (
 	"context"
-	"errors"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
-	"github.com/teaelephant/TeaElephantMemory/internal/consumption"
+	authPkg "github.com/teaelephant/TeaElephantMemory/internal/auth"
+	"github.com/teaelephant/TeaElephantMemory/internal/dedup"
+	qrPkg "github.com/teaelephant/TeaElephantMemory/internal/managers/qr"
+	"github.com/teaelephant/TeaElephantMemory/internal/teaoftheday"
 	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
 )
@@ -18,16 +19,9 @@ import (
 
 var (
 	// ErrNoTeas indicates the user has no teas in their collections to select from.
-	ErrNoTeas = errors.New("you should have more teas")
+	ErrNoTeas = teaoftheday.ErrNoTeas
 	// ErrNoTeaCandidates indicates scoring produced no valid tea candidates.
-	ErrNoTeaCandidates = errors.New("no tea candidates")
-)
-
-const (
-	logKeyUser    = "user"
-	logKeyWeekday = "weekday"
-	logKeyWeather = "weather"
-	logKeyErr     = "err"
+	ErrNoTeaCandidates = teaoftheday.ErrNoTeaCandidates
 )
 
 type logger interface {
@@ -41,14 +35,22 @@ type teaData interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	Get(ctx context.Context, id uuid.UUID) (record *common.Tea, err error)
 	List(ctx context.Context, search *string) ([]common.Tea, error)
+	ListByTag(ctx context.Context, tagID uuid.UUID, search *string) ([]common.Tea, error)
+	DuplicateCandidates(ctx context.Context, threshold *float64) ([]dedup.Pair, error)
+	Merge(ctx context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error)
 	SubscribeOnCreate(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnUpdate(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnDelete(ctx context.Context) (<-chan gqlCommon.ID, error)
 }
 
 type qrManager interface {
-	Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData) (err error)
-	Get(ctx context.Context, id uuid.UUID) (*model.QRRecordData, error)
+	Set(ctx context.Context, id uuid.UUID, data *model.QRRecordData, actor qrPkg.Actor) (err error)
+	Get(ctx context.Context, id uuid.UUID) (*common.QR, error)
+	Release(ctx context.Context, id uuid.UUID, actor qrPkg.Actor) error
+	Transfer(ctx context.Context, id uuid.UUID, to uuid.UUID, actor qrPkg.Actor) error
+	Hidden(record *common.QR, actor *qrPkg.Actor) []model.QRRecordField
+	Batches(ctx context.Context) ([]common.QRBatch, error)
+	Batch(ctx context.Context, id uuid.UUID) (*common.QRBatch, error)
 }
 
 type tagManager interface {
@@ -72,8 +74,16 @@ type tagManager interface {
 	ListByTea(ctx context.Context, id uuid.UUID) (list []common.Tag, err error)
 	AddTagToTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
 	DeleteTagFromTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID) error
+	SetTeaTags(ctx context.Context, tea uuid.UUID, tags []uuid.UUID) error
+	BulkTag(ctx context.Context, teas, add, remove []uuid.UUID) error
 	SubscribeOnAddTagToTea(ctx context.Context) (<-chan *model.Tea, error)
 	SubscribeOnDeleteTagToTea(ctx context.Context) (<-chan *model.Tea, error)
+	SetParent(ctx context.Context, id uuid.UUID, parentID *uuid.UUID) (*common.Tag, error)
+	Children(ctx context.Context, id uuid.UUID) (list []common.Tag, err error)
+	AddSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error)
+	DeleteSynonym(ctx context.Context, id uuid.UUID, name string) (*common.Tag, error)
+	Synonyms(ctx context.Context, id uuid.UUID) ([]string, error)
+	Merge(ctx context.Context, source, target uuid.UUID) (*common.Tag, error)
 }
 
 type collectionManager interface {
@@ -86,7 +96,16 @@ type collectionManager interface {
 }
 
 type auth interface {
-	Auth(ctx context.Context, token string) (*common.Session, error)
+	Auth(ctx context.Context, token string, deviceID uuid.UUID, device common.DeviceInfo) (*common.Session, error)
+	RefreshSession(ctx context.Context, refreshToken string, deviceID uuid.UUID) (*common.Session, error)
+	Logout(ctx context.Context, user *common.User) error
+	LogoutEverywhere(ctx context.Context, userID uuid.UUID) (int, error)
+	AdminKeys() authPkg.AdminKeyStatus
+	RevokeAdminToken(ctx context.Context, jti, reason string) (bool, error)
+	ConsumeAdminToken(ctx context.Context) error
+	AdminTokenRevocations(ctx context.Context) ([]common.AdminTokenRevocation, error)
+	SetUserRole(ctx context.Context, userID uuid.UUID, role common.Role) error
+	DevLogin(ctx context.Context, name string, deviceID uuid.UUID, role *common.Role, admin bool) (*authPkg.DevSession, error)
 }
 
 type ai interface {
@@ -95,25 +114,45 @@ type ai interface {
 }
 
 type notificationsManager interface {
-	BindDevice(ctx context.Context, userID uuid.UUID, deviceID uuid.UUID) error
-	RegisterDeviceToken(ctx context.Context, deviceID uuid.UUID, deviceToken string) error
-	Notifications(ctx context.Context, userID uuid.UUID) ([]common.Notification, error)
+	Notifications(ctx context.Context, userID uuid.UUID, first *int, after *uuid.UUID) ([]common.Notification, error)
+	UnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error)
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int, error)
+	Preferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error)
+	SetPreferences(ctx context.Context, p *common.NotificationPreferences) error
+	Routes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error)
+	SetRoutes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) ([]common.NotificationRoute, error)
+	VerifyEmail(ctx context.Context, userID uuid.UUID, address, code string) (*common.NotificationRoute, error)
+	Subscribe(ctx context.Context, userID uuid.UUID, backlog bool) (<-chan *common.Notification, error)
+}
+
+type deviceManager interface {
+	List(ctx context.Context, userID uuid.UUID) ([]common.Device, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (*common.Device, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	RegisterToken(
+		ctx context.Context, userID, id uuid.UUID, token string, env common.DeviceEnvironment, info common.DeviceInfo,
+	) error
 }
 
-type debug interface {
-	Run(ctx context.Context) error
+type jobs interface {
+	Trigger(ctx context.Context, name string) (*common.JobRun, error)
+	Runs(ctx context.Context, job string, limit *int) ([]common.JobRun, error)
 }
 
 type adviser interface {
 	RecommendTea(ctx context.Context, teas []common.Tea, weather common.Weather, feelings string) (string, error)
 	RecommendTeaStream(ctx context.Context, teas []common.Tea, weather common.Weather, feelings string, res chan<- string) error
-	ContextScores(ctx context.Context, teas []string, weather common.Weather, day time.Weekday) (map[string]int, error)
 }
 
 type weather interface {
 	CurrentCyprus(ctx context.Context) (common.Weather, error)
 }
 
+type teaOfTheDay interface {
+	Today(ctx context.Context, userID uuid.UUID) (*common.TeaOfTheDay, error)
+}
+
 // Resolver wires together data sources and services for GraphQL resolvers.
 type Resolver struct {
 	teaData
@@ -123,13 +162,15 @@ type Resolver struct {
 	auth
 	ai
 	notificationsManager
-	debug
 	adviser
 	weather
-	consumption consumption.Store
+	teaOfTheDay   teaOfTheDay
+	jobs          jobs
+	deviceManager deviceManager
 
-	todCache *teaOfTheDayCache
-	log      logger
+	// publicBaseURL is the origin of the universal links encoded in labels and NFC payloads.
+	publicBaseURL string
+	log           logger
 }
 
 // NewResolver constructs a Resolver with its required dependencies.
@@ -142,10 +183,12 @@ func NewResolver(
 	auth auth,
 	ai ai,
 	notificationsManager notificationsManager,
-	debug debug,
+	deviceManager deviceManager,
+	jobs jobs,
 	adviser adviser,
 	weather weather,
-	cons consumption.Store,
+	teaOfTheDay teaOfTheDay,
+	publicBaseURL string,
 ) *Resolver {
 	return &Resolver{
 		teaData:              teaData,
@@ -155,11 +198,72 @@ func NewResolver(
 		auth:                 auth,
 		ai:                   ai,
 		notificationsManager: notificationsManager,
-		debug:                debug,
 		adviser:              adviser,
 		weather:              weather,
-		consumption:          cons,
-		todCache:             newTeaOfTheDayCache(),
+		teaOfTheDay:          teaOfTheDay,
+		jobs:                 jobs,
+		deviceManager:        deviceManager,
+		publicBaseURL:        publicBaseURL,
 		log:                  logger,
 	}
 }
+
+func toUUIDs(ids []gqlCommon.ID) []uuid.UUID {
+	res := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		res[i] = uuid.UUID(id)
+	}
+	return res
+}
+
+// qrActor identifies the caller for QR ownership checks: an admin session or an authenticated user,
+// who acts as admin with the admin role.
+func qrActor(ctx context.Context) (*qrPkg.Actor, error) {
+	if _, ok := authPkg.AdminPrincipalFrom(ctx); ok {
+		return &qrPkg.Actor{Admin: true}, nil
+	}
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, common.ErrUnauthorized
+	}
+	return &qrPkg.Actor{UserID: &user.ID, Admin: user.Role == common.RoleAdmin}, nil
+}
+
+// visibleTeas returns the teas of the records whose tea the caller may see.
+func visibleTeas(records []*model.QRRecord) []common.Tea {
+	teas := make([]common.Tea, 0, len(records))
+	for _, rec := range records {
+		if rec.Visible(model.QRRecordFieldTea) && rec.Tea != nil {
+			teas = append(teas, rec.Tea.ToCommonTea())
+		}
+	}
+
+	return teas
+}
+
+// qrRecord loads a QR record with its tea, hiding the fields actor may not see.
+func (r *Resolver) qrRecord(ctx context.Context, id uuid.UUID, actor *qrPkg.Actor) (*model.QRRecord, error) {
+	rec, err := r.qrManager.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tea, err := r.teaData.Get(ctx, rec.Tea)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.QRRecord{
+		ID:             gqlCommon.ID(id),
+		Tea:            model.FromCommonTea(tea),
+		BowlingTemp:    rec.BowlingTemp,
+		ExpirationDate: rec.ExpirationDate,
+		Hidden:         r.qrManager.Hidden(rec, actor),
+	}
+	if rec.Owner != nil {
+		owner := gqlCommon.ID(*rec.Owner)
+		res.Owner = &owner
+	}
+
+	return res, nil
+}
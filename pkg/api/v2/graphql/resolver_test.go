@@ -0,0 +1,34 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
+
+type failingAuth struct {
+	auth
+	err error
+}
+
+func (a failingAuth) Auth(context.Context, string, uuid.UUID, common.DeviceInfo) (*common.Session, error) {
+	return nil, a.err
+}
+
+func TestAuthApple(t *testing.T) {
+	r := &mutationResolver{&Resolver{auth: failingAuth{err: fmt.Errorf("bind device: %w", common.ErrDeviceInUse)}}}
+
+	_, err := r.AuthApple(context.Background(), "code", gqlCommon.ID(uuid.New()), nil)
+
+	var gqlErr *gqlerror.Error
+	require.ErrorAs(t, err, &gqlErr)
+	assert.Equal(t, ErrDeviceInUse, gqlErr.Extensions["code"])
+}
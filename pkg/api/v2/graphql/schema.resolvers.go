@@ -6,18 +6,19 @@ package graphql
 
 import (
 	"context"
-	"strings"
+	"encoding/base64"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	rootCommon "github.com/teaelephant/TeaElephantMemory/common"
 	authPkg "github.com/teaelephant/TeaElephantMemory/internal/auth"
-	"github.com/teaelephant/TeaElephantMemory/internal/scoring"
+	"github.com/teaelephant/TeaElephantMemory/internal/expiration"
+	"github.com/teaelephant/TeaElephantMemory/nfc"
 	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/graphql/generated"
 	model "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/models"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
 )
 
 // Records is the resolver for the records field.
@@ -26,27 +27,78 @@ func (r *collectionResolver) Records(ctx context.Context, obj *model.Collection)
 }
 
 // AuthApple is the resolver for the authApple field.
-func (r *mutationResolver) AuthApple(ctx context.Context, appleCode string, deviceID common.ID) (*model.Session, error) {
-	session, err := r.Auth(ctx, appleCode)
+func (r *mutationResolver) AuthApple(ctx context.Context, appleCode string, deviceID common.ID, device *model.DeviceInfo) (*model.Session, error) {
+	session, err := r.Auth(ctx, appleCode, uuid.UUID(deviceID), device.ToCommon())
 	if err != nil {
-		return nil, gqlerror.Wrap(err)
+		return nil, castGQLError(ctx, err)
 	}
 
-	if err = r.BindDevice(ctx, session.User.ID, uuid.UUID(deviceID)); err != nil {
-		return nil, err
+	return model.FromCommonSession(session), nil
+}
+
+// RefreshSession is the resolver for the refreshSession field.
+func (r *mutationResolver) RefreshSession(ctx context.Context, refreshToken string, deviceID common.ID) (*model.Session, error) {
+	session, err := r.auth.RefreshSession(ctx, refreshToken, uuid.UUID(deviceID))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	return &model.Session{
-		Token:     session.JWT,
-		ExpiredAt: session.ExpiredAt,
-	}, nil
+	return model.FromCommonSession(session), nil
 }
 
-// NewTea is the resolver for the newTea field.
-func (r *mutationResolver) NewTea(ctx context.Context, tea model.TeaData) (*model.Tea, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return false, castGQLError(ctx, err)
+	}
+
+	if err = r.auth.Logout(ctx, user); err != nil {
+		return false, castGQLError(ctx, err)
+	}
+
+	return true, nil
+}
+
+// LogoutEverywhere is the resolver for the logoutEverywhere field.
+func (r *mutationResolver) LogoutEverywhere(ctx context.Context) (int, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	n, err := r.auth.LogoutEverywhere(ctx, user.ID)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	return n, nil
+}
+
+// DevLogin is the resolver for the devLogin field.
+func (r *mutationResolver) DevLogin(ctx context.Context, name string, deviceID common.ID, role *model.Role, admin *bool) (*model.DevLogin, error) {
+	var commonRole *rootCommon.Role
+	if role != nil {
+		rl := role.ToCommon()
+		commonRole = &rl
+	}
+
+	res, err := r.auth.DevLogin(ctx, name, uuid.UUID(deviceID), commonRole, admin != nil && *admin)
+	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
+
+	login := &model.DevLogin{Session: model.FromCommonSession(res.Session)}
+	if res.AdminToken != "" {
+		login.AdminToken = &res.AdminToken
+		login.AdminTokenExpiredAt = &res.AdminTokenExpiredAt
+	}
+
+	return login, nil
+}
+
+// NewTea is the resolver for the newTea field.
+func (r *mutationResolver) NewTea(ctx context.Context, tea model.TeaData) (*model.Tea, error) {
 	res, err := r.teaData.Create(ctx, tea.ToCommonTeaData())
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -57,9 +109,6 @@ func (r *mutationResolver) NewTea(ctx context.Context, tea model.TeaData) (*mode
 
 // UpdateTea is the resolver for the updateTea field.
 func (r *mutationResolver) UpdateTea(ctx context.Context, id common.ID, tea model.TeaData) (*model.Tea, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	res, err := r.teaData.Update(ctx, uuid.UUID(id), tea.ToCommonTeaData())
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -70,9 +119,6 @@ func (r *mutationResolver) UpdateTea(ctx context.Context, id common.ID, tea mode
 
 // AddTagToTea is the resolver for the addTagToTea field.
 func (r *mutationResolver) AddTagToTea(ctx context.Context, teaID common.ID, tagID common.ID) (*model.Tea, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	if err := r.tagManager.AddTagToTea(ctx, uuid.UUID(teaID), uuid.UUID(tagID)); err != nil {
 		return nil, castGQLError(ctx, err)
 	}
@@ -87,10 +133,21 @@ func (r *mutationResolver) AddTagToTea(ctx context.Context, teaID common.ID, tag
 
 // DeleteTagFromTea is the resolver for the deleteTagFromTea field.
 func (r *mutationResolver) DeleteTagFromTea(ctx context.Context, teaID common.ID, tagID common.ID) (*model.Tea, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+	if err := r.tagManager.DeleteTagFromTea(ctx, uuid.UUID(teaID), uuid.UUID(tagID)); err != nil {
 		return nil, castGQLError(ctx, err)
 	}
-	if err := r.tagManager.DeleteTagFromTea(ctx, uuid.UUID(teaID), uuid.UUID(tagID)); err != nil {
+
+	t, err := r.teaData.Get(ctx, uuid.UUID(teaID))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTea(t), nil
+}
+
+// SetTeaTags is the resolver for the setTeaTags field.
+func (r *mutationResolver) SetTeaTags(ctx context.Context, teaID common.ID, tagIDs []common.ID) (*model.Tea, error) {
+	if err := r.tagManager.SetTeaTags(ctx, uuid.UUID(teaID), toUUIDs(tagIDs)); err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
@@ -102,9 +159,28 @@ func (r *mutationResolver) DeleteTagFromTea(ctx context.Context, teaID common.ID
 	return model.FromCommonTea(t), nil
 }
 
+// BulkTag is the resolver for the bulkTag field.
+func (r *mutationResolver) BulkTag(ctx context.Context, teaIDs []common.ID, addTagIDs []common.ID, removeTagIDs []common.ID) ([]*model.Tea, error) {
+	teas := toUUIDs(teaIDs)
+	if err := r.tagManager.BulkTag(ctx, teas, toUUIDs(addTagIDs), toUUIDs(removeTagIDs)); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	res := make([]*model.Tea, len(teas))
+	for i, id := range teas {
+		t, err := r.teaData.Get(ctx, id)
+		if err != nil {
+			return nil, castGQLError(ctx, err)
+		}
+		res[i] = model.FromCommonTea(t)
+	}
+
+	return res, nil
+}
+
 // DeleteTea is the resolver for the deleteTea field.
 func (r *mutationResolver) DeleteTea(ctx context.Context, id common.ID) (common.ID, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+	if err := r.auth.ConsumeAdminToken(ctx); err != nil {
 		return common.ID{}, castGQLError(ctx, err)
 	}
 	if err := r.teaData.Delete(ctx, uuid.UUID(id)); err != nil {
@@ -114,35 +190,76 @@ func (r *mutationResolver) DeleteTea(ctx context.Context, id common.ID) (common.
 	return id, nil
 }
 
+// MergeTeas is the resolver for the mergeTeas field.
+func (r *mutationResolver) MergeTeas(ctx context.Context, keepID common.ID, mergeIDs []common.ID) (*model.Tea, error) {
+	if err := r.auth.ConsumeAdminToken(ctx); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+	t, err := r.teaData.Merge(ctx, uuid.UUID(keepID), toUUIDs(mergeIDs))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTea(t), nil
+}
+
 // WriteToQR is the resolver for the writeToQR field.
 func (r *mutationResolver) WriteToQR(ctx context.Context, id common.ID, data model.QRRecordData) (*model.QRRecord, error) {
-	if err := r.Set(ctx, uuid.UUID(id), &data); err != nil {
+	actor, err := qrActor(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+	if err = r.qrManager.Set(ctx, uuid.UUID(id), &data, *actor); err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	tea, err := r.teaData.Get(ctx, uuid.UUID(data.Tea))
+	res, err := r.qrRecord(ctx, uuid.UUID(id), actor)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	return &model.QRRecord{
-		ID: id,
-		Tea: &model.Tea{
-			ID:          common.ID(tea.ID),
-			Name:        tea.Name,
-			Type:        model.FromBeverageType(tea.Type),
-			Description: tea.Description,
-		},
-		BowlingTemp:    data.BowlingTemp,
-		ExpirationDate: data.ExpirationDate,
-	}, nil
+	return res, nil
 }
 
-// CreateTagCategory is the resolver for the createTagCategory field.
-func (r *mutationResolver) CreateTagCategory(ctx context.Context, name string) (*model.TagCategory, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+// ReleaseQR is the resolver for the releaseQR field.
+func (r *mutationResolver) ReleaseQR(ctx context.Context, id common.ID) (*model.QRRecord, error) {
+	actor, err := qrActor(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+	if err = r.qrManager.Release(ctx, uuid.UUID(id), *actor); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	res, err := r.qrRecord(ctx, uuid.UUID(id), actor)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return res, nil
+}
+
+// TransferQR is the resolver for the transferQR field.
+func (r *mutationResolver) TransferQR(ctx context.Context, id common.ID, toUser common.ID) (*model.QRRecord, error) {
+	actor, err := qrActor(ctx)
+	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
+	if err = r.qrManager.Transfer(ctx, uuid.UUID(id), uuid.UUID(toUser), *actor); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	// The caller may no longer own the record, so visibility follows the new owner.
+	res, err := r.qrRecord(ctx, uuid.UUID(id), actor)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return res, nil
+}
+
+// CreateTagCategory is the resolver for the createTagCategory field.
+func (r *mutationResolver) CreateTagCategory(ctx context.Context, name string) (*model.TagCategory, error) {
 	category, err := r.CreateCategory(ctx, name)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -156,9 +273,6 @@ func (r *mutationResolver) CreateTagCategory(ctx context.Context, name string) (
 
 // UpdateTagCategory is the resolver for the updateTagCategory field.
 func (r *mutationResolver) UpdateTagCategory(ctx context.Context, id common.ID, name string) (*model.TagCategory, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	cat, err := r.UpdateCategory(ctx, uuid.UUID(id), name)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -172,7 +286,7 @@ func (r *mutationResolver) UpdateTagCategory(ctx context.Context, id common.ID,
 
 // DeleteTagCategory is the resolver for the deleteTagCategory field.
 func (r *mutationResolver) DeleteTagCategory(ctx context.Context, id common.ID) (common.ID, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+	if err := r.auth.ConsumeAdminToken(ctx); err != nil {
 		return common.ID{}, castGQLError(ctx, err)
 	}
 	if err := r.DeleteCategory(ctx, uuid.UUID(id)); err != nil {
@@ -184,9 +298,6 @@ func (r *mutationResolver) DeleteTagCategory(ctx context.Context, id common.ID)
 
 // CreateTag is the resolver for the createTag field.
 func (r *mutationResolver) CreateTag(ctx context.Context, name string, color string, category common.ID) (*model.Tag, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	tag, err := r.tagManager.Create(ctx, name, color, uuid.UUID(category))
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -201,9 +312,6 @@ func (r *mutationResolver) CreateTag(ctx context.Context, name string, color str
 
 // UpdateTag is the resolver for the updateTag field.
 func (r *mutationResolver) UpdateTag(ctx context.Context, id common.ID, name string, color string) (*model.Tag, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	tag, err := r.tagManager.Update(ctx, uuid.UUID(id), name, color)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -218,9 +326,6 @@ func (r *mutationResolver) UpdateTag(ctx context.Context, id common.ID, name str
 
 // ChangeTagCategory is the resolver for the changeTagCategory field.
 func (r *mutationResolver) ChangeTagCategory(ctx context.Context, id common.ID, category common.ID) (*model.Tag, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
-		return nil, castGQLError(ctx, err)
-	}
 	tag, err := r.ChangeCategory(ctx, uuid.UUID(id), uuid.UUID(category))
 	if err != nil {
 		return nil, castGQLError(ctx, err)
@@ -235,7 +340,7 @@ func (r *mutationResolver) ChangeTagCategory(ctx context.Context, id common.ID,
 
 // DeleteTag is the resolver for the deleteTag field.
 func (r *mutationResolver) DeleteTag(ctx context.Context, id common.ID) (common.ID, error) {
-	if err := authPkg.RequireAdmin(ctx); err != nil {
+	if err := r.auth.ConsumeAdminToken(ctx); err != nil {
 		return common.ID{}, castGQLError(ctx, err)
 	}
 	if err := r.tagManager.Delete(ctx, uuid.UUID(id)); err != nil {
@@ -245,6 +350,55 @@ func (r *mutationResolver) DeleteTag(ctx context.Context, id common.ID) (common.
 	return id, nil
 }
 
+// SetTagParent is the resolver for the setTagParent field.
+func (r *mutationResolver) SetTagParent(ctx context.Context, id common.ID, parent *common.ID) (*model.Tag, error) {
+	var parentID *uuid.UUID
+	if parent != nil {
+		pid := uuid.UUID(*parent)
+		parentID = &pid
+	}
+
+	tag, err := r.tagManager.SetParent(ctx, uuid.UUID(id), parentID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTag(tag), nil
+}
+
+// AddTagSynonym is the resolver for the addTagSynonym field.
+func (r *mutationResolver) AddTagSynonym(ctx context.Context, id common.ID, name string) (*model.Tag, error) {
+	tag, err := r.tagManager.AddSynonym(ctx, uuid.UUID(id), name)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTag(tag), nil
+}
+
+// DeleteTagSynonym is the resolver for the deleteTagSynonym field.
+func (r *mutationResolver) DeleteTagSynonym(ctx context.Context, id common.ID, name string) (*model.Tag, error) {
+	tag, err := r.tagManager.DeleteSynonym(ctx, uuid.UUID(id), name)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTag(tag), nil
+}
+
+// MergeTags is the resolver for the mergeTags field.
+func (r *mutationResolver) MergeTags(ctx context.Context, source common.ID, target common.ID) (*model.Tag, error) {
+	if err := r.auth.ConsumeAdminToken(ctx); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+	tag, err := r.tagManager.Merge(ctx, uuid.UUID(source), uuid.UUID(target))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTag(tag), nil
+}
+
 // CreateCollection is the resolver for the createCollection field.
 func (r *mutationResolver) CreateCollection(ctx context.Context, name string) (*model.Collection, error) {
 	user, err := authPkg.GetUser(ctx)
@@ -323,22 +477,166 @@ func (r *mutationResolver) DeleteCollection(ctx context.Context, id common.ID) (
 }
 
 // RegisterDeviceToken is the resolver for the registerDeviceToken field.
-func (r *mutationResolver) RegisterDeviceToken(ctx context.Context, deviceID common.ID, deviceToken string) (bool, error) {
-	if err := r.notificationsManager.RegisterDeviceToken(ctx, uuid.UUID(deviceID), deviceToken); err != nil {
+func (r *mutationResolver) RegisterDeviceToken(ctx context.Context, deviceID common.ID, deviceToken string, environment *model.DeviceEnvironment, device *model.DeviceInfo) (bool, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return false, castGQLError(ctx, err)
+	}
+
+	if err = r.deviceManager.RegisterToken(
+		ctx, user.ID, uuid.UUID(deviceID), deviceToken, environment.ToCommon(), device.ToCommon(),
+	); err != nil {
 		return false, castGQLError(ctx, err)
 	}
 
 	return true, nil
 }
 
-// Send is the resolver for the send field.
-func (r *mutationResolver) Send(ctx context.Context) (bool, error) {
-	_, err := authPkg.GetUser(ctx)
+// RenameDevice is the resolver for the renameDevice field.
+func (r *mutationResolver) RenameDevice(ctx context.Context, id common.ID, name string) (*model.Device, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	d, err := r.deviceManager.Rename(ctx, user.ID, uuid.UUID(id), name)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonDevice(d, user.DeviceID), nil
+}
+
+// RevokeDevice is the resolver for the revokeDevice field.
+func (r *mutationResolver) RevokeDevice(ctx context.Context, id common.ID) (common.ID, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return common.ID{}, castGQLError(ctx, err)
+	}
+
+	if err = r.deviceManager.Revoke(ctx, user.ID, uuid.UUID(id)); err != nil {
+		return common.ID{}, castGQLError(ctx, err)
+	}
+
+	return id, nil
+}
+
+// MarkNotificationRead is the resolver for the markNotificationRead field.
+func (r *mutationResolver) MarkNotificationRead(ctx context.Context, id common.ID) (*model.Notification, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	n, err := r.notificationsManager.MarkRead(ctx, user.ID, uuid.UUID(id))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotification(n), nil
+}
+
+// MarkAllRead is the resolver for the markAllRead field.
+func (r *mutationResolver) MarkAllRead(ctx context.Context) (int, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	count, err := r.notificationsManager.MarkAllRead(ctx, user.ID)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	return count, nil
+}
+
+// SetNotificationPreferences is the resolver for the setNotificationPreferences field.
+func (r *mutationResolver) SetNotificationPreferences(ctx context.Context, preferences model.NotificationPreferencesInput) (*model.NotificationPreferences, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	prefs, err := preferences.ToCommon(user.ID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	if err = r.notificationsManager.SetPreferences(ctx, prefs); err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotificationPreferences(prefs), nil
+}
+
+// SetNotificationRoutes is the resolver for the setNotificationRoutes field.
+func (r *mutationResolver) SetNotificationRoutes(ctx context.Context, routes []*model.NotificationRouteInput) ([]*model.NotificationRoute, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	res, err := r.notificationsManager.SetRoutes(ctx, user.ID, model.ToCommonNotificationRoutes(routes))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotificationRoutes(res), nil
+}
+
+// VerifyNotificationEmail is the resolver for the verifyNotificationEmail field.
+func (r *mutationResolver) VerifyNotificationEmail(ctx context.Context, address string, code string) (*model.NotificationRoute, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	route, err := r.notificationsManager.VerifyEmail(ctx, user.ID, address, code)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotificationRoute(route), nil
+}
+
+// RunJob is the resolver for the runJob field.
+func (r *mutationResolver) RunJob(ctx context.Context, name string) (*model.JobRun, error) {
+	run, err := r.jobs.Trigger(ctx, name)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonJobRun(run), nil
+}
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, userID common.ID, role model.Role) (model.Role, error) {
+	if err := r.auth.SetUserRole(ctx, uuid.UUID(userID), role.ToCommon()); err != nil {
+		return "", castGQLError(ctx, err)
+	}
+
+	return role, nil
+}
+
+// RevokeAdminToken is the resolver for the revokeAdminToken field.
+func (r *mutationResolver) RevokeAdminToken(ctx context.Context, jti string, reason *string) (bool, error) {
+	var why string
+	if reason != nil {
+		why = *reason
+	}
+
+	revoked, err := r.auth.RevokeAdminToken(ctx, jti, why)
 	if err != nil {
 		return false, castGQLError(ctx, err)
 	}
 
-	if err = r.Run(ctx); err != nil {
+	return revoked, nil
+}
+
+// Send is the resolver for the send field.
+func (r *mutationResolver) Send(ctx context.Context) (bool, error) {
+	if _, err := r.jobs.Trigger(ctx, expiration.JobName); err != nil {
 		return false, castGQLError(ctx, err)
 	}
 
@@ -362,16 +660,11 @@ func (r *mutationResolver) TeaRecommendation(ctx context.Context, collectionID c
 		return "", castGQLError(ctx, err)
 	}
 
-	if len(records) == 0 {
+	teas := visibleTeas(records)
+	if len(teas) == 0 {
 		return "", ErrNoTeas
 	}
 
-	teas := make([]rootCommon.Tea, len(records))
-
-	for i, rec := range records {
-		teas[i] = rec.Tea.ToCommonTea()
-	}
-
 	res, err := r.RecommendTea(ctx, teas, wth, feelings)
 	if err != nil {
 		return "", castGQLError(ctx, err)
@@ -380,6 +673,56 @@ func (r *mutationResolver) TeaRecommendation(ctx context.Context, collectionID c
 	return res, nil
 }
 
+// Tea is the resolver for the tea field.
+func (r *qRRecordResolver) Tea(ctx context.Context, obj *model.QRRecord) (*model.Tea, error) {
+	if !obj.Visible(model.QRRecordFieldTea) {
+		return nil, nil
+	}
+
+	return obj.Tea, nil
+}
+
+// BowlingTemp is the resolver for the bowlingTemp field.
+func (r *qRRecordResolver) BowlingTemp(ctx context.Context, obj *model.QRRecord) (*int, error) {
+	if !obj.Visible(model.QRRecordFieldBowlingTemp) {
+		return nil, nil
+	}
+
+	return &obj.BowlingTemp, nil
+}
+
+// ExpirationDate is the resolver for the expirationDate field.
+func (r *qRRecordResolver) ExpirationDate(ctx context.Context, obj *model.QRRecord) (*time.Time, error) {
+	if !obj.Visible(model.QRRecordFieldExpirationDate) {
+		return nil, nil
+	}
+
+	return &obj.ExpirationDate, nil
+}
+
+// Owner is the resolver for the owner field.
+func (r *qRRecordResolver) Owner(ctx context.Context, obj *model.QRRecord) (*common.ID, error) {
+	if !obj.Visible(model.QRRecordFieldOwner) {
+		return nil, nil
+	}
+
+	return obj.Owner, nil
+}
+
+// NfcPayload is the resolver for the nfcPayload field.
+func (r *qRRecordResolver) NfcPayload(ctx context.Context, obj *model.QRRecord) (*model.NFCPayload, error) {
+	id := uuid.UUID(obj.ID)
+
+	var name string
+	if obj.Visible(model.QRRecordFieldTea) && obj.Tea != nil {
+		name = obj.Tea.Name
+	}
+
+	msg := nfc.Message(qrlink.URL(r.publicBaseURL, id), name)
+
+	return &model.NFCPayload{Data: base64.StdEncoding.EncodeToString(msg), Hex: nfc.Hex(msg)}, nil
+}
+
 // Me is the resolver for the me field.
 func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
 	user, err := authPkg.GetUser(ctx)
@@ -387,12 +730,23 @@ func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
 		return nil, castGQLError(ctx, err)
 	}
 
-	return &model.User{TokenExpiredAt: user.ExpiredAt}, nil
+	res := &model.User{TokenExpiredAt: user.ExpiredAt}
+	res.Role.FromCommon(user.Role)
+
+	return res, nil
 }
 
 // Teas is the resolver for the teas field.
-func (r *queryResolver) Teas(ctx context.Context, prefix *string) ([]*model.Tea, error) {
-	res, err := r.teaData.List(ctx, prefix)
+func (r *queryResolver) Teas(ctx context.Context, prefix *string, tag *common.ID) ([]*model.Tea, error) {
+	var (
+		res []rootCommon.Tea
+		err error
+	)
+	if tag != nil {
+		res, err = r.teaData.ListByTag(ctx, uuid.UUID(*tag), prefix)
+	} else {
+		res, err = r.teaData.List(ctx, prefix)
+	}
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
@@ -427,22 +781,33 @@ func (r *queryResolver) GenerateDescription(ctx context.Context, name string) (s
 
 // QRRecord is the resolver for the qrRecord field.
 func (r *queryResolver) QRRecord(ctx context.Context, id common.ID) (*model.QRRecord, error) {
-	qr, err := r.qrManager.Get(ctx, uuid.UUID(id))
+	// Anonymous reads are allowed and only see the public fields.
+	actor, _ := qrActor(ctx)
+
+	res, err := r.qrRecord(ctx, uuid.UUID(id), actor)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	res, err := r.teaData.Get(ctx, uuid.UUID(qr.Tea))
+	return res, nil
+}
+
+// QRRecordByCode is the resolver for the qrRecordByCode field.
+func (r *queryResolver) QRRecordByCode(ctx context.Context, code string) (*model.QRRecord, error) {
+	id, err := qrlink.Parse(code)
+	if err != nil {
+		// Foreign QR codes are reported like unknown ones.
+		return nil, castGQLError(ctx, rootCommon.ErrQRRecordNotExist)
+	}
+
+	actor, _ := qrActor(ctx)
+
+	res, err := r.qrRecord(ctx, id, actor)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	return &model.QRRecord{
-		ID:             id,
-		Tea:            model.FromCommonTea(res),
-		BowlingTemp:    qr.BowlingTemp,
-		ExpirationDate: qr.ExpirationDate,
-	}, nil
+	return res, nil
 }
 
 // Tag is the resolver for the tag field.
@@ -502,151 +867,131 @@ func (r *queryResolver) TeaOfTheDay(ctx context.Context) (*model.TeaOfTheDay, er
 		return nil, castGQLError(ctx, err)
 	}
 
-	now := time.Now()
-	// Try cache first: cache is per-user and expires at next local midnight.
-	if cached, ok := r.todCache.Get(user.ID, now); ok {
-		if r.log != nil {
-			r.log.
-				WithField(logKeyUser, user.ID.String()).
-				WithField("date", now.Format(time.RFC3339)).
-				WithField("tea", cached.Tea.Tea.Name).
-				WithField("tea_id", uuid.UUID(cached.Tea.Tea.ID).String()).
-				Debug("tea_of_day cache_hit")
-		}
-		return cached, nil
+	pick, err := r.teaOfTheDay.Today(ctx, user.ID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	// Aggregate all teas from user's collections and track earliest expiration per tea
-	cols, err := r.collectionManager.List(ctx, user.ID)
+	actor, err := qrActor(ctx)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	earliestRec := make(map[common.ID]*model.QRRecord)
-	// Build candidates and name mappings on the fly to avoid extra iteration.
-	candidates := make([]scoring.Candidate, 0, 32)
-	names := make([]string, 0, 32)
-	nameToID := make(map[string]uuid.UUID, 32)
-	idToIdx := make(map[uuid.UUID]int, 32)
+	rec, err := r.qrRecord(ctx, pick.Record.ID, actor)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
 
-	for _, c := range cols {
-		records, err := r.ListRecords(ctx, uuid.UUID(c.ID), user.ID)
-		if err != nil {
-			return nil, castGQLError(ctx, err)
-		}
+	return &model.TeaOfTheDay{Tea: rec, Date: pick.Day}, nil
+}
 
-		for _, rec := range records {
-			if rec.Tea == nil {
-				continue
-			}
+// MyDevices is the resolver for the myDevices field.
+func (r *queryResolver) MyDevices(ctx context.Context) ([]*model.Device, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
 
-			idCommon := rec.Tea.ID
-			uid := uuid.UUID(idCommon)
-			name := rec.Tea.Name
-
-			if prev, seen := earliestRec[idCommon]; !seen {
-				// first time we see this tea: record earliestRec and add candidate + name mappings
-				earliestRec[idCommon] = rec
-				candidates = append(candidates, scoring.Candidate{ID: uid, Name: name, Expiration: rec.ExpirationDate})
-				idToIdx[uid] = len(candidates) - 1
-				names = append(names, name)
-				nameToID[strings.ToLower(strings.TrimSpace(name))] = uid
-			} else if rec.ExpirationDate.Before(prev.ExpirationDate) {
-				// found an earlier expiration for this tea: update earliestRec and candidate expiration
-				earliestRec[idCommon] = rec
-				if idx, ok := idToIdx[uid]; ok {
-					candidates[idx].Expiration = rec.ExpirationDate
-				}
-			}
-		}
+	devices, err := r.deviceManager.List(ctx, user.ID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	if len(earliestRec) == 0 {
-		return nil, ErrNoTeas
+	res := make([]*model.Device, len(devices))
+	for i := range devices {
+		res[i] = model.FromCommonDevice(&devices[i], user.DeviceID)
 	}
 
-	// Weather and recent consumption (best-effort)
-	w, wErr := r.CurrentCyprus(ctx)
-	if wErr != nil && r.log != nil {
-		r.log.WithField(logKeyUser, user.ID.String()).WithField(logKeyErr, wErr).Debug("tea_of_day weather fetch failed")
+	return res, nil
+}
+
+// DuplicateTeaCandidates is the resolver for the duplicateTeaCandidates field.
+func (r *queryResolver) DuplicateTeaCandidates(ctx context.Context, threshold *float64) ([]*model.DuplicateTeaCandidate, error) {
+	pairs, err := r.teaData.DuplicateCandidates(ctx, threshold)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	recent, recErr := r.consumption.Recent(ctx, user.ID, now.Add(-96*time.Hour))
-	if recErr != nil {
-		if r.log != nil {
-			r.log.WithField(logKeyUser, user.ID.String()).WithField(logKeyErr, recErr).Debug("tea_of_day recent fetch failed")
+	res := make([]*model.DuplicateTeaCandidate, len(pairs))
+	for i := range pairs {
+		res[i] = &model.DuplicateTeaCandidate{
+			A:                model.FromCommonTea(&pairs[i].A),
+			B:                model.FromCommonTea(&pairs[i].B),
+			Score:            pairs[i].Score,
+			NameScore:        pairs[i].NameScore,
+			TagScore:         pairs[i].TagScore,
+			DescriptionScore: pairs[i].DescriptionScore,
 		}
-		recent = nil
 	}
 
-	lastBy := make(map[uuid.UUID]time.Time, len(recent))
-	for _, c := range recent {
-		if t, ok := lastBy[c.TeaID]; !ok || c.Time.After(t) {
-			lastBy[c.TeaID] = c.Time
-		}
+	return res, nil
+}
+
+// QRBatches is the resolver for the qrBatches field.
+func (r *queryResolver) QRBatches(ctx context.Context) ([]*model.QRBatch, error) {
+	batches, err := r.qrManager.Batches(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	ctxScores, ctxErr := r.ContextScores(ctx, names, w, now.Weekday())
-	if ctxErr != nil {
-		if r.log != nil {
-			r.log.WithField("user", user.ID.String()).WithField("err", ctxErr).Debug("tea_of_the_day scoring failed")
-		}
-		ctxScores = make(map[string]int)
+	res := make([]*model.QRBatch, len(batches))
+	for i := range batches {
+		res[i] = model.FromCommonQRBatch(&batches[i])
 	}
-	aiScores := make(map[uuid.UUID]int, len(ctxScores))
-	for name, score := range ctxScores {
-		if id, ok := nameToID[strings.ToLower(strings.TrimSpace(name))]; ok {
-			aiScores[id] = score
-		}
+
+	return res, nil
+}
+
+// QRBatch is the resolver for the qrBatch field.
+func (r *queryResolver) QRBatch(ctx context.Context, id common.ID) (*model.QRBatch, error) {
+	batch, err := r.qrManager.Batch(ctx, uuid.UUID(id))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	// High-level context log
-	if r.log != nil {
-		weekday := now.Weekday().String()
-		wStr := w.String()
-		r.log.
-			WithField(logKeyUser, user.ID.String()).
-			WithField(logKeyWeekday, weekday).
-			WithField(logKeyWeather, wStr).
-			WithField("candidates", len(candidates)).
-			Debug("tea_of_day context")
-	}
-
-	// Delegate detailed candidate and selection logging to scoring package
-	var bestID uuid.UUID
-	if r.log != nil {
-		best, _ := scoring.SelectBestWithLogging(aiScores, candidates, lastBy, now, func(fields map[string]interface{}, msg string) {
-			entry := r.log.
-				WithField(logKeyUser, user.ID.String()).
-				WithField(logKeyWeekday, now.Weekday().String()).
-				WithField(logKeyWeather, w.String())
-			for k, v := range fields {
-				entry = entry.WithField(k, v)
-			}
-			entry.Debug(msg)
-		})
-		bestID = best
-	} else {
-		best, _ := scoring.SelectBest(aiScores, candidates, lastBy, now)
-		bestID = best
+	return model.FromCommonQRBatch(batch), nil
+}
+
+// JobRuns is the resolver for the jobRuns field.
+func (r *queryResolver) JobRuns(ctx context.Context, job *string, limit *int) ([]*model.JobRun, error) {
+	var name string
+	if job != nil {
+		name = *job
+	}
+	runs, err := r.jobs.Runs(ctx, name, limit)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	if bestID == uuid.Nil {
-		return nil, ErrNoTeaCandidates
+	res := make([]*model.JobRun, len(runs))
+	for i := range runs {
+		res[i] = model.FromCommonJobRun(&runs[i])
 	}
 
-	if err := r.consumption.Record(ctx, user.ID, bestID, now); err != nil && r.log != nil {
-		r.log.WithField(logKeyUser, user.ID.String()).WithField(logKeyErr, err).Debug("tea_of_day record consumption failed")
+	return res, nil
+}
+
+// AdminDiagnostics is the resolver for the adminDiagnostics field.
+func (r *queryResolver) AdminDiagnostics(ctx context.Context) (*model.AdminDiagnostics, error) {
+	revocations, err := r.auth.AdminTokenRevocations(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
 	}
 
-	// Build QRRecord for the selected tea using the earliest record we observed
-	qrr := earliestRec[common.ID(bestID)]
-	if qrr == nil {
-		return nil, ErrNoTeaCandidates
+	keys := r.auth.AdminKeys()
+	res := &model.AdminDiagnostics{
+		AdminKeyIDs:        keys.KeyIDs,
+		AdminKeysSource:    keys.Source,
+		AdminKeysLoadedAt:  keys.LoadedAt,
+		RevokedAdminTokens: make([]*model.AdminTokenRevocation, len(revocations)),
+	}
+	if keys.ReloadError != "" {
+		res.AdminKeysError = &keys.ReloadError
+	}
+	for i := range revocations {
+		res.RevokedAdminTokens[i] = model.FromCommonAdminTokenRevocation(&revocations[i])
 	}
 
-	res := &model.TeaOfTheDay{Tea: qrr, Date: now}
-	r.todCache.Set(user.ID, res, now)
 	return res, nil
 }
 
@@ -762,6 +1107,35 @@ func (r *subscriptionResolver) StartGenerateDescription(ctx context.Context, nam
 	return res, nil
 }
 
+// OnNotification is the resolver for the onNotification field.
+func (r *subscriptionResolver) OnNotification(ctx context.Context, backlog *bool) (<-chan *model.Notification, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	notifications, err := r.notificationsManager.Subscribe(ctx, user.ID, backlog != nil && *backlog)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	res := make(chan *model.Notification)
+
+	go func() {
+		defer close(res)
+
+		for n := range notifications {
+			select {
+			case res <- model.FromCommonNotification(n):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return res, nil
+}
+
 // RecommendTea is the resolver for the recommendTea field.
 func (r *subscriptionResolver) RecommendTea(ctx context.Context, collectionID common.ID, feelings string) (<-chan string, error) {
 	user, err := authPkg.GetUser(ctx)
@@ -779,16 +1153,11 @@ func (r *subscriptionResolver) RecommendTea(ctx context.Context, collectionID co
 		return nil, castGQLError(ctx, err)
 	}
 
-	if len(records) == 0 {
+	teas := visibleTeas(records)
+	if len(teas) == 0 {
 		return nil, ErrNoTeas
 	}
 
-	teas := make([]rootCommon.Tea, len(records))
-
-	for i, rec := range records {
-		teas[i] = rec.Tea.ToCommonTea()
-	}
-
 	res := make(chan string, 1000)
 	if err = r.RecommendTeaStream(ctx, teas, wth, feelings, res); err != nil {
 		return nil, castGQLError(ctx, err)
@@ -818,6 +1187,54 @@ func (r *tagResolver) Category(ctx context.Context, obj *model.Tag) (*model.TagC
 	}, nil
 }
 
+// Parent is the resolver for the parent field.
+func (r *tagResolver) Parent(ctx context.Context, obj *model.Tag) (*model.Tag, error) {
+	tag, err := r.tagManager.Get(ctx, uuid.UUID(obj.ID))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	if tag.ParentID == nil {
+		return nil, nil //nolint:nilnil // top-level tags have no parent
+	}
+
+	parent, err := r.tagManager.Get(ctx, *tag.ParentID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonTag(parent), nil
+}
+
+// Children is the resolver for the children field.
+func (r *tagResolver) Children(ctx context.Context, obj *model.Tag) ([]*model.Tag, error) {
+	children, err := r.tagManager.Children(ctx, uuid.UUID(obj.ID))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	result := make([]*model.Tag, len(children))
+	for i := range children {
+		result[i] = model.FromCommonTag(&children[i])
+	}
+
+	return result, nil
+}
+
+// Synonyms is the resolver for the synonyms field.
+func (r *tagResolver) Synonyms(ctx context.Context, obj *model.Tag) ([]string, error) {
+	synonyms, err := r.tagManager.Synonyms(ctx, uuid.UUID(obj.ID))
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	if synonyms == nil {
+		synonyms = []string{}
+	}
+
+	return synonyms, nil
+}
+
 // Tags is the resolver for the tags field.
 func (r *tagCategoryResolver) Tags(ctx context.Context, obj *model.TagCategory, name *string) ([]*model.Tag, error) {
 	var cat uuid.UUID
@@ -892,34 +1309,80 @@ func (r *userResolver) Collections(ctx context.Context, obj *model.User) ([]*mod
 }
 
 // Notifications is the resolver for the notifications field.
-func (r *userResolver) Notifications(ctx context.Context, obj *model.User) ([]*model.Notification, error) {
+func (r *userResolver) Notifications(ctx context.Context, obj *model.User, first *int, after *common.ID) ([]*model.Notification, error) {
 	user, err := authPkg.GetUser(ctx)
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
-	notifications, err := r.notificationsManager.Notifications(ctx, user.ID)
+	notifications, err := r.notificationsManager.Notifications(ctx, user.ID, first, (*uuid.UUID)(after))
 	if err != nil {
 		return nil, castGQLError(ctx, err)
 	}
 
 	res := make([]*model.Notification, len(notifications))
 
-	for i, not := range notifications {
-		t := new(model.NotificationType)
-		t.FromCommon(not.Type)
-		res[i] = &model.Notification{Type: *t}
+	for i := range notifications {
+		res[i] = model.FromCommonNotification(&notifications[i])
 	}
 
 	return res, nil
 }
 
+// UnreadNotifications is the resolver for the unreadNotifications field.
+func (r *userResolver) UnreadNotifications(ctx context.Context, obj *model.User) (int, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	count, err := r.notificationsManager.UnreadCount(ctx, user.ID)
+	if err != nil {
+		return 0, castGQLError(ctx, err)
+	}
+
+	return count, nil
+}
+
+// NotificationPreferences is the resolver for the notificationPreferences field.
+func (r *userResolver) NotificationPreferences(ctx context.Context, obj *model.User) (*model.NotificationPreferences, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	prefs, err := r.notificationsManager.Preferences(ctx, user.ID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotificationPreferences(prefs), nil
+}
+
+// NotificationRoutes is the resolver for the notificationRoutes field.
+func (r *userResolver) NotificationRoutes(ctx context.Context, obj *model.User) ([]*model.NotificationRoute, error) {
+	user, err := authPkg.GetUser(ctx)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	routes, err := r.notificationsManager.Routes(ctx, user.ID)
+	if err != nil {
+		return nil, castGQLError(ctx, err)
+	}
+
+	return model.FromCommonNotificationRoutes(routes), nil
+}
+
 // Collection returns generated.CollectionResolver implementation.
 func (r *Resolver) Collection() generated.CollectionResolver { return &collectionResolver{r} }
 
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// QRRecord returns generated.QRRecordResolver implementation.
+func (r *Resolver) QRRecord() generated.QRRecordResolver { return &qRRecordResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
@@ -940,6 +1403,7 @@ func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
 type collectionResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type qRRecordResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
 type tagResolver struct{ *Resolver }
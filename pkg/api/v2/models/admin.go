@@ -0,0 +1,13 @@
+package model
+
+import "github.com/teaelephant/TeaElephantMemory/common"
+
+// FromCommonAdminTokenRevocation converts a common.AdminTokenRevocation to the GraphQL type.
+func FromCommonAdminTokenRevocation(r *common.AdminTokenRevocation) *AdminTokenRevocation {
+	return &AdminTokenRevocation{
+		Jti:       r.JTI,
+		Reason:    r.Reason,
+		RevokedAt: r.RevokedAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
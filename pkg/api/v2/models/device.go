@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
+
+// ToCommon converts the GraphQL DeviceInfo to common.DeviceInfo; nil and omitted fields are empty.
+func (i *DeviceInfo) ToCommon() common.DeviceInfo {
+	var res common.DeviceInfo
+	if i == nil {
+		return res
+	}
+	if i.Platform != nil {
+		res.Platform = *i.Platform
+	}
+	if i.Model != nil {
+		res.Model = *i.Model
+	}
+	if i.AppVersion != nil {
+		res.AppVersion = *i.AppVersion
+	}
+
+	return res
+}
+
+// FromCommonDevice converts a common.Device to the GraphQL Device; current is the device of the
+// calling session.
+func FromCommonDevice(d *common.Device, current uuid.UUID) *Device {
+	res := &Device{
+		ID:          gqlCommon.ID(d.ID),
+		Name:        d.Name,
+		Platform:    d.Platform,
+		Model:       d.Model,
+		AppVersion:  d.AppVersion,
+		Environment: DeviceEnvironmentSandbox,
+		PushEnabled: d.Token != "",
+		LastSeenAt:  d.LastSeenAt,
+		SignedInAt:  d.BoundAt,
+		Current:     d.ID == current,
+	}
+	if d.Environment == common.DeviceEnvironmentProduction {
+		res.Environment = DeviceEnvironmentProduction
+	}
+
+	return res
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
+
+var jobTriggers = map[common.JobTrigger]JobTrigger{
+	common.JobTriggerSchedule: JobTriggerSchedule,
+	common.JobTriggerManual:   JobTriggerManual,
+}
+
+var jobRunStatuses = map[common.JobRunStatus]JobRunStatus{
+	common.JobRunStatusPending:   JobRunStatusPending,
+	common.JobRunStatusRunning:   JobRunStatusRunning,
+	common.JobRunStatusSucceeded: JobRunStatusSucceeded,
+	common.JobRunStatusFailed:    JobRunStatusFailed,
+}
+
+// FromCommonJobRun converts common.JobRun into GraphQL JobRun.
+func FromCommonJobRun(source *common.JobRun) *JobRun {
+	res := &JobRun{
+		ID:         gqlCommon.ID(source.ID),
+		Job:        source.Job,
+		Trigger:    jobTriggers[source.Trigger],
+		Status:     jobRunStatuses[source.Status],
+		CreatedAt:  source.CreatedAt,
+		StartedAt:  source.StartedAt,
+		FinishedAt: source.FinishedAt,
+	}
+	if source.Error != "" {
+		res.Error = &source.Error
+	}
+
+	return res
+}
@@ -12,6 +12,28 @@ import (
 	"github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
 )
 
+type AdminDiagnostics struct {
+	// kid of every admin key tokens are verified with; an empty kid is the key of a single PEM file, used for tokens without kid.
+	AdminKeyIDs []string `json:"adminKeyIDs"`
+	// The file or directory admin keys are loaded from.
+	AdminKeysSource string `json:"adminKeysSource"`
+	// When the admin keys last changed.
+	AdminKeysLoadedAt time.Time `json:"adminKeysLoadedAt"`
+	// Why the last reload of the admin keys failed; the previous keys stay in use.
+	AdminKeysError *string `json:"adminKeysError,omitempty"`
+	// Admin tokens that are revoked and not expired yet, newest first.
+	RevokedAdminTokens []*AdminTokenRevocation `json:"revokedAdminTokens"`
+}
+
+type AdminTokenRevocation struct {
+	Jti string `json:"jti"`
+	// Given to revokeAdminToken; "used" for tokens spent on a one-time-use mutation.
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revokedAt"`
+	// When the revocation is dropped; the token is expired by then.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 type Collection struct {
 	ID      common.ID   `json:"id"`
 	Name    string      `json:"name"`
@@ -19,18 +41,145 @@ type Collection struct {
 	Records []*QRRecord `json:"records"`
 }
 
+type DevLogin struct {
+	Session *Session `json:"session"`
+	// Admin token signed with the ephemeral admin key of dev mode, if admin was set.
+	AdminToken          *string    `json:"adminToken,omitempty"`
+	AdminTokenExpiredAt *time.Time `json:"adminTokenExpiredAt,omitempty"`
+}
+
+type Device struct {
+	ID common.ID `json:"id"`
+	// Set by the user with renameDevice; empty until then.
+	Name        string            `json:"name"`
+	Platform    string            `json:"platform"`
+	Model       string            `json:"model"`
+	AppVersion  string            `json:"appVersion"`
+	Environment DeviceEnvironment `json:"environment"`
+	// Whether the device registered a push token.
+	PushEnabled bool       `json:"pushEnabled"`
+	LastSeenAt  *time.Time `json:"lastSeenAt,omitempty"`
+	// When the device was signed in with the user.
+	SignedInAt time.Time `json:"signedInAt"`
+	// Whether this is the device of the calling session.
+	Current bool `json:"current"`
+}
+
+// Metadata reported by the app; omitted fields keep their previous value.
+type DeviceInfo struct {
+	// e.g. iOS 18.1
+	Platform *string `json:"platform,omitempty"`
+	// e.g. iPhone16,2
+	Model      *string `json:"model,omitempty"`
+	AppVersion *string `json:"appVersion,omitempty"`
+}
+
+type DuplicateTeaCandidate struct {
+	A *Tea `json:"a"`
+	B *Tea `json:"b"`
+	// Combined score in [0, 1].
+	Score            float64 `json:"score"`
+	NameScore        float64 `json:"nameScore"`
+	TagScore         float64 `json:"tagScore"`
+	DescriptionScore float64 `json:"descriptionScore"`
+}
+
+type JobRun struct {
+	ID         common.ID    `json:"id"`
+	Job        string       `json:"job"`
+	Trigger    JobTrigger   `json:"trigger"`
+	Status     JobRunStatus `json:"status"`
+	Error      *string      `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	StartedAt  *time.Time   `json:"startedAt,omitempty"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+}
+
 type Mutation struct {
 }
 
+type NFCPayload struct {
+	// Raw NDEF message, base64 encoded.
+	Data string `json:"data"`
+	// The same message as upper-case hex.
+	Hex string `json:"hex"`
+}
+
 type Notification struct {
-	Type NotificationType `json:"type"`
+	ID    common.ID        `json:"id"`
+	Type  NotificationType `json:"type"`
+	Title string           `json:"title"`
+	Body  string           `json:"body"`
+	// QR record or tea the notification is about; null for digests.
+	Entity    *NotificationEntity `json:"entity,omitempty"`
+	CreatedAt time.Time           `json:"createdAt"`
+	ReadAt    *time.Time          `json:"readAt,omitempty"`
+	// Push outcome per device.
+	Deliveries []*NotificationDelivery `json:"deliveries"`
 }
 
-type QRRecord struct {
-	ID             common.ID `json:"id"`
-	Tea            *Tea      `json:"tea"`
-	BowlingTemp    int       `json:"bowlingTemp"`
-	ExpirationDate time.Time `json:"expirationDate"`
+type NotificationDelivery struct {
+	Channel NotificationChannel `json:"channel"`
+	// Device id, webhook URL or email address.
+	Target string `json:"target"`
+	// Set for apns deliveries.
+	Device *common.ID     `json:"device,omitempty"`
+	Status DeliveryStatus `json:"status"`
+	// Failure reason reported by the push service.
+	Reason    *string   `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type NotificationEntity struct {
+	Type NotificationEntityType `json:"type"`
+	ID   common.ID              `json:"id"`
+}
+
+// Times of day are HH:MM in the user's time zone.
+type NotificationPreferences struct {
+	// IANA time zone name, e.g. Europe/Nicosia.
+	Timezone string `json:"timezone"`
+	// Notification types that are not sent at all.
+	DisabledTypes []NotificationType `json:"disabledTypes"`
+	// Pushes are held back during quiet hours and sent when they end.
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	// When set, pushes are held back until this time of day.
+	DeliveryTime *string `json:"deliveryTime,omitempty"`
+	// Opt-in daily Tea of the Day push at this time of day. Quiet hours and deliveryTime do not hold it back.
+	TeaOfTheDayTime *string `json:"teaOfTheDayTime,omitempty"`
+}
+
+type NotificationPreferencesInput struct {
+	Timezone        string             `json:"timezone"`
+	DisabledTypes   []NotificationType `json:"disabledTypes"`
+	QuietHours      *QuietHoursInput   `json:"quietHours,omitempty"`
+	DeliveryTime    *string            `json:"deliveryTime,omitempty"`
+	TeaOfTheDayTime *string            `json:"teaOfTheDayTime,omitempty"`
+}
+
+type NotificationRoute struct {
+	Channel NotificationChannel `json:"channel"`
+	// Webhook URL or email address; null for apns and inbox.
+	Target *string `json:"target,omitempty"`
+	// False for an email address not confirmed with verifyNotificationEmail yet; nothing is mailed to it until then.
+	Verified bool `json:"verified"`
+}
+
+type NotificationRouteInput struct {
+	Channel NotificationChannel `json:"channel"`
+	Target  *string             `json:"target,omitempty"`
+}
+
+type QRBatch struct {
+	ID        common.ID `json:"id"`
+	PrintedBy string    `json:"printedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Number of codes in the batch.
+	Total int `json:"total"`
+	// Codes already written with a tea.
+	Claimed int `json:"claimed"`
+	// Codes still blank.
+	Blank int `json:"blank"`
 }
 
 type QRRecordData struct {
@@ -42,9 +191,23 @@ type QRRecordData struct {
 type Query struct {
 }
 
+type QuietHours struct {
+	Start string `json:"start"`
+	// May be before start for a window that spans midnight.
+	End string `json:"end"`
+}
+
+type QuietHoursInput struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
 type Session struct {
 	Token     string    `json:"token"`
 	ExpiredAt time.Time `json:"expiredAt"`
+	// Exchange it with refreshSession before refreshExpiredAt to stay signed in.
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiredAt time.Time `json:"refreshExpiredAt"`
 }
 
 type Subscription struct {
@@ -55,6 +218,9 @@ type Tag struct {
 	Name     string       `json:"name"`
 	Color    string       `json:"color"`
 	Category *TagCategory `json:"category"`
+	Parent   *Tag         `json:"parent,omitempty"`
+	Children []*Tag       `json:"children"`
+	Synonyms []string     `json:"synonyms"`
 }
 
 type TagCategory struct {
@@ -83,9 +249,355 @@ type TeaOfTheDay struct {
 }
 
 type User struct {
-	TokenExpiredAt time.Time       `json:"tokenExpiredAt"`
-	Collections    []*Collection   `json:"collections"`
-	Notifications  []*Notification `json:"notifications"`
+	TokenExpiredAt time.Time     `json:"tokenExpiredAt"`
+	Role           Role          `json:"role"`
+	Collections    []*Collection `json:"collections"`
+	// Newest first. Pass the id of the last notification received as after to get the next page.
+	Notifications           []*Notification          `json:"notifications"`
+	UnreadNotifications     int                      `json:"unreadNotifications"`
+	NotificationPreferences *NotificationPreferences `json:"notificationPreferences"`
+	// Channels notifications are delivered over; empty when the server default is used.
+	NotificationRoutes []*NotificationRoute `json:"notificationRoutes"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent   DeliveryStatus = "sent"
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+var AllDeliveryStatus = []DeliveryStatus{
+	DeliveryStatusSent,
+	DeliveryStatusFailed,
+}
+
+func (e DeliveryStatus) IsValid() bool {
+	switch e {
+	case DeliveryStatusSent, DeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e DeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *DeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e DeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DeliveryStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DeliveryStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type DeviceEnvironment string
+
+const (
+	DeviceEnvironmentSandbox    DeviceEnvironment = "sandbox"
+	DeviceEnvironmentProduction DeviceEnvironment = "production"
+)
+
+var AllDeviceEnvironment = []DeviceEnvironment{
+	DeviceEnvironmentSandbox,
+	DeviceEnvironmentProduction,
+}
+
+func (e DeviceEnvironment) IsValid() bool {
+	switch e {
+	case DeviceEnvironmentSandbox, DeviceEnvironmentProduction:
+		return true
+	}
+	return false
+}
+
+func (e DeviceEnvironment) String() string {
+	return string(e)
+}
+
+func (e *DeviceEnvironment) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DeviceEnvironment(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DeviceEnvironment", str)
+	}
+	return nil
+}
+
+func (e DeviceEnvironment) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DeviceEnvironment) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DeviceEnvironment) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type JobRunStatus string
+
+const (
+	// queued by runJob and not started yet
+	JobRunStatusPending   JobRunStatus = "pending"
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	// error holds the reason; interrupted runs were cut short by a restart
+	JobRunStatusFailed JobRunStatus = "failed"
+)
+
+var AllJobRunStatus = []JobRunStatus{
+	JobRunStatusPending,
+	JobRunStatusRunning,
+	JobRunStatusSucceeded,
+	JobRunStatusFailed,
+}
+
+func (e JobRunStatus) IsValid() bool {
+	switch e {
+	case JobRunStatusPending, JobRunStatusRunning, JobRunStatusSucceeded, JobRunStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e JobRunStatus) String() string {
+	return string(e)
+}
+
+func (e *JobRunStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobRunStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobRunStatus", str)
+	}
+	return nil
+}
+
+func (e JobRunStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *JobRunStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e JobRunStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type JobTrigger string
+
+const (
+	JobTriggerSchedule JobTrigger = "schedule"
+	JobTriggerManual   JobTrigger = "manual"
+)
+
+var AllJobTrigger = []JobTrigger{
+	JobTriggerSchedule,
+	JobTriggerManual,
+}
+
+func (e JobTrigger) IsValid() bool {
+	switch e {
+	case JobTriggerSchedule, JobTriggerManual:
+		return true
+	}
+	return false
+}
+
+func (e JobTrigger) String() string {
+	return string(e)
+}
+
+func (e *JobTrigger) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobTrigger(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobTrigger", str)
+	}
+	return nil
+}
+
+func (e JobTrigger) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *JobTrigger) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e JobTrigger) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NotificationChannel string
+
+const (
+	NotificationChannelApns    NotificationChannel = "apns"
+	NotificationChannelWebhook NotificationChannel = "webhook"
+	NotificationChannelEmail   NotificationChannel = "email"
+	NotificationChannelInbox   NotificationChannel = "inbox"
+)
+
+var AllNotificationChannel = []NotificationChannel{
+	NotificationChannelApns,
+	NotificationChannelWebhook,
+	NotificationChannelEmail,
+	NotificationChannelInbox,
+}
+
+func (e NotificationChannel) IsValid() bool {
+	switch e {
+	case NotificationChannelApns, NotificationChannelWebhook, NotificationChannelEmail, NotificationChannelInbox:
+		return true
+	}
+	return false
+}
+
+func (e NotificationChannel) String() string {
+	return string(e)
+}
+
+func (e *NotificationChannel) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationChannel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationChannel", str)
+	}
+	return nil
+}
+
+func (e NotificationChannel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NotificationChannel) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NotificationChannel) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NotificationEntityType string
+
+const (
+	NotificationEntityTypeQR  NotificationEntityType = "qr"
+	NotificationEntityTypeTea NotificationEntityType = "tea"
+)
+
+var AllNotificationEntityType = []NotificationEntityType{
+	NotificationEntityTypeQR,
+	NotificationEntityTypeTea,
+}
+
+func (e NotificationEntityType) IsValid() bool {
+	switch e {
+	case NotificationEntityTypeQR, NotificationEntityTypeTea:
+		return true
+	}
+	return false
+}
+
+func (e NotificationEntityType) String() string {
+	return string(e)
+}
+
+func (e *NotificationEntityType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationEntityType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationEntityType", str)
+	}
+	return nil
+}
+
+func (e NotificationEntityType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NotificationEntityType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NotificationEntityType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
 
 type NotificationType string
@@ -145,6 +657,64 @@ func (e NotificationType) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// edits tags and tags teas
+	RoleCurator Role = "curator"
+	RoleAdmin   Role = "admin"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleCurator,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleCurator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type Type string
 
 const (
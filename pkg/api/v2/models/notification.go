@@ -1,7 +1,12 @@
 // Package model contains GraphQL models and helpers for API v2.
 package model
 
-import "github.com/teaelephant/TeaElephantMemory/common"
+import (
+	"github.com/google/uuid"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
 
 // FromCommon converts a common.NotificationType to the GraphQL NotificationType.
 func (t *NotificationType) FromCommon(data common.NotificationType) {
@@ -14,3 +19,190 @@ func (t *NotificationType) FromCommon(data common.NotificationType) {
 		*t = NotificationTypeUnknown
 	}
 }
+
+// ToCommon converts the GraphQL NotificationType to common.NotificationType; ok is false for unknown.
+func (t NotificationType) ToCommon() (res common.NotificationType, ok bool) {
+	switch t {
+	case NotificationTypeTeaExpiration:
+		return common.NotificationTypeTeaExpiration, true
+	case NotificationTypeTeaRecommendation:
+		return common.NotificationTypeTeaRecommendation, true
+	case NotificationTypeUnknown:
+	}
+
+	return 0, false
+}
+
+// ToCommon converts the GraphQL DeviceEnvironment to common.DeviceEnvironment; nil means sandbox.
+func (e *DeviceEnvironment) ToCommon() common.DeviceEnvironment {
+	if e != nil && *e == DeviceEnvironmentProduction {
+		return common.DeviceEnvironmentProduction
+	}
+
+	return common.DeviceEnvironmentSandbox
+}
+
+var notificationChannels = map[common.NotificationChannel]NotificationChannel{
+	common.NotificationChannelAPNs:    NotificationChannelApns,
+	common.NotificationChannelWebhook: NotificationChannelWebhook,
+	common.NotificationChannelEmail:   NotificationChannelEmail,
+	common.NotificationChannelInbox:   NotificationChannelInbox,
+}
+
+// FromCommon converts a common.NotificationChannel to the GraphQL NotificationChannel.
+func (c *NotificationChannel) FromCommon(data common.NotificationChannel) {
+	*c = notificationChannels[data]
+}
+
+// ToCommon converts the GraphQL NotificationChannel to common.NotificationChannel; unknown values map
+// to a channel that fails route validation.
+func (c NotificationChannel) ToCommon() common.NotificationChannel {
+	for k, v := range notificationChannels {
+		if v == c {
+			return k
+		}
+	}
+
+	return -1
+}
+
+// FromCommonNotificationRoute converts a common route into a GraphQL NotificationRoute.
+func FromCommonNotificationRoute(source *common.NotificationRoute) *NotificationRoute {
+	res := &NotificationRoute{Verified: source.Verified}
+	res.Channel.FromCommon(source.Channel)
+	if source.Target != "" {
+		target := source.Target
+		res.Target = &target
+	}
+
+	return res
+}
+
+// FromCommonNotificationRoutes converts common routes into GraphQL NotificationRoutes.
+func FromCommonNotificationRoutes(source []common.NotificationRoute) []*NotificationRoute {
+	res := make([]*NotificationRoute, len(source))
+	for i := range source {
+		res[i] = FromCommonNotificationRoute(&source[i])
+	}
+
+	return res
+}
+
+// ToCommonNotificationRoutes converts route inputs into common routes.
+func ToCommonNotificationRoutes(source []*NotificationRouteInput) []common.NotificationRoute {
+	res := make([]common.NotificationRoute, len(source))
+	for i, r := range source {
+		res[i].Channel = r.Channel.ToCommon()
+		if r.Target != nil {
+			res[i].Target = *r.Target
+		}
+	}
+
+	return res
+}
+
+// FromCommonNotification converts a common.Notification into a GraphQL Notification.
+func FromCommonNotification(source *common.Notification) *Notification {
+	res := &Notification{
+		ID:         gqlCommon.ID(source.ID),
+		Title:      source.Title,
+		Body:       source.Body,
+		CreatedAt:  source.CreatedAt,
+		ReadAt:     source.ReadAt,
+		Deliveries: make([]*NotificationDelivery, len(source.Deliveries)),
+	}
+	res.Type.FromCommon(source.Type)
+
+	switch source.EntityType {
+	case common.NotificationEntityQR:
+		res.Entity = &NotificationEntity{Type: NotificationEntityTypeQR, ID: gqlCommon.ID(source.EntityID)}
+	case common.NotificationEntityTea:
+		res.Entity = &NotificationEntity{Type: NotificationEntityTypeTea, ID: gqlCommon.ID(source.EntityID)}
+	case common.NotificationEntityNone:
+	}
+
+	for i, d := range source.Deliveries {
+		res.Deliveries[i] = &NotificationDelivery{
+			Target:    d.Target,
+			Status:    DeliveryStatusSent,
+			CreatedAt: d.CreatedAt,
+		}
+		res.Deliveries[i].Channel.FromCommon(d.Channel)
+		if d.DeviceID != uuid.Nil {
+			device := gqlCommon.ID(d.DeviceID)
+			res.Deliveries[i].Device = &device
+		}
+		if d.Status == common.DeliveryStatusFailed {
+			res.Deliveries[i].Status = DeliveryStatusFailed
+		}
+		if d.Reason != "" {
+			res.Deliveries[i].Reason = &d.Reason
+		}
+	}
+
+	return res
+}
+
+// FromCommonNotificationPreferences converts common.NotificationPreferences into GraphQL NotificationPreferences.
+func FromCommonNotificationPreferences(source *common.NotificationPreferences) *NotificationPreferences {
+	res := &NotificationPreferences{
+		Timezone:      source.Timezone,
+		DisabledTypes: make([]NotificationType, len(source.Disabled)),
+	}
+	for i, t := range source.Disabled {
+		res.DisabledTypes[i].FromCommon(t)
+	}
+	if source.QuietHours != nil {
+		res.QuietHours = &QuietHours{Start: source.QuietHours.Start.String(), End: source.QuietHours.End.String()}
+	}
+	if source.DeliveryTime != nil {
+		t := source.DeliveryTime.String()
+		res.DeliveryTime = &t
+	}
+	if source.TeaOfTheDayTime != nil {
+		t := source.TeaOfTheDayTime.String()
+		res.TeaOfTheDayTime = &t
+	}
+
+	return res
+}
+
+// ToCommon converts the input into common.NotificationPreferences of the user.
+func (p *NotificationPreferencesInput) ToCommon(userID uuid.UUID) (*common.NotificationPreferences, error) {
+	res := &common.NotificationPreferences{UserID: userID, Timezone: p.Timezone}
+	for _, t := range p.DisabledTypes {
+		if ct, ok := t.ToCommon(); ok {
+			res.Disabled = append(res.Disabled, ct)
+		}
+	}
+
+	if p.QuietHours != nil {
+		start, err := common.ParseTimeOfDay(p.QuietHours.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := common.ParseTimeOfDay(p.QuietHours.End)
+		if err != nil {
+			return nil, err
+		}
+		res.QuietHours = &common.QuietHours{Start: start, End: end}
+	}
+
+	if p.DeliveryTime != nil {
+		t, err := common.ParseTimeOfDay(*p.DeliveryTime)
+		if err != nil {
+			return nil, err
+		}
+		res.DeliveryTime = &t
+	}
+
+	if p.TeaOfTheDayTime != nil {
+		t, err := common.ParseTimeOfDay(*p.TeaOfTheDayTime)
+		if err != nil {
+			return nil, err
+		}
+		res.TeaOfTheDayTime = &t
+	}
+
+	return res, nil
+}
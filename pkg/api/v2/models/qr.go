@@ -0,0 +1,48 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
+
+// QRRecordField names a QRRecord field that can be hidden from callers that do not own the record.
+type QRRecordField string
+
+// QRRecord fields that can be hidden.
+const (
+	QRRecordFieldTea            QRRecordField = "tea"
+	QRRecordFieldBowlingTemp    QRRecordField = "bowlingTemp"
+	QRRecordFieldExpirationDate QRRecordField = "expirationDate"
+	QRRecordFieldOwner          QRRecordField = "owner"
+)
+
+// QRRecord is a QR record as exposed over GraphQL. Fields listed in Hidden resolve to null.
+type QRRecord struct {
+	ID             gqlCommon.ID  `json:"id"`
+	Tea            *Tea          `json:"tea"`
+	BowlingTemp    int           `json:"bowlingTemp"`
+	ExpirationDate time.Time     `json:"expirationDate"`
+	Owner          *gqlCommon.ID `json:"owner"`
+
+	Hidden []QRRecordField `json:"-"`
+}
+
+// Visible reports whether field may be shown to the caller.
+func (r *QRRecord) Visible(field QRRecordField) bool {
+	return !slices.Contains(r.Hidden, field)
+}
+
+// FromCommonQRBatch converts a common.QRBatch into a GraphQL QRBatch.
+func FromCommonQRBatch(source *common.QRBatch) *QRBatch {
+	return &QRBatch{
+		ID:        gqlCommon.ID(source.ID),
+		PrintedBy: source.PrintedBy,
+		CreatedAt: source.CreatedAt,
+		Total:     source.Total,
+		Claimed:   source.Claimed,
+		Blank:     source.Total - source.Claimed,
+	}
+}
@@ -0,0 +1,31 @@
+package model
+
+import "github.com/teaelephant/TeaElephantMemory/common"
+
+var roles = map[common.Role]Role{
+	common.RoleUser:    RoleUser,
+	common.RoleCurator: RoleCurator,
+	common.RoleAdmin:   RoleAdmin,
+}
+
+// FromCommon converts a common.Role to the GraphQL Role; an unknown stored role reads as user, the
+// role that grants nothing extra.
+func (r *Role) FromCommon(data common.Role) {
+	role, ok := roles[data]
+	if !ok {
+		role = RoleUser
+	}
+	*r = role
+}
+
+// ToCommon converts the GraphQL Role to common.Role; unknown values map to a role that fails
+// validation.
+func (r Role) ToCommon() common.Role {
+	for k, v := range roles {
+		if v == r {
+			return k
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,13 @@
+package model
+
+import "github.com/teaelephant/TeaElephantMemory/common"
+
+// FromCommonSession converts an issued or refreshed common.Session to the GraphQL Session.
+func FromCommonSession(s *common.Session) *Session {
+	return &Session{
+		Token:            s.JWT,
+		ExpiredAt:        s.ExpiredAt,
+		RefreshToken:     s.RefreshToken,
+		RefreshExpiredAt: s.RefreshExpiredAt,
+	}
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"github.com/teaelephant/TeaElephantMemory/common"
+	gqlCommon "github.com/teaelephant/TeaElephantMemory/pkg/api/v2/common"
+)
+
+// FromCommonTag converts a common.Tag into a GraphQL Tag. Only the category ID is set;
+// the category resolver loads the rest on demand.
+func FromCommonTag(source *common.Tag) *Tag {
+	return &Tag{
+		ID:       gqlCommon.ID(source.ID),
+		Name:     source.Name,
+		Color:    source.Color,
+		Category: &TagCategory{ID: gqlCommon.ID(source.CategoryID)},
+	}
+}
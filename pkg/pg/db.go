@@ -9,8 +9,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/sirupsen/logrus"
 
 	"github.com/teaelephant/TeaElephantMemory/common"
@@ -54,6 +58,28 @@ func (d *db) GetUsers(ctx context.Context) ([]common.User, error) {
 	return res, nil
 }
 
+func (d *db) UserRole(ctx context.Context, userID uuid.UUID) (common.Role, error) {
+	role, err := d.queries.GetUserRole(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, common.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("get user role: %w", err)
+	}
+	return common.Role(role), nil
+}
+
+func (d *db) SetUserRole(ctx context.Context, userID uuid.UUID, role common.Role) error {
+	affected, err := d.queries.SetUserRole(ctx, userID, int16(role)) //nolint:gosec // small enum
+	if err != nil {
+		return fmt.Errorf("set user role: %w", err)
+	}
+	if affected == 0 {
+		return common.ErrUserNotFound
+	}
+	return nil
+}
+
 // ===== Teas (records) =====
 
 func (d *db) WriteRecord(ctx context.Context, rec *common.TeaData) (*common.Tea, error) {
@@ -113,6 +139,23 @@ func (d *db) ReadAllRecords(ctx context.Context, search string) ([]common.Tea, e
 	return res, nil
 }
 
+func (d *db) ReadRecordsByTag(ctx context.Context, tagID uuid.UUID, search string) ([]common.Tea, error) {
+	teas, err := d.queries.ListTeasByTagTree(ctx, tagID, search)
+	if err != nil {
+		return nil, fmt.Errorf("list teas by tag: %w", err)
+	}
+	res := make([]common.Tea, 0, len(teas))
+	for _, t := range teas {
+		td := common.TeaData{
+			Name:        t.Name,
+			Type:        common.StringToBeverageType(t.Type),
+			Description: nullableString(t.Description),
+		}
+		res = append(res, common.Tea{ID: t.ID, TeaData: &td})
+	}
+	return res, nil
+}
+
 func (d *db) Update(ctx context.Context, id uuid.UUID, rec *common.TeaData) (*common.Tea, error) {
 	tea, err := d.queries.UpdateTea(ctx, pgstore.UpdateTeaParams{
 		ID:          id,
@@ -137,9 +180,68 @@ func (d *db) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// ReadAllTeaTags returns tag assignments of every tea keyed by tea ID.
+func (d *db) ReadAllTeaTags(ctx context.Context) (map[uuid.UUID][]uuid.UUID, error) {
+	pairs, err := d.queries.ListTeaTagPairs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tea tags: %w", err)
+	}
+	res := make(map[uuid.UUID][]uuid.UUID)
+	for _, p := range pairs {
+		res[p.TeaID] = append(res[p.TeaID], p.TagID)
+	}
+	return res, nil
+}
+
+// MergeTeas re-points QR records, tag assignments and consumptions of merge onto keep and
+// deletes the merged teas, all in one transaction.
+func (d *db) MergeTeas(ctx context.Context, keep uuid.UUID, merge []uuid.UUID) (*common.Tea, error) {
+	var kept pgstore.Tea
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		var err error
+		if kept, err = q.GetTea(ctx, keep); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return common.ErrTeaNotFound
+			}
+			return fmt.Errorf("get kept tea: %w", err)
+		}
+		for _, id := range merge {
+			if _, err = q.GetTea(ctx, id); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return common.ErrTeaNotFound
+				}
+				return fmt.Errorf("get merged tea: %w", err)
+			}
+		}
+		if err = q.MoveQRRecordsToTea(ctx, keep, merge); err != nil {
+			return fmt.Errorf("move qr records: %w", err)
+		}
+		if err = q.CopyTeaTagsToTea(ctx, keep, merge); err != nil {
+			return fmt.Errorf("copy tea tags: %w", err)
+		}
+		if err = q.CopyConsumptionsToTea(ctx, keep, merge); err != nil {
+			return fmt.Errorf("copy consumptions: %w", err)
+		}
+		if err = q.DeleteTeas(ctx, merge); err != nil {
+			return fmt.Errorf("delete merged teas: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.Tea{ID: kept.ID, TeaData: &common.TeaData{
+		Name:        kept.Name,
+		Type:        common.StringToBeverageType(kept.Type),
+		Description: nullableString(kept.Description),
+	}}, nil
+}
+
 // ===== QR =====
 
-func (d *db) WriteQR(ctx context.Context, id uuid.UUID, data *common.QR) error {
+// WriteQR creates or rewrites a QR record. data.Owner becomes the owner of a new or unowned
+// record; an owned record may only be rewritten by its owner unless force is set.
+func (d *db) WriteQR(ctx context.Context, id uuid.UUID, data *common.QR, force bool) error {
 	// Clamp to int32 range to avoid overflow (gosec G115)
 	bt := data.BowlingTemp
 	if bt > math.MaxInt32 {
@@ -147,14 +249,19 @@ func (d *db) WriteQR(ctx context.Context, id uuid.UUID, data *common.QR) error {
 	} else if bt < math.MinInt32 {
 		bt = math.MinInt32
 	}
-	if err := d.queries.UpsertQR(ctx, pgstore.QRRecord{
+	n, err := d.queries.UpsertQR(ctx, pgstore.QRRecord{
 		ID:             id,
 		TeaID:          data.Tea,
 		BoilingTemp:    int32(bt), //nolint:gosec // domain: boiling temp is bounded (0..100C), clamped above
 		ExpirationDate: data.ExpirationDate.UTC(),
-	}); err != nil {
+		OwnerID:        nullUUID(data.Owner),
+	}, force)
+	if err != nil {
 		return fmt.Errorf("upsert qr: %w", err)
 	}
+	if n == 0 {
+		return common.ErrQRRecordForbidden
+	}
 	return nil
 }
 
@@ -166,11 +273,87 @@ func (d *db) ReadQR(ctx context.Context, id uuid.UUID) (*common.QR, error) {
 		}
 		return nil, fmt.Errorf("get qr: %w", err)
 	}
-	return &common.QR{
+	res := &common.QR{
 		Tea:            qr.TeaID,
 		BowlingTemp:    int(qr.BoilingTemp),
 		ExpirationDate: qr.ExpirationDate,
-	}, nil
+	}
+	if qr.OwnerID.Valid {
+		res.Owner = &qr.OwnerID.UUID
+	}
+	return res, nil
+}
+
+// SetQROwner changes the owner of a QR record to owner (nil releases it). Unless force is set
+// the record must currently be owned by current.
+func (d *db) SetQROwner(ctx context.Context, id uuid.UUID, owner, current *uuid.UUID, force bool) error {
+	n, err := d.queries.SetQROwner(ctx, id, nullUUID(owner), nullUUID(current), force)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return common.ErrUserNotFound
+		}
+		return fmt.Errorf("set qr owner: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err = d.ReadQR(ctx, id); err != nil {
+		return err
+	}
+	return common.ErrQRRecordForbidden
+}
+
+// CreateQRBatch records a printed batch of QR codes in one transaction.
+func (d *db) CreateQRBatch(ctx context.Context, printedBy string, codes []uuid.UUID) (*common.QRBatch, error) {
+	var batch pgstore.QRBatch
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		var err error
+		if batch, err = q.InsertQRBatch(ctx, uuid.New(), printedBy); err != nil {
+			return fmt.Errorf("insert qr batch: %w", err)
+		}
+		if err = q.InsertQRBatchCodes(ctx, batch.ID, codes); err != nil {
+			return fmt.Errorf("insert qr batch codes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.QRBatch{ID: batch.ID, PrintedBy: batch.PrintedBy, CreatedAt: batch.CreatedAt, Total: len(codes)}, nil
+}
+
+func (d *db) ListQRBatches(ctx context.Context) ([]common.QRBatch, error) {
+	batches, err := d.queries.ListQRBatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list qr batches: %w", err)
+	}
+	res := make([]common.QRBatch, 0, len(batches))
+	for _, b := range batches {
+		res = append(res, toCommonQRBatch(b))
+	}
+	return res, nil
+}
+
+func (d *db) GetQRBatch(ctx context.Context, id uuid.UUID) (*common.QRBatch, error) {
+	batch, err := d.queries.GetQRBatch(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.ErrQRBatchNotFound
+		}
+		return nil, fmt.Errorf("get qr batch: %w", err)
+	}
+	res := toCommonQRBatch(batch)
+	return &res, nil
+}
+
+// IsQRCodeIssued reports whether code belongs to a printed batch or was already written before
+// the batch registry existed.
+func (d *db) IsQRCodeIssued(ctx context.Context, code uuid.UUID) (bool, error) {
+	issued, err := d.queries.IsQRCodeIssued(ctx, code)
+	if err != nil {
+		return false, fmt.Errorf("check qr code: %w", err)
+	}
+	return issued, nil
 }
 
 // ===== Tags & Categories =====
@@ -249,7 +432,7 @@ func (d *db) CreateTag(ctx context.Context, name, color string, categoryID uuid.
 	if err != nil {
 		return nil, fmt.Errorf("insert tag: %w", err)
 	}
-	return &common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}}, nil
+	return toCommonTag(tag), nil
 }
 
 func (d *db) UpdateTag(ctx context.Context, id uuid.UUID, name, color string) (*common.Tag, error) {
@@ -257,7 +440,7 @@ func (d *db) UpdateTag(ctx context.Context, id uuid.UUID, name, color string) (*
 	if err != nil {
 		return nil, fmt.Errorf("update tag: %w", err)
 	}
-	return &common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}}, nil
+	return toCommonTag(tag), nil
 }
 
 func (d *db) ChangeTagCategory(ctx context.Context, id, categoryID uuid.UUID) (*common.Tag, error) {
@@ -265,7 +448,7 @@ func (d *db) ChangeTagCategory(ctx context.Context, id, categoryID uuid.UUID) (*
 	if err != nil {
 		return nil, fmt.Errorf("change tag category: %w", err)
 	}
-	return &common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}}, nil
+	return toCommonTag(tag), nil
 }
 
 func (d *db) DeleteTag(ctx context.Context, id uuid.UUID) error {
@@ -278,9 +461,12 @@ func (d *db) DeleteTag(ctx context.Context, id uuid.UUID) error {
 func (d *db) GetTag(ctx context.Context, id uuid.UUID) (*common.Tag, error) {
 	tag, err := d.queries.GetTag(ctx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.ErrTagNotFound
+		}
 		return nil, fmt.Errorf("get tag: %w", err)
 	}
-	return &common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}}, nil
+	return toCommonTag(tag), nil
 }
 
 func (d *db) ListTags(ctx context.Context, name *string, categoryID *uuid.UUID) ([]common.Tag, error) {
@@ -307,7 +493,7 @@ func (d *db) ListTags(ctx context.Context, name *string, categoryID *uuid.UUID)
 	}
 	res := make([]common.Tag, 0, len(tags))
 	for _, tag := range tags {
-		res = append(res, common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}})
+		res = append(res, *toCommonTag(tag))
 	}
 	return res, nil
 }
@@ -326,6 +512,75 @@ func (d *db) DeleteTagFromTea(ctx context.Context, tea uuid.UUID, tag uuid.UUID)
 	return nil
 }
 
+// SetTeaTags replaces the tag set of a tea with tags and reports what was actually added and removed.
+func (d *db) SetTeaTags(ctx context.Context, tea uuid.UUID, tags []uuid.UUID) (*common.TeaTagsChange, error) {
+	change := &common.TeaTagsChange{TeaID: tea}
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		current, err := q.ListTagsByTea(ctx, tea)
+		if err != nil {
+			return fmt.Errorf("list tags by tea: %w", err)
+		}
+		desired := make(map[uuid.UUID]struct{}, len(tags))
+		for _, id := range tags {
+			desired[id] = struct{}{}
+		}
+		remove := make([]uuid.UUID, 0, len(current))
+		for _, tag := range current {
+			if _, ok := desired[tag.ID]; !ok {
+				remove = append(remove, tag.ID)
+			}
+		}
+		return applyTeaTags(ctx, q, change, tags, remove)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// BulkTag adds and removes tags for every tea in one transaction. Only teas whose tags
+// actually changed are returned.
+func (d *db) BulkTag(ctx context.Context, teas, add, remove []uuid.UUID) ([]common.TeaTagsChange, error) {
+	changes := make([]common.TeaTagsChange, 0, len(teas))
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		for _, tea := range teas {
+			change := common.TeaTagsChange{TeaID: tea}
+			if err := applyTeaTags(ctx, q, &change, add, remove); err != nil {
+				return err
+			}
+			if !change.Empty() {
+				changes = append(changes, change)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func applyTeaTags(ctx context.Context, q *pgstore.Queries, change *common.TeaTagsChange, add, remove []uuid.UUID) error {
+	var err error
+	if len(remove) > 0 {
+		if change.Removed, err = q.DeleteTeaTags(ctx, change.TeaID, remove); err != nil {
+			return fmt.Errorf("delete tea tags: %w", err)
+		}
+	}
+	if len(add) > 0 {
+		if change.Added, err = q.InsertTeaTags(ctx, change.TeaID, add); err != nil {
+			switch {
+			case isForeignKeyViolationOf(err, teaTagsTeaFK):
+				return common.ErrTeaNotFound
+			case isForeignKeyViolation(err):
+				return common.ErrTagNotFound
+			}
+			return fmt.Errorf("insert tea tags: %w", err)
+		}
+	}
+	return nil
+}
+
 func (d *db) ListByTea(ctx context.Context, id uuid.UUID) ([]common.Tag, error) {
 	tags, err := d.queries.ListTagsByTea(ctx, id)
 	if err != nil {
@@ -333,11 +588,173 @@ func (d *db) ListByTea(ctx context.Context, id uuid.UUID) ([]common.Tag, error)
 	}
 	res := make([]common.Tag, 0, len(tags))
 	for _, tag := range tags {
-		res = append(res, common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}})
+		res = append(res, *toCommonTag(tag))
+	}
+	return res, nil
+}
+
+func (d *db) SetTagParent(ctx context.Context, id uuid.UUID, parentID *uuid.UUID) (*common.Tag, error) {
+	var tag pgstore.Tag
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		parent := uuid.NullUUID{}
+		if parentID != nil {
+			descendants, err := q.ListTagDescendantIDs(ctx, id)
+			if err != nil {
+				return fmt.Errorf("list tag descendants: %w", err)
+			}
+			for _, descendant := range descendants {
+				if descendant == *parentID {
+					return common.ErrTagCycle
+				}
+			}
+			parent = uuid.NullUUID{UUID: *parentID, Valid: true}
+		}
+		var err error
+		tag, err = q.SetTagParent(ctx, id, parent)
+		if err != nil {
+			// No rows: unknown tag; foreign key violation: unknown parent.
+			if errors.Is(err, sql.ErrNoRows) || isForeignKeyViolation(err) {
+				return common.ErrTagNotFound
+			}
+			return fmt.Errorf("set tag parent: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toCommonTag(tag), nil
+}
+
+func (d *db) ListTagChildren(ctx context.Context, id uuid.UUID) ([]common.Tag, error) {
+	tags, err := d.queries.ListTagChildren(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list tag children: %w", err)
+	}
+	res := make([]common.Tag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, *toCommonTag(tag))
 	}
 	return res, nil
 }
 
+// AddTagSynonym adds name as a synonym of tag id. It fails with common.ErrTagSynonymExists if any
+// tag, in any category, already has the synonym.
+func (d *db) AddTagSynonym(ctx context.Context, id uuid.UUID, name string) error {
+	inserted, err := d.queries.InsertTagSynonym(ctx, id, name)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return common.ErrTagNotFound
+		}
+		return fmt.Errorf("insert tag synonym: %w", err)
+	}
+	if inserted == 0 {
+		return common.ErrTagSynonymExists
+	}
+	return nil
+}
+
+func (d *db) DeleteTagSynonym(ctx context.Context, id uuid.UUID, name string) error {
+	if err := d.queries.DeleteTagSynonym(ctx, id, name); err != nil {
+		return fmt.Errorf("delete tag synonym: %w", err)
+	}
+	return nil
+}
+
+func (d *db) ListTagSynonyms(ctx context.Context, id uuid.UUID) ([]string, error) {
+	names, err := d.queries.ListTagSynonyms(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list tag synonyms: %w", err)
+	}
+	return names, nil
+}
+
+func (d *db) ResolveTagSynonym(ctx context.Context, name string, categoryID uuid.UUID) (*common.Tag, error) {
+	tag, err := d.queries.ResolveTagSynonym(ctx, name, categoryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.ErrTagNotFound
+		}
+		return nil, fmt.Errorf("resolve tag synonym: %w", err)
+	}
+	return toCommonTag(tag), nil
+}
+
+// MergeTags moves tea assignments, children and synonyms of source onto target, keeps the
+// source name as a synonym of target and deletes source, all in one transaction. Target may be a
+// child of source, in which case it takes the place of source under its parent, but no deeper
+// descendant: the child between them would become its own grandparent. It fails with
+// common.ErrTagSynonymExists if another tag already has the source name as a synonym.
+func (d *db) MergeTags(ctx context.Context, source, target uuid.UUID) (*common.Tag, error) {
+	var merged pgstore.Tag
+	err := d.inTx(ctx, func(q *pgstore.Queries) error {
+		src, err := q.GetTag(ctx, source)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return common.ErrTagNotFound
+			}
+			return fmt.Errorf("get source tag: %w", err)
+		}
+		dst, err := q.GetTag(ctx, target)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return common.ErrTagNotFound
+			}
+			return fmt.Errorf("get target tag: %w", err)
+		}
+		if dst.ParentID.Valid && dst.ParentID.UUID == source {
+			if _, err = q.SetTagParent(ctx, target, src.ParentID); err != nil {
+				return fmt.Errorf("set tag parent: %w", err)
+			}
+		} else {
+			descendants, err := q.ListTagDescendantIDs(ctx, source)
+			if err != nil {
+				return fmt.Errorf("list tag descendants: %w", err)
+			}
+			for _, descendant := range descendants {
+				if descendant == target {
+					return common.ErrTagCycle
+				}
+			}
+		}
+		if err = q.MoveTeaTags(ctx, source, target); err != nil {
+			return fmt.Errorf("move tea tags: %w", err)
+		}
+		if err = q.MoveTagChildren(ctx, source, target); err != nil {
+			return fmt.Errorf("move tag children: %w", err)
+		}
+		if err = q.MoveTagSynonyms(ctx, source, target); err != nil {
+			return fmt.Errorf("move tag synonyms: %w", err)
+		}
+		if err = q.DeleteTag(ctx, source); err != nil {
+			return fmt.Errorf("delete source tag: %w", err)
+		}
+		inserted, err := q.InsertTagSynonym(ctx, target, src.Name)
+		if err != nil {
+			return fmt.Errorf("insert tag synonym: %w", err)
+		}
+		if inserted == 0 {
+			// Fine if the name was already a synonym of source and has just been moved.
+			owner, err := q.ResolveTagSynonym(ctx, src.Name, dst.CategoryID)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && owner.ID != target) {
+				return common.ErrTagSynonymExists
+			}
+			if err != nil {
+				return fmt.Errorf("resolve tag synonym: %w", err)
+			}
+		}
+		merged, err = q.GetTag(ctx, target)
+		if err != nil {
+			return fmt.Errorf("get merged tag: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toCommonTag(merged), nil
+}
+
 // ===== Collections =====
 
 func (d *db) CreateCollection(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
@@ -426,6 +843,9 @@ func (d *db) CollectionRecords(ctx context.Context, id uuid.UUID) ([]*common.Col
 			BowlingTemp:    int(row.BoilingTemp),
 			ExpirationDate: row.ExpirationDate,
 		}
+		if row.OwnerID.Valid {
+			rec.Owner = &row.OwnerID.UUID
+		}
 		res = append(res, rec)
 	}
 	return res, nil
@@ -433,59 +853,978 @@ func (d *db) CollectionRecords(ctx context.Context, id uuid.UUID) ([]*common.Col
 
 // ===== Notifications & Devices =====
 
-func (d *db) AddDeviceForUser(ctx context.Context, userID uuid.UUID, deviceID uuid.UUID) error {
-	if err := d.queries.InsertDevice(ctx, deviceID, userID, deviceID.String()); err != nil {
-		return fmt.Errorf("insert device: %w", err)
+// BindDevice signs the device in with the user at boundAt. It fails with common.ErrDeviceInUse while
+// another user has a live session on the device.
+func (d *db) BindDevice(ctx context.Context, userID, deviceID uuid.UUID, info common.DeviceInfo, boundAt time.Time) error {
+	affected, err := d.queries.BindDevice(ctx, pgstore.BindDeviceParams{
+		ID:         deviceID,
+		UserID:     userID,
+		Platform:   info.Platform,
+		Model:      info.Model,
+		AppVersion: info.AppVersion,
+		BoundAt:    boundAt,
+	})
+	if err != nil {
+		return fmt.Errorf("bind device: %w", err)
+	}
+	if affected == 0 {
+		return common.ErrDeviceInUse
 	}
 	return nil
 }
 
-func (d *db) CreateOrUpdateDeviceToken(ctx context.Context, deviceID uuid.UUID, deviceToken string) error {
-	affected, err := d.queries.UpdateDeviceToken(ctx, deviceID, deviceToken)
+// DeviceSessionValid reports whether a session issued to the device at issuedAt is still valid,
+// i.e. the device was neither revoked nor bound to another user since, and marks the device seen.
+func (d *db) DeviceSessionValid(ctx context.Context, userID, deviceID uuid.UUID, issuedAt time.Time) (bool, error) {
+	valid, err := d.queries.SeeDevice(ctx, deviceID, userID, issuedAt)
+	if err != nil {
+		return false, fmt.Errorf("see device: %w", err)
+	}
+	return valid, nil
+}
+
+func (d *db) CreateOrUpdateDeviceToken(
+	ctx context.Context, userID, deviceID uuid.UUID, deviceToken string, env common.DeviceEnvironment, info common.DeviceInfo,
+) error {
+	affected, err := d.queries.UpdateDeviceToken(ctx, pgstore.UpdateDeviceTokenParams{
+		ID:          deviceID,
+		UserID:      userID,
+		Token:       deviceToken,
+		Environment: int16(env), //nolint:gosec // small enum
+		Platform:    info.Platform,
+		Model:       info.Model,
+		AppVersion:  info.AppVersion,
+	})
 	if err != nil {
 		return fmt.Errorf("update device token: %w", err)
 	}
 	if affected == 0 {
-		d.log.WithField("device_id", deviceID).Warn("device token update skipped: no matching device row")
+		return common.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (d *db) Devices(ctx context.Context, userID uuid.UUID) ([]common.Device, error) {
+	rows, err := d.queries.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list devices: %w", err)
+	}
+	res := make([]common.Device, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, toCommonDevice(row))
+	}
+	return res, nil
+}
+
+func (d *db) RenameDevice(ctx context.Context, userID, deviceID uuid.UUID, name string) (*common.Device, error) {
+	row, err := d.queries.RenameDevice(ctx, deviceID, userID, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rename device: %w", err)
+	}
+	dev := toCommonDevice(row)
+	return &dev, nil
+}
+
+func (d *db) DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	affected, err := d.queries.DeleteDevice(ctx, deviceID, userID)
+	if err != nil {
+		return fmt.Errorf("delete device: %w", err)
+	}
+	if affected == 0 {
+		return common.ErrDeviceNotFound
 	}
 	return nil
 }
 
-func (d *db) Notifications(ctx context.Context, userID uuid.UUID) ([]common.Notification, error) {
-	notifs, err := d.queries.ListNotifications(ctx, userID)
+func toCommonDevice(row pgstore.DeviceDetails) common.Device {
+	res := common.Device{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Token:       row.Token,
+		Environment: common.DeviceEnvironment(row.Environment),
+		DeviceInfo:  common.DeviceInfo{Platform: row.Platform, Model: row.Model, AppVersion: row.AppVersion},
+		Name:        row.Name,
+		BoundAt:     row.BoundAt,
+	}
+	if row.LastSeenAt.Valid {
+		res.LastSeenAt = &row.LastSeenAt.Time
+	}
+	return res
+}
+
+// CreateNotification stores n and updates it with the stored id and creation time. If the user
+// already has a notification with n.SourceKey, n is replaced by that one, with its deliveries, and
+// created is false.
+func (d *db) CreateNotification(ctx context.Context, n *common.Notification) (created bool, err error) {
+	arg := pgstore.InsertNotificationParams{
+		ID:     uuid.New(),
+		UserID: n.UserID,
+		Type:   int16(n.Type), //nolint:gosec // small enum
+		Title:  n.Title,
+		Body:   n.Body,
+	}
+	if n.DeliverAt != nil {
+		arg.DeliverAt = sql.NullTime{Time: *n.DeliverAt, Valid: true}
+	}
+	if n.EntityType != common.NotificationEntityNone {
+		arg.EntityType = sql.NullInt16{Int16: int16(n.EntityType), Valid: true} //nolint:gosec // small enum
+		arg.EntityID = uuid.NullUUID{UUID: n.EntityID, Valid: true}
+	}
+	if n.SourceKey != "" {
+		arg.SourceKey = sql.NullString{String: n.SourceKey, Valid: true}
+	}
+	row, err := d.queries.InsertNotification(ctx, arg)
+	if errors.Is(err, sql.ErrNoRows) && arg.SourceKey.Valid {
+		existing, err := d.notificationBySource(ctx, n.UserID, n.SourceKey)
+		if err != nil {
+			return false, err
+		}
+		*n = *existing
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert notification: %w", err)
+	}
+	*n = toCommonNotification(row)
+	n.SourceKey = arg.SourceKey.String
+	return true, nil
+}
+
+func (d *db) notificationBySource(ctx context.Context, userID uuid.UUID, key string) (*common.Notification, error) {
+	row, err := d.queries.GetNotificationBySource(ctx, userID, key)
+	if err != nil {
+		return nil, fmt.Errorf("get notification by source: %w", err)
+	}
+	res, err := d.withDeliveries(ctx, []pgstore.Notification{row})
+	if err != nil {
+		return nil, err
+	}
+	res[0].SourceKey = key
+	return &res[0], nil
+}
+
+func (d *db) AddNotificationDeliveries(ctx context.Context, id uuid.UUID, deliveries []common.NotificationDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	arg := pgstore.InsertNotificationDeliveriesParams{
+		NotificationID: id,
+		Channels:       make([]int16, len(deliveries)),
+		Targets:        make([]string, len(deliveries)),
+		DeviceIDs:      make([]uuid.UUID, len(deliveries)),
+		Statuses:       make([]int16, len(deliveries)),
+		Reasons:        make([]string, len(deliveries)),
+	}
+	for i, del := range deliveries {
+		arg.Channels[i] = int16(del.Channel) //nolint:gosec // small enum
+		arg.Targets[i] = del.Target
+		arg.DeviceIDs[i] = del.DeviceID
+		arg.Statuses[i] = int16(del.Status) //nolint:gosec // small enum
+		arg.Reasons[i] = del.Reason
+	}
+	if err := d.queries.InsertNotificationDeliveries(ctx, arg); err != nil {
+		return fmt.Errorf("insert notification deliveries: %w", err)
+	}
+	return nil
+}
+
+func (d *db) Notifications(ctx context.Context, userID uuid.UUID, limit int, after *uuid.UUID) ([]common.Notification, error) {
+	rows, err := d.queries.ListNotifications(ctx, pgstore.ListNotificationsParams{
+		UserID: userID,
+		After:  nullUUID(after),
+		Limit:  int32(limit), //nolint:gosec // page size is clamped by the manager
+	})
 	if err != nil {
 		return nil, fmt.Errorf("list notifications: %w", err)
 	}
-	res := make([]common.Notification, 0, len(notifs))
-	for _, n := range notifs {
-		res = append(res, common.Notification{UserID: userID, Type: common.NotificationType(n.Type)})
+	return d.withDeliveries(ctx, rows)
+}
+
+func (d *db) UnreadNotificationList(ctx context.Context, userID uuid.UUID, limit int) ([]common.Notification, error) {
+	rows, err := d.queries.ListUnreadNotifications(ctx, userID, int32(limit)) //nolint:gosec // limit is clamped by the manager
+	if err != nil {
+		return nil, fmt.Errorf("list unread notifications: %w", err)
+	}
+	return d.withDeliveries(ctx, rows)
+}
+
+// withDeliveries converts the rows and loads their deliveries.
+func (d *db) withDeliveries(ctx context.Context, rows []pgstore.Notification) ([]common.Notification, error) {
+	res := make([]common.Notification, 0, len(rows))
+	ids := make([]uuid.UUID, 0, len(rows))
+	index := make(map[uuid.UUID]int, len(rows))
+	for i, row := range rows {
+		res = append(res, toCommonNotification(row))
+		ids = append(ids, row.ID)
+		index[row.ID] = i
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	deliveries, err := d.queries.ListNotificationDeliveries(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list notification deliveries: %w", err)
+	}
+	for _, del := range deliveries {
+		n := &res[index[del.NotificationID]]
+		n.Deliveries = append(n.Deliveries, common.NotificationDelivery{
+			Channel:   common.NotificationChannel(del.Channel),
+			Target:    del.Target,
+			DeviceID:  del.DeviceID.UUID,
+			Status:    common.DeliveryStatus(del.Status),
+			Reason:    del.Reason,
+			CreatedAt: del.CreatedAt,
+		})
 	}
 	return res, nil
 }
 
-func (d *db) MapUserIDToDeviceID(ctx context.Context, userID uuid.UUID) ([]string, error) {
-	tokens, err := d.queries.ListDeviceTokens(ctx, userID)
+func (d *db) UnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	count, err := d.queries.CountUnreadNotifications(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("list device tokens: %w", err)
+		return 0, fmt.Errorf("count unread notifications: %w", err)
 	}
-	return tokens, nil
+	return int(count), nil
 }
 
-// ===== Version =====
+func (d *db) MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error) {
+	row, err := d.queries.MarkNotificationRead(ctx, id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mark notification read: %w", err)
+	}
+	n := toCommonNotification(row)
+	return &n, nil
+}
 
-func (d *db) GetVersion(_ context.Context) (uint32, error) {
-	return 0, nil
+func (d *db) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int, error) {
+	affected, err := d.queries.MarkAllNotificationsRead(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("mark all notifications read: %w", err)
+	}
+	return int(affected), nil
 }
 
-func (d *db) WriteVersion(_ context.Context, _ uint32) error {
+func (d *db) UserDevices(ctx context.Context, userID uuid.UUID) ([]common.Device, error) {
+	rows, err := d.queries.ListUserDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user devices: %w", err)
+	}
+	res := make([]common.Device, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, common.Device{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			Token:       row.Token,
+			Environment: common.DeviceEnvironment(row.Environment),
+		})
+	}
+	return res, nil
+}
+
+// RemoveDeviceToken forgets a token APNs reported as dead, unless the device registered another one.
+func (d *db) RemoveDeviceToken(ctx context.Context, deviceID uuid.UUID, token string) error {
+	if err := d.queries.ClearDeviceToken(ctx, deviceID, token); err != nil {
+		return fmt.Errorf("clear device token: %w", err)
+	}
 	return nil
 }
 
-// ===== Consumption Store =====
+// SetDeviceEnvironment records the APNs environment that accepted the token, unless the device
+// registered another token meanwhile.
+func (d *db) SetDeviceEnvironment(ctx context.Context, deviceID uuid.UUID, token string, env common.DeviceEnvironment) error {
+	if err := d.queries.SetDeviceEnvironment(ctx, deviceID, token, int16(env)); err != nil { //nolint:gosec // small enum
+		return fmt.Errorf("set device environment: %w", err)
+	}
+	return nil
+}
 
-func nullableString(src sql.NullString) string {
-	if src.Valid {
-		return src.String
+func toCommonNotification(row pgstore.Notification) common.Notification {
+	n := common.Notification{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Type:      common.NotificationType(row.Type),
+		Title:     row.Title,
+		Body:      row.Body,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.EntityType.Valid && row.EntityID.Valid {
+		n.EntityType = common.NotificationEntityType(row.EntityType.Int16)
+		n.EntityID = row.EntityID.UUID
+	}
+	if row.ReadAt.Valid {
+		n.ReadAt = &row.ReadAt.Time
+	}
+	if row.DeliverAt.Valid {
+		n.DeliverAt = &row.DeliverAt.Time
+	}
+	return n
+}
+
+func (d *db) ClaimDueNotifications(ctx context.Context, limit int, claim time.Duration) ([]common.DueNotification, error) {
+	//nolint:gosec // small batch size and claim of minutes
+	rows, err := d.queries.ClaimDueNotifications(ctx, int32(limit), int32(claim/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("claim due notifications: %w", err)
+	}
+	res := make([]common.DueNotification, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, common.DueNotification{
+			Notification: toCommonNotification(row.Notification),
+			Attempt:      int(row.PushAttempts),
+		})
+	}
+	return res, nil
+}
+
+func (d *db) CompleteDeferredNotification(ctx context.Context, id uuid.UUID) error {
+	if err := d.queries.CompleteDeferredNotification(ctx, id); err != nil {
+		return fmt.Errorf("complete deferred notification: %w", err)
+	}
+	return nil
+}
+
+// Notification returns the user's notification with the id.
+func (d *db) Notification(ctx context.Context, userID, id uuid.UUID) (*common.Notification, error) {
+	row, err := d.queries.GetNotification(ctx, id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get notification: %w", err)
+	}
+	res, err := d.withDeliveries(ctx, []pgstore.Notification{row})
+	if err != nil {
+		return nil, err
+	}
+	return &res[0], nil
+}
+
+// notificationsChannel is the LISTEN/NOTIFY channel announcing notifications that reached an
+// inbox. The payload is "<user id>/<notification id>".
+const notificationsChannel = "notifications"
+
+// PublishNotification announces n to the listeners of every replica.
+func (d *db) PublishNotification(ctx context.Context, n *common.Notification) error {
+	if err := d.queries.NotifyNotification(ctx, notificationsChannel, n.UserID.String()+"/"+n.ID.String()); err != nil {
+		return fmt.Errorf("notify notification: %w", err)
+	}
+	return nil
+}
+
+// ListenNotifications calls fn for every notification published by any replica until ctx is done
+// or the connection fails; it always returns an error. It keeps one pooled connection for itself.
+func (d *db) ListenNotifications(ctx context.Context, fn func(ctx context.Context, userID, id uuid.UUID)) error {
+	conn, err := d.pg.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listen: unexpected driver connection %T", driverConn)
+		}
+		pgxConn := stdConn.Conn()
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+notificationsChannel); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		// The connection goes back to the pool; a cancelled wait closes it anyway.
+		defer func() {
+			unlistenCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+			defer cancel()
+			_, _ = pgxConn.Exec(unlistenCtx, "UNLISTEN "+notificationsChannel)
+		}()
+
+		for {
+			msg, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return fmt.Errorf("wait for notification: %w", err)
+			}
+			userID, id, err := parseNotificationPayload(msg.Payload)
+			if err != nil {
+				d.log.WithError(err).Warn("skip notification announcement")
+				continue
+			}
+			fn(ctx, userID, id)
+		}
+	})
+}
+
+func parseNotificationPayload(payload string) (uuid.UUID, uuid.UUID, error) {
+	user, id, ok := strings.Cut(payload, "/")
+	if !ok {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("malformed payload %q", payload)
+	}
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("parse user id: %w", err)
+	}
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("parse notification id: %w", err)
+	}
+	return userID, notificationID, nil
+}
+
+func (d *db) NotificationPreferences(ctx context.Context, userID uuid.UUID) (*common.NotificationPreferences, error) {
+	p := common.DefaultNotificationPreferences(userID)
+	row, err := d.queries.GetNotificationPreferences(ctx, userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("get notification preferences: %w", err)
+	default:
+		p = toCommonNotificationPreferences(row)
+	}
+	disabled, err := d.queries.ListDisabledNotificationTypes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list disabled notification types: %w", err)
+	}
+	for _, t := range disabled {
+		p.Disabled = append(p.Disabled, common.NotificationType(t))
+	}
+	return p, nil
+}
+
+// TeaOfTheDaySubscribers lists the preferences of users who opted in to the Tea of the Day push,
+// without their disabled types.
+func (d *db) TeaOfTheDaySubscribers(ctx context.Context) ([]*common.NotificationPreferences, error) {
+	rows, err := d.queries.ListTeaOfTheDaySubscribers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tea of the day subscribers: %w", err)
+	}
+	res := make([]*common.NotificationPreferences, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, toCommonNotificationPreferences(row))
+	}
+	return res, nil
+}
+
+func toCommonNotificationPreferences(row pgstore.NotificationPreference) *common.NotificationPreferences {
+	p := &common.NotificationPreferences{UserID: row.UserID, Timezone: row.Timezone}
+	if row.QuietStart.Valid && row.QuietEnd.Valid {
+		p.QuietHours = &common.QuietHours{
+			Start: common.TimeOfDay(row.QuietStart.Int16),
+			End:   common.TimeOfDay(row.QuietEnd.Int16),
+		}
+	}
+	if row.DeliveryTime.Valid {
+		t := common.TimeOfDay(row.DeliveryTime.Int16)
+		p.DeliveryTime = &t
+	}
+	if row.TeaOfTheDayTime.Valid {
+		t := common.TimeOfDay(row.TeaOfTheDayTime.Int16)
+		p.TeaOfTheDayTime = &t
+	}
+	return p
+}
+
+func (d *db) SaveNotificationPreferences(ctx context.Context, p *common.NotificationPreferences) error {
+	arg := pgstore.NotificationPreference{UserID: p.UserID, Timezone: p.Timezone}
+	if p.QuietHours != nil {
+		arg.QuietStart = sql.NullInt16{Int16: int16(p.QuietHours.Start), Valid: true} //nolint:gosec // validated time of day
+		arg.QuietEnd = sql.NullInt16{Int16: int16(p.QuietHours.End), Valid: true}     //nolint:gosec // validated time of day
+	}
+	if p.DeliveryTime != nil {
+		arg.DeliveryTime = sql.NullInt16{Int16: int16(*p.DeliveryTime), Valid: true} //nolint:gosec // validated time of day
+	}
+	if p.TeaOfTheDayTime != nil {
+		arg.TeaOfTheDayTime = sql.NullInt16{Int16: int16(*p.TeaOfTheDayTime), Valid: true} //nolint:gosec // validated time of day
+	}
+	disabled := make([]int16, len(p.Disabled))
+	for i, t := range p.Disabled {
+		disabled[i] = int16(t) //nolint:gosec // small enum
+	}
+	return d.inTx(ctx, func(q *pgstore.Queries) error {
+		if err := q.UpsertNotificationPreferences(ctx, arg); err != nil {
+			return fmt.Errorf("upsert notification preferences: %w", err)
+		}
+		if err := q.DeleteDisabledNotificationTypes(ctx, p.UserID); err != nil {
+			return fmt.Errorf("delete disabled notification types: %w", err)
+		}
+		if len(disabled) == 0 {
+			return nil
+		}
+		if err := q.InsertDisabledNotificationTypes(ctx, p.UserID, disabled); err != nil {
+			return fmt.Errorf("insert disabled notification types: %w", err)
+		}
+		return nil
+	})
+}
+
+func (d *db) NotificationRoutes(ctx context.Context, userID uuid.UUID) ([]common.NotificationRoute, error) {
+	rows, err := d.queries.ListNotificationRoutes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list notification routes: %w", err)
+	}
+	res := make([]common.NotificationRoute, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, common.NotificationRoute{
+			Channel: common.NotificationChannel(row.Channel), Target: row.Target, Verified: row.Verified,
+		})
+	}
+	return res, nil
+}
+
+func (d *db) SaveNotificationRoutes(ctx context.Context, userID uuid.UUID, routes []common.NotificationRoute) error {
+	arg := pgstore.InsertNotificationRoutesParams{
+		UserID:   userID,
+		Channels: make([]int16, len(routes)),
+		Targets:  make([]string, len(routes)),
+	}
+	for i, r := range routes {
+		arg.Channels[i] = int16(r.Channel) //nolint:gosec // small enum
+		arg.Targets[i] = r.Target
+	}
+	return d.inTx(ctx, func(q *pgstore.Queries) error {
+		if err := q.DeleteNotificationRoutes(ctx, userID); err != nil {
+			return fmt.Errorf("delete notification routes: %w", err)
+		}
+		if len(routes) == 0 {
+			return nil
+		}
+		if err := q.InsertNotificationRoutes(ctx, arg); err != nil {
+			return fmt.Errorf("insert notification routes: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetEmailVerificationCode stores the hash of a new code for the user's email address. It reports
+// false, storing nothing, if the address is verified already or a code was sent within resend.
+func (d *db) SetEmailVerificationCode(
+	ctx context.Context, userID uuid.UUID, address string, codeHash []byte, resend time.Duration,
+) (bool, error) {
+	n, err := d.queries.SetEmailVerificationCode(ctx, pgstore.SetEmailVerificationCodeParams{
+		UserID:        userID,
+		Address:       address,
+		CodeHash:      codeHash,
+		ResendSeconds: int32(resend / time.Second), //nolint:gosec // resend interval of minutes
+	})
+	if err != nil {
+		return false, fmt.Errorf("set email verification code: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ClaimEmailVerificationAttempt counts a guess at the pending code of the user's email address and
+// returns the code to check it against.
+func (d *db) ClaimEmailVerificationAttempt(ctx context.Context, userID uuid.UUID, address string) (*common.EmailVerification, error) {
+	row, err := d.queries.ClaimEmailVerificationAttempt(ctx, userID, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.ErrInvalidVerificationCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim email verification attempt: %w", err)
+	}
+	return &common.EmailVerification{CodeHash: row.CodeHash, SentAt: row.CodeSentAt, Attempts: int(row.Attempts)}, nil
+}
+
+func (d *db) MarkEmailVerified(ctx context.Context, userID uuid.UUID, address string) error {
+	if err := d.queries.MarkEmailVerified(ctx, userID, address); err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+	return nil
+}
+
+// TeaOfTheDay returns the id of the record picked for the user on day, uuid.Nil if none was picked.
+func (d *db) TeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time) (uuid.UUID, error) {
+	id, err := d.queries.GetTeaOfTheDay(ctx, userID, day)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get tea of the day: %w", err)
+	}
+	return id, nil
+}
+
+// SaveTeaOfTheDay stores the pick unless another one was stored first; it returns the stored record id.
+func (d *db) SaveTeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time, recordID uuid.UUID) (uuid.UUID, error) {
+	id, err := d.queries.InsertTeaOfTheDay(ctx, userID, day, recordID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("insert tea of the day: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimTeaOfTheDayNotification marks the day's pick as pushed and reports whether this call did so.
+func (d *db) ClaimTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) (bool, error) {
+	affected, err := d.queries.ClaimTeaOfTheDayNotification(ctx, userID, day)
+	if err != nil {
+		return false, fmt.Errorf("claim tea of the day notification: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// ReleaseTeaOfTheDayNotification gives back a claim whose push failed.
+func (d *db) ReleaseTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) error {
+	if err := d.queries.ReleaseTeaOfTheDayNotification(ctx, userID, day); err != nil {
+		return fmt.Errorf("release tea of the day notification: %w", err)
+	}
+	return nil
+}
+
+func (d *db) ExpirationAlerts(ctx context.Context, userID uuid.UUID) ([]common.ExpirationAlert, error) {
+	rows, err := d.queries.ListExpirationAlerts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list expiration alerts: %w", err)
+	}
+	res := make([]common.ExpirationAlert, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, common.ExpirationAlert{
+			RecordID:       row.QRID,
+			LeadDays:       int(row.LeadDays),
+			ExpirationDate: row.ExpirationDate,
+		})
+	}
+	return res, nil
+}
+
+func (d *db) MarkExpirationAlertsSent(ctx context.Context, userID uuid.UUID, alerts []common.ExpirationAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	arg := pgstore.InsertExpirationAlertsParams{
+		UserID:          userID,
+		QRIDs:           make([]uuid.UUID, len(alerts)),
+		LeadDays:        make([]int32, len(alerts)),
+		ExpirationDates: make([]time.Time, len(alerts)),
+	}
+	for i, a := range alerts {
+		arg.QRIDs[i] = a.RecordID
+		arg.LeadDays[i] = int32(a.LeadDays) //nolint:gosec // lead times are a few hundred days at most
+		arg.ExpirationDates[i] = a.ExpirationDate
+	}
+	if err := d.queries.InsertExpirationAlerts(ctx, arg); err != nil {
+		return fmt.Errorf("insert expiration alerts: %w", err)
+	}
+	return nil
+}
+
+// PruneExpirationAlerts deletes the alerts for expiration dates that records no longer have.
+func (d *db) PruneExpirationAlerts(ctx context.Context) (int64, error) {
+	n, err := d.queries.DeleteStaleExpirationAlerts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale expiration alerts: %w", err)
+	}
+	return n, nil
+}
+
+// ===== Sessions =====
+
+func (d *db) CreateSession(
+	ctx context.Context, id, userID, deviceID uuid.UUID, refreshTokenHash []byte, createdAt, expiresAt time.Time,
+) error {
+	if err := d.queries.InsertSession(ctx, pgstore.InsertSessionParams{
+		ID:               id,
+		UserID:           userID,
+		DeviceID:         deviceID,
+		RefreshTokenHash: refreshTokenHash,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        createdAt,
+	}); err != nil {
+		return fmt.Errorf("insert session: %w", err)
+	}
+	return nil
+}
+
+// RotateSession replaces the refresh token of the live session of the device holding refreshTokenHash.
+func (d *db) RotateSession(
+	ctx context.Context, deviceID uuid.UUID, refreshTokenHash, newRefreshTokenHash []byte, expiresAt time.Time,
+) (*common.Session, error) {
+	row, err := d.queries.RotateSession(ctx, pgstore.RotateSessionParams{
+		RefreshTokenHash:    refreshTokenHash,
+		DeviceID:            deviceID,
+		NewRefreshTokenHash: newRefreshTokenHash,
+		ExpiresAt:           expiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("rotate session: %w", err)
+	}
+	return &common.Session{
+		ID:               row.ID,
+		User:             &common.User{ID: row.UserID},
+		DeviceID:         row.DeviceID,
+		RefreshExpiredAt: expiresAt,
+	}, nil
+}
+
+// RevokeReusedSession revokes the session whose refresh token was replaced by one with the hash,
+// reporting whether there was one.
+func (d *db) RevokeReusedSession(ctx context.Context, refreshTokenHash []byte, grace time.Duration) (bool, error) {
+	affected, err := d.queries.RevokeSessionByPreviousToken(ctx, refreshTokenHash, int32(grace/time.Second)) //nolint:gosec // seconds of a short grace window
+	if err != nil {
+		return false, fmt.Errorf("revoke session by previous token: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (d *db) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if _, err := d.queries.RevokeSession(ctx, sessionID, userID); err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
+func (d *db) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	affected, err := d.queries.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke user sessions: %w", err)
+	}
+	return int(affected), nil
+}
+
+// SessionValid reports whether the session is neither revoked nor was its device revoked or bound
+// to another user since, and marks the device seen.
+func (d *db) SessionValid(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	valid, err := d.queries.SeeSession(ctx, sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("see session: %w", err)
+	}
+	return valid, nil
+}
+
+// PruneSessions deletes the sessions that expired or were revoked more than keep ago.
+func (d *db) PruneSessions(ctx context.Context, keep time.Duration) (int64, error) {
+	n, err := d.queries.DeleteEndedSessions(ctx, int32(keep/time.Second)) //nolint:gosec // a day or so
+	if err != nil {
+		return 0, fmt.Errorf("delete ended sessions: %w", err)
+	}
+	return n, nil
+}
+
+// ===== Admin token revocations =====
+
+// RevokeAdminToken revokes the admin token until expiresAt, reporting false if it already was.
+func (d *db) RevokeAdminToken(ctx context.Context, jti, reason string, expiresAt time.Time) (bool, error) {
+	affected, err := d.queries.RevokeAdminToken(ctx, jti, reason, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("revoke admin token: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (d *db) AdminTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := d.queries.AdminTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, fmt.Errorf("admin token revoked: %w", err)
+	}
+	return revoked, nil
+}
+
+func (d *db) AdminTokenRevocations(ctx context.Context) ([]common.AdminTokenRevocation, error) {
+	rows, err := d.queries.ListAdminTokenRevocations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list admin token revocations: %w", err)
+	}
+	res := make([]common.AdminTokenRevocation, len(rows))
+	for i, r := range rows {
+		res[i] = common.AdminTokenRevocation{
+			JTI:       r.Jti,
+			Reason:    r.Reason,
+			RevokedAt: r.RevokedAt,
+			ExpiresAt: r.ExpiresAt,
+		}
+	}
+	return res, nil
+}
+
+// PruneAdminTokenRevocations deletes revocations of tokens that have expired since.
+func (d *db) PruneAdminTokenRevocations(ctx context.Context) (int64, error) {
+	n, err := d.queries.DeleteExpiredAdminTokenRevocations(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired admin token revocations: %w", err)
+	}
+	return n, nil
+}
+
+// ===== Job runs =====
+
+func (d *db) QueueJobRun(ctx context.Context, job string) (*common.JobRun, error) {
+	row, err := d.queries.InsertJobRun(ctx, pgstore.InsertJobRunParams{
+		ID:      uuid.New(),
+		Job:     job,
+		Trigger: int16(common.JobTriggerManual),
+		Status:  int16(common.JobRunStatusPending),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("queue job run: %w", err)
+	}
+	return toCommonJobRun(row), nil
+}
+
+func (d *db) StartJobRun(ctx context.Context, job string) (*common.JobRun, error) {
+	row, err := d.queries.InsertJobRun(ctx, pgstore.InsertJobRunParams{
+		ID:        uuid.New(),
+		Job:       job,
+		Trigger:   int16(common.JobTriggerSchedule),
+		Status:    int16(common.JobRunStatusRunning),
+		StartedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start job run: %w", err)
+	}
+	return toCommonJobRun(row), nil
+}
+
+// StartPendingJobRun starts the oldest queued run of the job; it returns nil if none is queued.
+func (d *db) StartPendingJobRun(ctx context.Context, job string) (*common.JobRun, error) {
+	row, err := d.queries.StartPendingJobRun(ctx, job)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil //nolint:nilnil // no pending run is not an error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start pending job run: %w", err)
+	}
+	return toCommonJobRun(row), nil
+}
+
+func (d *db) FinishJobRun(ctx context.Context, id uuid.UUID, status common.JobRunStatus, message string) error {
+	arg := pgstore.FinishJobRunParams{ID: id, Status: int16(status), Error: message} //nolint:gosec // small enum
+	if err := d.queries.FinishJobRun(ctx, arg); err != nil {
+		return fmt.Errorf("finish job run: %w", err)
+	}
+	return nil
+}
+
+func (d *db) AbandonRunningJobRuns(ctx context.Context) (int64, error) {
+	affected, err := d.queries.AbandonRunningJobRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("abandon running job runs: %w", err)
+	}
+	return affected, nil
+}
+
+func (d *db) LastJobStarts(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := d.queries.ListLastJobStarts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list last job starts: %w", err)
+	}
+	res := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		res[row.Job] = row.StartedAt
+	}
+	return res, nil
+}
+
+func (d *db) JobRuns(ctx context.Context, job string, limit int) ([]common.JobRun, error) {
+	rows, err := d.queries.ListJobRuns(ctx, job, int32(limit)) //nolint:gosec // limit is validated by the caller
+	if err != nil {
+		return nil, fmt.Errorf("list job runs: %w", err)
+	}
+	res := make([]common.JobRun, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, *toCommonJobRun(row))
+	}
+	return res, nil
+}
+
+func toCommonJobRun(row pgstore.JobRun) *common.JobRun {
+	res := &common.JobRun{
+		ID:        row.ID,
+		Job:       row.Job,
+		Trigger:   common.JobTrigger(row.Trigger),
+		Status:    common.JobRunStatus(row.Status),
+		Error:     row.Error,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.StartedAt.Valid {
+		res.StartedAt = &row.StartedAt.Time
+	}
+	if row.FinishedAt.Valid {
+		res.FinishedAt = &row.FinishedAt.Time
+	}
+	return res
+}
+
+// ===== Version =====
+
+func (d *db) GetVersion(_ context.Context) (uint32, error) {
+	return 0, nil
+}
+
+func (d *db) WriteVersion(_ context.Context, _ uint32) error {
+	return nil
+}
+
+// ===== Consumption Store =====
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func nullableString(src sql.NullString) string {
+	if src.Valid {
+		return src.String
 	}
 	return ""
 }
+
+func toCommonTag(tag pgstore.Tag) *common.Tag {
+	res := &common.Tag{ID: tag.ID, TagData: &common.TagData{Name: tag.Name, Color: tag.Color, CategoryID: tag.CategoryID}}
+	if tag.ParentID.Valid {
+		parent := tag.ParentID.UUID
+		res.ParentID = &parent
+	}
+	return res
+}
+
+// foreignKeyViolation is the SQLSTATE of a reference to a row that does not exist.
+const foreignKeyViolation = "23503"
+
+// teaTagsTeaFK is the constraint from tea_tags to teas, as named by Postgres.
+const teaTagsTeaFK = "tea_tags_tea_id_fkey"
+
+// isForeignKeyViolation reports whether err is Postgres rejecting a reference to a missing row.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// isForeignKeyViolationOf reports whether err is a foreign key violation of the named constraint.
+func isForeignKeyViolationOf(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation && pgErr.ConstraintName == constraint
+}
+
+// inTx runs fn inside a database transaction, committing on success and rolling back on error.
+func (d *db) inTx(ctx context.Context, fn func(q *pgstore.Queries) error) error {
+	tx, err := d.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	if err = fn(d.queries.WithTx(tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			d.log.WithError(rbErr).Warn("rollback failed")
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func toCommonQRBatch(batch pgstore.QRBatchStats) common.QRBatch {
+	return common.QRBatch{
+		ID:        batch.ID,
+		PrintedBy: batch.PrintedBy,
+		CreatedAt: batch.CreatedAt,
+		Total:     int(batch.Total),
+		Claimed:   int(batch.Claimed),
+	}
+}
@@ -0,0 +1,74 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestBindDevice(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	alice, bob := newTestUser(t, d), newTestUser(t, d)
+	device := uuid.New()
+	session := newTestSession(t, d, alice, device)
+	issuedAt := time.Now().UTC()
+
+	// Signing in again keeps the binding and its sessions.
+	require.NoError(t, d.BindDevice(ctx, alice, device, common.DeviceInfo{Model: "iPhone"}, time.Now().UTC()))
+	valid, err := d.SessionValid(ctx, alice, session)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Bob cannot take the device while Alice is signed in on it.
+	require.ErrorIs(t, d.BindDevice(ctx, bob, device, common.DeviceInfo{}, time.Now().UTC()), common.ErrDeviceInUse)
+	devices, err := d.Devices(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "iPhone", devices[0].Model)
+
+	// Once Alice signed out, Bob can, which ends Alice's access tokens on the device.
+	require.NoError(t, d.RevokeSession(ctx, alice, session))
+	newTestSession(t, d, bob, device)
+	devices, err = d.Devices(ctx, alice)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+	valid, err = d.DeviceSessionValid(ctx, alice, device, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = d.DeviceSessionValid(ctx, bob, device, time.Now().UTC())
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestDeviceScoping(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	alice, bob := newTestUser(t, d), newTestUser(t, d)
+	device := uuid.New()
+	newTestSession(t, d, alice, device)
+
+	// Only the owner can register a token on the device or revoke it.
+	err := d.CreateOrUpdateDeviceToken(ctx, bob, device, "bob", common.DeviceEnvironmentSandbox, common.DeviceInfo{})
+	require.ErrorIs(t, err, common.ErrDeviceNotFound)
+	require.ErrorIs(t, d.DeleteDevice(ctx, bob, device), common.ErrDeviceNotFound)
+	require.NoError(t, d.CreateOrUpdateDeviceToken(ctx, alice, device, "alice", common.DeviceEnvironmentSandbox, common.DeviceInfo{}))
+
+	devices, err := d.UserDevices(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "alice", devices[0].Token)
+
+	// A revoked device is free for anyone to sign in on.
+	require.NoError(t, d.DeleteDevice(ctx, alice, device))
+	newTestSession(t, d, bob, device)
+	devices, err = d.UserDevices(ctx, bob)
+	require.NoError(t, err)
+	assert.Empty(t, devices, "bob's device has no token until the app registers one")
+}
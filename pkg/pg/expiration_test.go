@@ -0,0 +1,47 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestPruneExpirationAlerts(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user := newTestUser(t, d)
+	id, tea := uuid.New(), newTestTea(t, d)
+	old := time.Now().UTC().AddDate(0, 0, 7).Truncate(time.Second)
+	require.NoError(t, d.WriteQR(ctx, id, &common.QR{Tea: tea, ExpirationDate: old, Owner: &user}, false))
+	require.NoError(t, d.MarkExpirationAlertsSent(ctx, user, []common.ExpirationAlert{
+		{RecordID: id, LeadDays: 30, ExpirationDate: old},
+		{RecordID: id, LeadDays: 7, ExpirationDate: old},
+	}))
+
+	// Alerts for the current date are kept.
+	_, err := d.PruneExpirationAlerts(ctx)
+	require.NoError(t, err)
+	alerts, err := d.ExpirationAlerts(ctx, user)
+	require.NoError(t, err)
+	assert.Len(t, alerts, 2)
+
+	moved := old.AddDate(0, 1, 0)
+	require.NoError(t, d.WriteQR(ctx, id, &common.QR{Tea: tea, ExpirationDate: moved, Owner: &user}, false))
+	require.NoError(t, d.MarkExpirationAlertsSent(ctx, user, []common.ExpirationAlert{
+		{RecordID: id, LeadDays: 30, ExpirationDate: moved},
+	}))
+
+	pruned, err := d.PruneExpirationAlerts(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, pruned, int64(2))
+	alerts, err = d.ExpirationAlerts(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.True(t, moved.Equal(alerts[0].ExpirationDate))
+}
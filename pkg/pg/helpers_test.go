@@ -0,0 +1,99 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+// newTestDB connects to the database in PG_TEST_DSN and applies db/schema.sql to it. The tests
+// create their own rows with random names, so the database can be shared; they are skipped when
+// PG_TEST_DSN is not set.
+func newTestDB(t *testing.T) *db {
+	t.Helper()
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	psql, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = psql.Close() })
+
+	schema, err := os.ReadFile("../../db/schema.sql")
+	require.NoError(t, err)
+	_, err = psql.ExecContext(context.Background(), string(schema))
+	require.NoError(t, err)
+
+	return NewDB(psql, logrus.NewEntry(logrus.New()))
+}
+
+// uniqueName returns prefix with a random suffix, for names that are unique in the database.
+func uniqueName(prefix string) string {
+	return prefix + "-" + uuid.NewString()
+}
+
+func newTestTea(t *testing.T, d *db) uuid.UUID {
+	t.Helper()
+	tea, err := d.WriteRecord(context.Background(), &common.TeaData{Name: uniqueName("tea"), Type: common.TeaBeverageType})
+	require.NoError(t, err)
+	return tea.ID
+}
+
+func newTestUser(t *testing.T, d *db) uuid.UUID {
+	t.Helper()
+	id, err := d.GetOrCreateUser(context.Background(), uniqueName("user"))
+	require.NoError(t, err)
+	return id
+}
+
+// newTestTags creates a category with a tag per name and returns the tag ids in order. Names get a
+// random suffix, as merges keep them as synonyms, which are unique across categories.
+func newTestTags(t *testing.T, d *db, names ...string) (uuid.UUID, []uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	category, err := d.CreateTagCategory(ctx, uniqueName("category"))
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, len(names))
+	for i, name := range names {
+		tag, err := d.CreateTag(ctx, uniqueName(name), "#000000", category.ID)
+		require.NoError(t, err)
+		ids[i] = tag.ID
+	}
+	return category.ID, ids
+}
+
+func setParent(t *testing.T, d *db, id, parent uuid.UUID) {
+	t.Helper()
+	_, err := d.SetTagParent(context.Background(), id, &parent)
+	require.NoError(t, err)
+}
+
+// newTestSession binds the device to the user and starts a session on it.
+func newTestSession(t *testing.T, d *db, userID, deviceID uuid.UUID) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	require.NoError(t, d.BindDevice(ctx, userID, deviceID, common.DeviceInfo{}, now))
+	id := uuid.New()
+	require.NoError(t, d.CreateSession(ctx, id, userID, deviceID, []byte(uuid.NewString()), now, now.Add(time.Hour)))
+	return id
+}
+
+func newTestNotification(t *testing.T, d *db, userID uuid.UUID) *common.Notification {
+	t.Helper()
+	n := &common.Notification{UserID: userID, Title: "title", Body: "body"}
+	created, err := d.CreateNotification(context.Background(), n)
+	require.NoError(t, err)
+	require.True(t, created)
+	return n
+}
@@ -0,0 +1,188 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestNotificationsCursor(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user, other := newTestUser(t, d), newTestUser(t, d)
+	first := newTestNotification(t, d, user)
+	second := newTestNotification(t, d, user)
+	foreign := newTestNotification(t, d, other)
+
+	page, err := d.Notifications(ctx, user, 10, &second.ID)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, first.ID, page[0].ID)
+
+	// Another user's notification is not a valid cursor.
+	page, err = d.Notifications(ctx, user, 10, &foreign.ID)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestClaimDueNotifications(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user := newTestUser(t, d)
+	past := time.Now().Add(-time.Minute)
+	n := &common.Notification{UserID: user, Title: "title", DeliverAt: &past}
+	_, err := d.CreateNotification(ctx, n)
+	require.NoError(t, err)
+
+	claim := func(claim time.Duration) []int {
+		due, err := d.ClaimDueNotifications(ctx, 1000, claim)
+		require.NoError(t, err)
+		var attempts []int
+		for _, c := range due {
+			if c.ID == n.ID {
+				attempts = append(attempts, c.Attempt)
+			}
+		}
+		return attempts
+	}
+
+	// A claim that ran out, as after a failed push, is claimed again.
+	assert.Equal(t, []int{1}, claim(0))
+	assert.Equal(t, []int{2}, claim(time.Hour))
+	assert.Empty(t, claim(time.Hour), "claimed notifications are skipped")
+
+	// Due notifications are in the inbox while their push is pending.
+	list, err := d.Notifications(ctx, user, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	require.NoError(t, d.CompleteDeferredNotification(ctx, n.ID))
+	assert.Empty(t, claim(time.Hour), "completed notifications are not claimed")
+}
+
+func TestListenNotifications(t *testing.T) {
+	d := newTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	user := newTestUser(t, d)
+	n := newTestNotification(t, d, user)
+
+	type announcement struct{ userID, id uuid.UUID }
+	received := make(chan announcement, 10)
+	listening := make(chan error, 1)
+	go func() {
+		listening <- d.ListenNotifications(ctx, func(_ context.Context, userID, id uuid.UUID) {
+			received <- announcement{userID, id}
+		})
+	}()
+
+	// LISTEN runs asynchronously; publish until the listener picks it up.
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for got := false; !got; {
+		select {
+		case a := <-received:
+			if a.id == n.ID {
+				assert.Equal(t, user, a.userID)
+				got = true
+			}
+		case <-ticker.C:
+			require.NoError(t, d.PublishNotification(ctx, n))
+		case <-timeout:
+			t.Fatal("notification was not received")
+		}
+	}
+
+	loaded, err := d.Notification(ctx, user, n.ID)
+	require.NoError(t, err)
+	assert.Equal(t, n.Title, loaded.Title)
+	_, err = d.Notification(ctx, newTestUser(t, d), n.ID)
+	require.ErrorIs(t, err, common.ErrNotificationNotFound)
+
+	cancel()
+	require.Error(t, <-listening)
+}
+
+func TestCreateNotification_SourceKey(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user, other := newTestUser(t, d), newTestUser(t, d)
+	key := uniqueName("tea-of-the-day")
+
+	first := &common.Notification{UserID: user, Title: "first", SourceKey: key}
+	created, err := d.CreateNotification(ctx, first)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NoError(t, d.AddNotificationDeliveries(ctx, first.ID, []common.NotificationDelivery{
+		{Channel: common.NotificationChannelWebhook, Target: "https://example.com/hook"},
+	}))
+
+	again := &common.Notification{UserID: user, Title: "again", SourceKey: key}
+	created, err = d.CreateNotification(ctx, again)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, "first", again.Title)
+	assert.Len(t, again.Deliveries, 1)
+
+	// The key is per user.
+	created, err = d.CreateNotification(ctx, &common.Notification{UserID: other, SourceKey: key})
+	require.NoError(t, err)
+	assert.True(t, created)
+}
+
+func TestEmailVerification(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user := newTestUser(t, d)
+	address := uniqueName("me") + "@example.com"
+	require.NoError(t, d.SaveNotificationRoutes(ctx, user, []common.NotificationRoute{
+		{Channel: common.NotificationChannelInbox},
+		{Channel: common.NotificationChannelEmail, Target: address},
+	}))
+	routes, err := d.NotificationRoutes(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.False(t, routes[0].Verified, "email")
+	assert.True(t, routes[1].Verified, "inbox")
+
+	_, err = d.ClaimEmailVerificationAttempt(ctx, user, address)
+	require.ErrorIs(t, err, common.ErrInvalidVerificationCode, "no code sent")
+
+	stored, err := d.SetEmailVerificationCode(ctx, user, address, []byte("first"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, stored)
+	stored, err = d.SetEmailVerificationCode(ctx, user, address, []byte("second"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, stored, "within the resend interval")
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		v, err := d.ClaimEmailVerificationAttempt(ctx, user, address)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), v.CodeHash)
+		assert.Equal(t, attempt, v.Attempts)
+	}
+
+	stored, err = d.SetEmailVerificationCode(ctx, user, address, []byte("third"), 0)
+	require.NoError(t, err)
+	assert.True(t, stored)
+	v, err := d.ClaimEmailVerificationAttempt(ctx, user, address)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("third"), v.CodeHash)
+	assert.Equal(t, 1, v.Attempts, "a new code resets the attempts")
+
+	require.NoError(t, d.MarkEmailVerified(ctx, user, address))
+	routes, err = d.NotificationRoutes(ctx, user)
+	require.NoError(t, err)
+	assert.True(t, routes[0].Verified)
+	stored, err = d.SetEmailVerificationCode(ctx, user, address, []byte("fourth"), 0)
+	require.NoError(t, err)
+	assert.False(t, stored, "already verified")
+}
@@ -0,0 +1,33 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestSetQROwner(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	owner, other := newTestUser(t, d), newTestUser(t, d)
+	id := uuid.New()
+	require.NoError(t, d.WriteQR(ctx, id, &common.QR{
+		Tea: newTestTea(t, d), BowlingTemp: 90, ExpirationDate: time.Now().AddDate(1, 0, 0), Owner: &owner,
+	}, false))
+
+	unknown := uuid.New()
+	require.ErrorIs(t, d.SetQROwner(ctx, id, &unknown, &owner, false), common.ErrUserNotFound)
+	require.ErrorIs(t, d.SetQROwner(ctx, id, &owner, &other, false), common.ErrQRRecordForbidden)
+	require.ErrorIs(t, d.SetQROwner(ctx, uuid.New(), &other, &owner, false), common.ErrQRRecordNotExist)
+
+	require.NoError(t, d.SetQROwner(ctx, id, &other, &owner, false))
+	qr, err := d.ReadQR(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, &other, qr.Owner)
+}
@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestRevokeReusedSession(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user, device := newTestUser(t, d), uuid.New()
+	now := time.Now().UTC()
+	require.NoError(t, d.BindDevice(ctx, user, device, common.DeviceInfo{}, now))
+	id := uuid.New()
+	first, second := []byte(uuid.NewString()), []byte(uuid.NewString())
+	require.NoError(t, d.CreateSession(ctx, id, user, device, first, now, now.Add(time.Hour)))
+
+	_, err := d.RotateSession(ctx, uuid.New(), first, second, now.Add(time.Hour))
+	require.ErrorIs(t, err, common.ErrInvalidRefreshToken, "refresh on another device")
+	session, err := d.RotateSession(ctx, device, first, second, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, id, session.ID)
+	_, err = d.RotateSession(ctx, device, first, []byte(uuid.NewString()), now.Add(time.Hour))
+	require.ErrorIs(t, err, common.ErrInvalidRefreshToken)
+
+	// Within the grace window the replay is rejected, but the session lives on.
+	reused, err := d.RevokeReusedSession(ctx, first, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, reused)
+	valid, err := d.SessionValid(ctx, user, id)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	reused, err = d.RevokeReusedSession(ctx, first, 0)
+	require.NoError(t, err)
+	assert.True(t, reused)
+	valid, err = d.SessionValid(ctx, user, id)
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestPruneSessions(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	user := newTestUser(t, d)
+	live := newTestSession(t, d, user, uuid.New())
+	revoked := newTestSession(t, d, user, uuid.New())
+	require.NoError(t, d.RevokeSession(ctx, user, revoked))
+	sessions := func() []uuid.UUID {
+		rows, err := d.pg.QueryContext(ctx, "SELECT id FROM sessions WHERE user_id = $1 ORDER BY created_at", user)
+		require.NoError(t, err)
+		defer rows.Close()
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	// A session revoked within keep is kept.
+	_, err := d.PruneSessions(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{live, revoked}, sessions())
+
+	pruned, err := d.PruneSessions(ctx, 0)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, pruned, int64(1))
+	assert.Equal(t, []uuid.UUID{live}, sessions())
+}
@@ -0,0 +1,218 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+)
+
+func TestTagHierarchy(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	_, ids := newTestTags(t, d, "oolong", "roasted", "dark roasted")
+	oolong, roasted, dark := ids[0], ids[1], ids[2]
+	setParent(t, d, roasted, oolong)
+	setParent(t, d, dark, roasted)
+
+	children, err := d.ListTagChildren(ctx, oolong)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, roasted, children[0].ID)
+
+	_, err = d.SetTagParent(ctx, oolong, &dark)
+	require.ErrorIs(t, err, common.ErrTagCycle)
+	_, err = d.SetTagParent(ctx, oolong, &oolong)
+	require.ErrorIs(t, err, common.ErrTagCycle)
+
+	unknown := uuid.New()
+	_, err = d.SetTagParent(ctx, roasted, &unknown)
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+	_, err = d.SetTagParent(ctx, unknown, &oolong)
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+
+	tea := newTestTea(t, d)
+	require.NoError(t, d.AddTagToTea(ctx, tea, dark))
+	teas, err := d.ReadRecordsByTag(ctx, oolong, "")
+	require.NoError(t, err)
+	require.Len(t, teas, 1)
+	assert.Equal(t, tea, teas[0].ID)
+
+	tag, err := d.SetTagParent(ctx, dark, nil)
+	require.NoError(t, err)
+	assert.Nil(t, tag.ParentID)
+	teas, err = d.ReadRecordsByTag(ctx, oolong, "")
+	require.NoError(t, err)
+	assert.Empty(t, teas)
+}
+
+func TestTagSynonyms(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	category, ids := newTestTags(t, d, "smoky")
+	synonym := uniqueName("smoked")
+
+	require.NoError(t, d.AddTagSynonym(ctx, ids[0], synonym))
+	require.ErrorIs(t, d.AddTagSynonym(ctx, uuid.New(), uniqueName("orphan")), common.ErrTagNotFound)
+	_, others := newTestTags(t, d, "smoky")
+	require.ErrorIs(t, d.AddTagSynonym(ctx, others[0], strings.ToUpper(synonym)), common.ErrTagSynonymExists)
+
+	tag, err := d.ResolveTagSynonym(ctx, synonym, category)
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], tag.ID)
+	_, err = d.ResolveTagSynonym(ctx, synonym, uuid.New())
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+
+	names, err := d.ListTagSynonyms(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{synonym}, names)
+
+	require.NoError(t, d.DeleteTagSynonym(ctx, ids[0], synonym))
+	_, err = d.ResolveTagSynonym(ctx, synonym, category)
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+}
+
+func TestMergeTags(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("moves teas, children and synonyms", func(t *testing.T) {
+		d := newTestDB(t)
+		category, ids := newTestTags(t, d, "smoked", "smoky", "lapsang")
+		source, target, child := ids[0], ids[1], ids[2]
+		src, err := d.GetTag(ctx, source)
+		require.NoError(t, err)
+		setParent(t, d, child, source)
+		synonym := uniqueName("campfire")
+		require.NoError(t, d.AddTagSynonym(ctx, source, synonym))
+		tea := newTestTea(t, d)
+		require.NoError(t, d.AddTagToTea(ctx, tea, source))
+
+		merged, err := d.MergeTags(ctx, source, target)
+		require.NoError(t, err)
+		assert.Equal(t, target, merged.ID)
+
+		_, err = d.GetTag(ctx, source)
+		require.ErrorIs(t, err, common.ErrTagNotFound)
+		tags, err := d.ListByTea(ctx, tea)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, target, tags[0].ID)
+		children, err := d.ListTagChildren(ctx, target)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		assert.Equal(t, child, children[0].ID)
+		for _, name := range []string{src.Name, synonym} {
+			tag, err := d.ResolveTagSynonym(ctx, name, category)
+			require.NoError(t, err)
+			assert.Equal(t, target, tag.ID)
+		}
+	})
+
+	t.Run("into a child", func(t *testing.T) {
+		d := newTestDB(t)
+		_, ids := newTestTags(t, d, "tea", "green", "sencha", "gyokuro")
+		root, source, target, sibling := ids[0], ids[1], ids[2], ids[3]
+		setParent(t, d, source, root)
+		setParent(t, d, target, source)
+		setParent(t, d, sibling, source)
+
+		merged, err := d.MergeTags(ctx, source, target)
+		require.NoError(t, err)
+		assert.Equal(t, &root, merged.ParentID)
+		children, err := d.ListTagChildren(ctx, target)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		assert.Equal(t, sibling, children[0].ID)
+	})
+
+	t.Run("name taken in another category", func(t *testing.T) {
+		d := newTestDB(t)
+		_, ids := newTestTags(t, d, "roasted", "toasty")
+		source, target := ids[0], ids[1]
+		src, err := d.GetTag(ctx, source)
+		require.NoError(t, err)
+		_, others := newTestTags(t, d, "roast")
+		require.NoError(t, d.AddTagSynonym(ctx, others[0], src.Name))
+
+		_, err = d.MergeTags(ctx, source, target)
+		require.ErrorIs(t, err, common.ErrTagSynonymExists)
+		_, err = d.GetTag(ctx, source)
+		require.NoError(t, err)
+	})
+
+	t.Run("into a grandchild", func(t *testing.T) {
+		d := newTestDB(t)
+		_, ids := newTestTags(t, d, "black", "assam", "second flush")
+		source, child, target := ids[0], ids[1], ids[2]
+		setParent(t, d, child, source)
+		setParent(t, d, target, child)
+
+		_, err := d.MergeTags(ctx, source, target)
+		require.ErrorIs(t, err, common.ErrTagCycle)
+
+		_, err = d.GetTag(ctx, source)
+		require.NoError(t, err)
+		tag, err := d.GetTag(ctx, child)
+		require.NoError(t, err)
+		assert.Equal(t, &source, tag.ParentID)
+	})
+}
+
+func TestSetTeaTags(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	_, ids := newTestTags(t, d, "floral", "fruity", "nutty")
+	tea := newTestTea(t, d)
+	require.NoError(t, d.AddTagToTea(ctx, tea, ids[0]))
+	require.NoError(t, d.AddTagToTea(ctx, tea, ids[1]))
+
+	change, err := d.SetTeaTags(ctx, tea, []uuid.UUID{ids[1], ids[2]})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[2]}, change.Added)
+	assert.Equal(t, []uuid.UUID{ids[0]}, change.Removed)
+
+	change, err = d.SetTeaTags(ctx, tea, []uuid.UUID{ids[1], ids[2]})
+	require.NoError(t, err)
+	assert.True(t, change.Empty())
+
+	_, err = d.SetTeaTags(ctx, tea, []uuid.UUID{ids[1], uuid.New()})
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+	_, err = d.SetTeaTags(ctx, uuid.New(), []uuid.UUID{ids[1]})
+	require.ErrorIs(t, err, common.ErrTeaNotFound)
+
+	tags, err := d.ListByTea(ctx, tea)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.ElementsMatch(t, []uuid.UUID{ids[1], ids[2]}, []uuid.UUID{tags[0].ID, tags[1].ID})
+}
+
+func TestBulkTag(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	_, ids := newTestTags(t, d, "spring", "autumn")
+	spring, autumn := ids[0], ids[1]
+	tagged, untagged := newTestTea(t, d), newTestTea(t, d)
+	require.NoError(t, d.AddTagToTea(ctx, tagged, spring))
+	require.NoError(t, d.AddTagToTea(ctx, untagged, autumn))
+
+	changes, err := d.BulkTag(ctx, []uuid.UUID{tagged, untagged}, []uuid.UUID{spring}, []uuid.UUID{autumn})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, untagged, changes[0].TeaID)
+	assert.Equal(t, []uuid.UUID{spring}, changes[0].Added)
+	assert.Equal(t, []uuid.UUID{autumn}, changes[0].Removed)
+
+	_, err = d.BulkTag(ctx, []uuid.UUID{tagged}, []uuid.UUID{uuid.New()}, nil)
+	require.ErrorIs(t, err, common.ErrTagNotFound)
+	_, err = d.BulkTag(ctx, []uuid.UUID{uuid.New()}, []uuid.UUID{spring}, nil)
+	require.ErrorIs(t, err, common.ErrTeaNotFound)
+	tags, err := d.ListByTea(ctx, tagged)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, spring, tags[0].ID)
+}
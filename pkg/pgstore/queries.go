@@ -10,16 +10,27 @@ import (
 	"github.com/google/uuid"
 )
 
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
 type Queries struct {
-	db *sql.DB
+	db DBTX
 }
 
-func New(db *sql.DB) *Queries {
+func New(db DBTX) *Queries {
 	return &Queries{db: db}
 }
 
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return &Queries{db: tx}
+}
+
 func (q *Queries) DB() *sql.DB {
-	return q.db
+	db, _ := q.db.(*sql.DB)
+	return db
 }
 
 // Users
@@ -74,6 +85,27 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const getUserRole = `-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1`
+
+func (q *Queries) GetUserRole(ctx context.Context, id uuid.UUID) (int16, error) {
+	row := q.db.QueryRowContext(ctx, getUserRole, id)
+	var role int16
+	err := row.Scan(&role)
+	return role, err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users SET role = $2 WHERE id = $1`
+
+func (q *Queries) SetUserRole(ctx context.Context, id uuid.UUID, role int16) (int64, error) {
+	res, err := q.db.ExecContext(ctx, setUserRole, id, role)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // Teas
 
 type InsertTeaParams struct {
@@ -197,6 +229,110 @@ func (q *Queries) SearchTeasByPrefix(ctx context.Context, name string, limit int
 	return items, nil
 }
 
+const listTeasByTagTree = `-- name: ListTeasByTagTree :many
+WITH RECURSIVE descendants AS (
+  SELECT tags.id FROM tags WHERE tags.id = $1
+  UNION
+  SELECT t.id FROM tags t JOIN descendants d ON t.parent_id = d.id
+)
+SELECT DISTINCT te.id, te.name, te.type, te.description, te.created_at
+FROM teas te
+JOIN tea_tags tt ON tt.tea_id = te.id
+JOIN descendants d ON d.id = tt.tag_id
+WHERE lower(te.name) LIKE lower($2) || '%'
+ORDER BY te.name ASC`
+
+func (q *Queries) ListTeasByTagTree(ctx context.Context, tagID uuid.UUID, prefix string) ([]Tea, error) {
+	rows, err := q.db.QueryContext(ctx, listTeasByTagTree, tagID, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tea
+	for rows.Next() {
+		var i Tea
+		if err := rows.Scan(&i.ID, &i.Name, &i.Type, &i.Description, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type TeaTag struct {
+	TeaID uuid.UUID
+	TagID uuid.UUID
+}
+
+const listTeaTagPairs = `-- name: ListTeaTagPairs :many
+SELECT tea_id, tag_id
+FROM tea_tags`
+
+func (q *Queries) ListTeaTagPairs(ctx context.Context) ([]TeaTag, error) {
+	rows, err := q.db.QueryContext(ctx, listTeaTagPairs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TeaTag
+	for rows.Next() {
+		var i TeaTag
+		if err := rows.Scan(&i.TeaID, &i.TagID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveQRRecordsToTea = `-- name: MoveQRRecordsToTea :exec
+UPDATE qr_records
+SET tea_id = $1
+WHERE tea_id = ANY($2::uuid[])`
+
+func (q *Queries) MoveQRRecordsToTea(ctx context.Context, teaID uuid.UUID, from []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, moveQRRecordsToTea, teaID, from)
+	return err
+}
+
+const copyTeaTagsToTea = `-- name: CopyTeaTagsToTea :exec
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT $1, tag_id
+FROM tea_tags
+WHERE tea_id = ANY($2::uuid[])
+ON CONFLICT (tea_id, tag_id) DO NOTHING`
+
+func (q *Queries) CopyTeaTagsToTea(ctx context.Context, teaID uuid.UUID, from []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, copyTeaTagsToTea, teaID, from)
+	return err
+}
+
+const copyConsumptionsToTea = `-- name: CopyConsumptionsToTea :exec
+INSERT INTO consumptions (user_id, ts, tea_id)
+SELECT user_id, ts, $1
+FROM consumptions
+WHERE tea_id = ANY($2::uuid[])
+ON CONFLICT (user_id, ts, tea_id) DO NOTHING`
+
+func (q *Queries) CopyConsumptionsToTea(ctx context.Context, teaID uuid.UUID, from []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, copyConsumptionsToTea, teaID, from)
+	return err
+}
+
+const deleteTeas = `-- name: DeleteTeas :exec
+DELETE FROM teas WHERE id = ANY($1::uuid[])`
+
+func (q *Queries) DeleteTeas(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteTeas, ids)
+	return err
+}
+
 // Tag categories and tags
 
 type TagCategory struct {
@@ -311,7 +447,7 @@ func (q *Queries) SearchTagCategories(ctx context.Context, prefix string) ([]Tag
 }
 
 const listTagsByCategory = `-- name: ListTagsByCategory :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE category_id = $1
 ORDER BY name ASC`
@@ -321,6 +457,7 @@ type Tag struct {
 	Name       string
 	Color      string
 	CategoryID uuid.UUID
+	ParentID   uuid.NullUUID
 }
 
 func (q *Queries) ListTagsByCategory(ctx context.Context, categoryID uuid.UUID) ([]Tag, error) {
@@ -332,7 +469,7 @@ func (q *Queries) ListTagsByCategory(ctx context.Context, categoryID uuid.UUID)
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -362,12 +499,12 @@ type InsertTagParams struct {
 const insertTag = `-- name: InsertTag :one
 INSERT INTO tags (id, name, color, category_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, name, color, category_id`
+RETURNING id, name, color, category_id, parent_id`
 
 func (q *Queries) InsertTag(ctx context.Context, arg InsertTagParams) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, insertTag, arg.ID, arg.Name, arg.Color, arg.CategoryID)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID)
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
 	return i, err
 }
 
@@ -382,12 +519,12 @@ UPDATE tags
 SET name = $2,
     color = $3
 WHERE id = $1
-RETURNING id, name, color, category_id`
+RETURNING id, name, color, category_id, parent_id`
 
 func (q *Queries) UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, updateTag, arg.ID, arg.Name, arg.Color)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID)
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
 	return i, err
 }
 
@@ -400,12 +537,12 @@ const changeTagCategory = `-- name: ChangeTagCategory :one
 UPDATE tags
 SET category_id = $2
 WHERE id = $1
-RETURNING id, name, color, category_id`
+RETURNING id, name, color, category_id, parent_id`
 
 func (q *Queries) ChangeTagCategory(ctx context.Context, arg ChangeTagCategoryParams) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, changeTagCategory, arg.ID, arg.CategoryID)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID)
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
 	return i, err
 }
 
@@ -418,19 +555,19 @@ func (q *Queries) DeleteTag(ctx context.Context, id uuid.UUID) error {
 }
 
 const getTag = `-- name: GetTag :one
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE id = $1`
 
 func (q *Queries) GetTag(ctx context.Context, id uuid.UUID) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, getTag, id)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID)
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
 	return i, err
 }
 
 const listTags = `-- name: ListTags :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 ORDER BY name ASC`
 
@@ -443,7 +580,7 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -455,9 +592,10 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 }
 
 const listTagsByName = `-- name: ListTagsByName :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE lower(name) LIKE lower($1) || '%'
+   OR id IN (SELECT tag_id FROM tag_synonyms WHERE lower(tag_synonyms.name) LIKE lower($1) || '%')
 ORDER BY name ASC`
 
 func (q *Queries) ListTagsByName(ctx context.Context, prefix string) ([]Tag, error) {
@@ -469,7 +607,7 @@ func (q *Queries) ListTagsByName(ctx context.Context, prefix string) ([]Tag, err
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -481,7 +619,7 @@ func (q *Queries) ListTagsByName(ctx context.Context, prefix string) ([]Tag, err
 }
 
 const listTagsByCategoryFilter = `-- name: ListTagsByCategoryFilter :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
 WHERE category_id = $1
 ORDER BY name ASC`
@@ -495,7 +633,7 @@ func (q *Queries) ListTagsByCategoryFilter(ctx context.Context, categoryID uuid.
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -507,9 +645,10 @@ func (q *Queries) ListTagsByCategoryFilter(ctx context.Context, categoryID uuid.
 }
 
 const listTagsByNameCategory = `-- name: ListTagsByNameCategory :many
-SELECT id, name, color, category_id
+SELECT id, name, color, category_id, parent_id
 FROM tags
-WHERE lower(name) LIKE lower($1) || '%'
+WHERE (lower(name) LIKE lower($1) || '%'
+       OR id IN (SELECT tag_id FROM tag_synonyms WHERE lower(tag_synonyms.name) LIKE lower($1) || '%'))
   AND category_id = $2
 ORDER BY name ASC`
 
@@ -522,7 +661,7 @@ func (q *Queries) ListTagsByNameCategory(ctx context.Context, prefix string, cat
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -552,7 +691,7 @@ func (q *Queries) DeleteTagFromTea(ctx context.Context, teaID uuid.UUID, tagID u
 }
 
 const listTagsByTea = `-- name: ListTagsByTea :many
-SELECT t.id, t.name, t.color, t.category_id
+SELECT t.id, t.name, t.color, t.category_id, t.parent_id
 FROM tea_tags tt
 JOIN tags t ON t.id = tt.tag_id
 WHERE tt.tea_id = $1
@@ -567,7 +706,220 @@ func (q *Queries) ListTagsByTea(ctx context.Context, teaID uuid.UUID) ([]Tag, er
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTagParent = `-- name: SetTagParent :one
+UPDATE tags
+SET parent_id = $2
+WHERE id = $1
+RETURNING id, name, color, category_id, parent_id`
+
+func (q *Queries) SetTagParent(ctx context.Context, id uuid.UUID, parentID uuid.NullUUID) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, setTagParent, id, parentID)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
+	return i, err
+}
+
+const listTagChildren = `-- name: ListTagChildren :many
+SELECT id, name, color, category_id, parent_id
+FROM tags
+WHERE parent_id = $1
+ORDER BY name ASC`
+
+func (q *Queries) ListTagChildren(ctx context.Context, parentID uuid.UUID) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listTagChildren, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagDescendantIDs = `-- name: ListTagDescendantIDs :many
+WITH RECURSIVE descendants AS (
+  SELECT tags.id FROM tags WHERE tags.id = $1
+  UNION
+  SELECT t.id FROM tags t JOIN descendants d ON t.parent_id = d.id
+)
+SELECT id FROM descendants`
+
+func (q *Queries) ListTagDescendantIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listTagDescendantIDs, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var i uuid.UUID
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertTagSynonym = `-- name: InsertTagSynonym :execrows
+INSERT INTO tag_synonyms (tag_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING`
+
+func (q *Queries) InsertTagSynonym(ctx context.Context, tagID uuid.UUID, name string) (int64, error) {
+	res, err := q.db.ExecContext(ctx, insertTagSynonym, tagID, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const deleteTagSynonym = `-- name: DeleteTagSynonym :exec
+DELETE FROM tag_synonyms
+WHERE tag_id = $1 AND lower(name) = lower($2)`
+
+func (q *Queries) DeleteTagSynonym(ctx context.Context, tagID uuid.UUID, name string) error {
+	_, err := q.db.ExecContext(ctx, deleteTagSynonym, tagID, name)
+	return err
+}
+
+const listTagSynonyms = `-- name: ListTagSynonyms :many
+SELECT name
+FROM tag_synonyms
+WHERE tag_id = $1
+ORDER BY name ASC`
+
+func (q *Queries) ListTagSynonyms(ctx context.Context, tagID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listTagSynonyms, tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resolveTagSynonym = `-- name: ResolveTagSynonym :one
+SELECT t.id, t.name, t.color, t.category_id, t.parent_id
+FROM tag_synonyms s
+JOIN tags t ON t.id = s.tag_id
+WHERE lower(s.name) = lower($1)
+  AND t.category_id = $2`
+
+func (q *Queries) ResolveTagSynonym(ctx context.Context, name string, categoryID uuid.UUID) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, resolveTagSynonym, name, categoryID)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name, &i.Color, &i.CategoryID, &i.ParentID)
+	return i, err
+}
+
+const moveTeaTags = `-- name: MoveTeaTags :exec
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT tea_id, $2
+FROM tea_tags
+WHERE tag_id = $1
+ON CONFLICT (tea_id, tag_id) DO NOTHING`
+
+func (q *Queries) MoveTeaTags(ctx context.Context, sourceID uuid.UUID, targetID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, moveTeaTags, sourceID, targetID)
+	return err
+}
+
+const moveTagChildren = `-- name: MoveTagChildren :exec
+UPDATE tags
+SET parent_id = $2
+WHERE parent_id = $1 AND id <> $2`
+
+func (q *Queries) MoveTagChildren(ctx context.Context, sourceID uuid.UUID, targetID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, moveTagChildren, sourceID, targetID)
+	return err
+}
+
+const moveTagSynonyms = `-- name: MoveTagSynonyms :exec
+UPDATE tag_synonyms
+SET tag_id = $2
+WHERE tag_id = $1`
+
+func (q *Queries) MoveTagSynonyms(ctx context.Context, sourceID uuid.UUID, targetID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, moveTagSynonyms, sourceID, targetID)
+	return err
+}
+
+const insertTeaTags = `-- name: InsertTeaTags :many
+INSERT INTO tea_tags (tea_id, tag_id)
+SELECT $1, x
+FROM unnest($2::uuid[]) AS t(x)
+ON CONFLICT (tea_id, tag_id) DO NOTHING
+RETURNING tag_id`
+
+func (q *Queries) InsertTeaTags(ctx context.Context, teaID uuid.UUID, tagIDs []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, insertTeaTags, teaID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var i uuid.UUID
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteTeaTags = `-- name: DeleteTeaTags :many
+DELETE FROM tea_tags
+WHERE tea_id = $1 AND tag_id = ANY($2::uuid[])
+RETURNING tag_id`
+
+func (q *Queries) DeleteTeaTags(ctx context.Context, teaID uuid.UUID, tagIDs []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, deleteTeaTags, teaID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var i uuid.UUID
+		if err := rows.Scan(&i); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -586,33 +938,142 @@ type QRRecord struct {
 	BoilingTemp    int32
 	ExpirationDate time.Time
 	CreatedAt      time.Time
+	OwnerID        uuid.NullUUID
 }
 
-const upsertQR = `-- name: UpsertQR :exec
-INSERT INTO qr_records (id, tea_id, boiling_temp, expiration_date)
-VALUES ($1, $2, $3, $4)
+const upsertQR = `-- name: UpsertQR :execrows
+INSERT INTO qr_records (id, tea_id, boiling_temp, expiration_date, owner_id)
+VALUES ($1, $2, $3, $4, $5)
 ON CONFLICT (id) DO UPDATE
 SET tea_id = EXCLUDED.tea_id,
     boiling_temp = EXCLUDED.boiling_temp,
-    expiration_date = EXCLUDED.expiration_date`
+    expiration_date = EXCLUDED.expiration_date,
+    owner_id = COALESCE(qr_records.owner_id, EXCLUDED.owner_id)
+WHERE qr_records.owner_id IS NULL OR qr_records.owner_id = EXCLUDED.owner_id OR $6::boolean`
 
-func (q *Queries) UpsertQR(ctx context.Context, arg QRRecord) error {
-	_, err := q.db.ExecContext(ctx, upsertQR, arg.ID, arg.TeaID, arg.BoilingTemp, arg.ExpirationDate)
-	return err
+func (q *Queries) UpsertQR(ctx context.Context, arg QRRecord, force bool) (int64, error) {
+	res, err := q.db.ExecContext(ctx, upsertQR, arg.ID, arg.TeaID, arg.BoilingTemp, arg.ExpirationDate, arg.OwnerID, force)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
 const getQR = `-- name: GetQR :one
-SELECT id, tea_id, boiling_temp, expiration_date, created_at
+SELECT id, tea_id, boiling_temp, expiration_date, created_at, owner_id
 FROM qr_records
 WHERE id = $1`
 
 func (q *Queries) GetQR(ctx context.Context, id uuid.UUID) (QRRecord, error) {
 	row := q.db.QueryRowContext(ctx, getQR, id)
 	var i QRRecord
-	err := row.Scan(&i.ID, &i.TeaID, &i.BoilingTemp, &i.ExpirationDate, &i.CreatedAt)
+	err := row.Scan(&i.ID, &i.TeaID, &i.BoilingTemp, &i.ExpirationDate, &i.CreatedAt, &i.OwnerID)
+	return i, err
+}
+
+const setQROwner = `-- name: SetQROwner :execrows
+UPDATE qr_records
+SET owner_id = $2
+WHERE id = $1 AND (owner_id = $3 OR $4::boolean)`
+
+func (q *Queries) SetQROwner(ctx context.Context, id uuid.UUID, owner, current uuid.NullUUID, force bool) (int64, error) {
+	res, err := q.db.ExecContext(ctx, setQROwner, id, owner, current, force)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type QRBatch struct {
+	ID        uuid.UUID
+	PrintedBy string
+	CreatedAt time.Time
+}
+
+type QRBatchStats struct {
+	ID        uuid.UUID
+	PrintedBy string
+	CreatedAt time.Time
+	Total     int64
+	Claimed   int64
+}
+
+const insertQRBatch = `-- name: InsertQRBatch :one
+INSERT INTO qr_batches (id, printed_by)
+VALUES ($1, $2)
+RETURNING id, printed_by, created_at`
+
+func (q *Queries) InsertQRBatch(ctx context.Context, id uuid.UUID, printedBy string) (QRBatch, error) {
+	row := q.db.QueryRowContext(ctx, insertQRBatch, id, printedBy)
+	var i QRBatch
+	err := row.Scan(&i.ID, &i.PrintedBy, &i.CreatedAt)
+	return i, err
+}
+
+const insertQRBatchCodes = `-- name: InsertQRBatchCodes :exec
+INSERT INTO qr_batch_codes (code, batch_id)
+SELECT x, $1
+FROM unnest($2::uuid[]) AS t(x)`
+
+func (q *Queries) InsertQRBatchCodes(ctx context.Context, batchID uuid.UUID, codes []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, insertQRBatchCodes, batchID, codes)
+	return err
+}
+
+const listQRBatches = `-- name: ListQRBatches :many
+SELECT b.id, b.printed_by, b.created_at, count(c.code) AS total, count(r.id) AS claimed
+FROM qr_batches b
+LEFT JOIN qr_batch_codes c ON c.batch_id = b.id
+LEFT JOIN qr_records r ON r.id = c.code
+GROUP BY b.id
+ORDER BY b.created_at DESC`
+
+func (q *Queries) ListQRBatches(ctx context.Context) ([]QRBatchStats, error) {
+	rows, err := q.db.QueryContext(ctx, listQRBatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QRBatchStats
+	for rows.Next() {
+		var i QRBatchStats
+		if err := rows.Scan(&i.ID, &i.PrintedBy, &i.CreatedAt, &i.Total, &i.Claimed); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQRBatch = `-- name: GetQRBatch :one
+SELECT b.id, b.printed_by, b.created_at, count(c.code) AS total, count(r.id) AS claimed
+FROM qr_batches b
+LEFT JOIN qr_batch_codes c ON c.batch_id = b.id
+LEFT JOIN qr_records r ON r.id = c.code
+WHERE b.id = $1
+GROUP BY b.id`
+
+func (q *Queries) GetQRBatch(ctx context.Context, id uuid.UUID) (QRBatchStats, error) {
+	row := q.db.QueryRowContext(ctx, getQRBatch, id)
+	var i QRBatchStats
+	err := row.Scan(&i.ID, &i.PrintedBy, &i.CreatedAt, &i.Total, &i.Claimed)
 	return i, err
 }
 
+const isQRCodeIssued = `-- name: IsQRCodeIssued :one
+SELECT EXISTS (SELECT 1 FROM qr_batch_codes WHERE code = $1)
+    OR EXISTS (SELECT 1 FROM qr_records WHERE id = $1)`
+
+func (q *Queries) IsQRCodeIssued(ctx context.Context, code uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isQRCodeIssued, code)
+	var issued bool
+	err := row.Scan(&issued)
+	return issued, err
+}
+
 // Collections
 
 type InsertCollectionParams struct {
@@ -726,6 +1187,7 @@ type ListCollectionRecordsRow struct {
 	Description    sql.NullString
 	BoilingTemp    int32
 	ExpirationDate time.Time
+	OwnerID        uuid.NullUUID
 }
 
 const listCollectionRecords = `-- name: ListCollectionRecords :many
@@ -736,7 +1198,8 @@ SELECT
   t.type,
   t.description,
   q.boiling_temp,
-  q.expiration_date
+  q.expiration_date,
+  q.owner_id
 FROM collection_qr_items c
 JOIN qr_records q ON q.id = c.qr_id
 JOIN teas t ON t.id = q.tea_id
@@ -752,7 +1215,7 @@ func (q *Queries) ListCollectionRecords(ctx context.Context, collectionID uuid.U
 	var items []ListCollectionRecordsRow
 	for rows.Next() {
 		var i ListCollectionRecordsRow
-		if err := rows.Scan(&i.QRID, &i.TeaID, &i.Name, &i.Type, &i.Description, &i.BoilingTemp, &i.ExpirationDate); err != nil {
+		if err := rows.Scan(&i.QRID, &i.TeaID, &i.Name, &i.Type, &i.Description, &i.BoilingTemp, &i.ExpirationDate, &i.OwnerID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -765,48 +1228,114 @@ func (q *Queries) ListCollectionRecords(ctx context.Context, collectionID uuid.U
 
 // Devices
 
-const insertDevice = `-- name: InsertDevice :exec
-INSERT INTO devices (id, user_id, token)
-VALUES ($1, $2, $3)
-ON CONFLICT (id) DO NOTHING`
+type BindDeviceParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Platform   string
+	Model      string
+	AppVersion string
+	BoundAt    time.Time
+}
 
-func (q *Queries) InsertDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID, token string) error {
-	_, err := q.db.ExecContext(ctx, insertDevice, id, userID, token)
-	return err
+const bindDevice = `-- name: BindDevice :execrows
+INSERT INTO devices (id, user_id, platform, model, app_version, last_seen_at, bound_at)
+VALUES ($1, $2, $3, $4, $5, now(), $6)
+ON CONFLICT (id) DO UPDATE
+SET platform = COALESCE(NULLIF(EXCLUDED.platform, ''), devices.platform),
+    model = COALESCE(NULLIF(EXCLUDED.model, ''), devices.model),
+    app_version = COALESCE(NULLIF(EXCLUDED.app_version, ''), devices.app_version),
+    last_seen_at = now(),
+    token = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.token END,
+    name = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.name ELSE '' END,
+    bound_at = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.bound_at ELSE EXCLUDED.bound_at END,
+    user_id = EXCLUDED.user_id
+WHERE devices.user_id = EXCLUDED.user_id OR NOT EXISTS (
+  SELECT 1 FROM sessions s
+  WHERE s.device_id = devices.id AND s.user_id = devices.user_id
+    AND s.revoked_at IS NULL AND s.expires_at > now()
+)`
+
+func (q *Queries) BindDevice(ctx context.Context, arg BindDeviceParams) (int64, error) {
+	res, err := q.db.ExecContext(ctx, bindDevice, arg.ID, arg.UserID, arg.Platform, arg.Model, arg.AppVersion, arg.BoundAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
-const updateDeviceToken = `-- name: UpdateDeviceToken :exec
+type UpdateDeviceTokenParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Token       string
+	Environment int16
+	Platform    string
+	Model       string
+	AppVersion  string
+}
+
+const updateDeviceToken = `-- name: UpdateDeviceToken :execrows
 UPDATE devices
-SET token = $2
-WHERE id = $1`
+SET token = $3, environment = $4,
+    platform = COALESCE(NULLIF($5, ''), platform),
+    model = COALESCE(NULLIF($6, ''), model),
+    app_version = COALESCE(NULLIF($7, ''), app_version)
+WHERE id = $1 AND user_id = $2`
 
-func (q *Queries) UpdateDeviceToken(ctx context.Context, id uuid.UUID, token string) (int64, error) {
-	res, err := q.db.ExecContext(ctx, updateDeviceToken, id, token)
+func (q *Queries) UpdateDeviceToken(ctx context.Context, arg UpdateDeviceTokenParams) (int64, error) {
+	res, err := q.db.ExecContext(ctx, updateDeviceToken,
+		arg.ID, arg.UserID, arg.Token, arg.Environment, arg.Platform, arg.Model, arg.AppVersion)
 	if err != nil {
 		return 0, err
 	}
 	return res.RowsAffected()
 }
 
-const listDeviceTokens = `-- name: ListDeviceTokens :many
-SELECT token
+const clearDeviceToken = `-- name: ClearDeviceToken :exec
+UPDATE devices
+SET token = NULL
+WHERE id = $1 AND token = $2`
+
+func (q *Queries) ClearDeviceToken(ctx context.Context, id uuid.UUID, token string) error {
+	_, err := q.db.ExecContext(ctx, clearDeviceToken, id, token)
+	return err
+}
+
+const setDeviceEnvironment = `-- name: SetDeviceEnvironment :exec
+UPDATE devices
+SET environment = $3
+WHERE id = $1 AND token = $2`
+
+func (q *Queries) SetDeviceEnvironment(ctx context.Context, id uuid.UUID, token string, environment int16) error {
+	_, err := q.db.ExecContext(ctx, setDeviceEnvironment, id, token, environment)
+	return err
+}
+
+type Device struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Token       string
+	Environment int16
+}
+
+const listUserDevices = `-- name: ListUserDevices :many
+SELECT id, user_id, token, environment
 FROM devices
-WHERE user_id = $1
+WHERE user_id = $1 AND token IS NOT NULL
 ORDER BY created_at DESC`
 
-func (q *Queries) ListDeviceTokens(ctx context.Context, userID uuid.UUID) ([]string, error) {
-	rows, err := q.db.QueryContext(ctx, listDeviceTokens, userID)
+func (q *Queries) ListUserDevices(ctx context.Context, userID uuid.UUID) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, listUserDevices, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []string
+	var items []Device
 	for rows.Next() {
-		var token string
-		if err := rows.Scan(&token); err != nil {
+		var i Device
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Token, &i.Environment); err != nil {
 			return nil, err
 		}
-		items = append(items, token)
+		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -814,23 +1343,285 @@ func (q *Queries) ListDeviceTokens(ctx context.Context, userID uuid.UUID) ([]str
 	return items, nil
 }
 
+type DeviceDetails struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Token       string
+	Environment int16
+	Name        string
+	Platform    string
+	Model       string
+	AppVersion  string
+	LastSeenAt  sql.NullTime
+	BoundAt     time.Time
+}
+
+const listDevices = `-- name: ListDevices :many
+SELECT id, user_id, COALESCE(token, '') AS token, environment, name, platform, model, app_version, last_seen_at, bound_at
+FROM devices
+WHERE user_id = $1
+ORDER BY last_seen_at DESC NULLS LAST, bound_at DESC`
+
+func (q *Queries) ListDevices(ctx context.Context, userID uuid.UUID) ([]DeviceDetails, error) {
+	rows, err := q.db.QueryContext(ctx, listDevices, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeviceDetails
+	for rows.Next() {
+		var i DeviceDetails
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Token, &i.Environment, &i.Name, &i.Platform, &i.Model, &i.AppVersion, &i.LastSeenAt, &i.BoundAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameDevice = `-- name: RenameDevice :one
+UPDATE devices
+SET name = $3
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, COALESCE(token, '') AS token, environment, name, platform, model, app_version, last_seen_at, bound_at`
+
+func (q *Queries) RenameDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID, name string) (DeviceDetails, error) {
+	row := q.db.QueryRowContext(ctx, renameDevice, id, userID, name)
+	var i DeviceDetails
+	err := row.Scan(&i.ID, &i.UserID, &i.Token, &i.Environment, &i.Name, &i.Platform, &i.Model, &i.AppVersion, &i.LastSeenAt, &i.BoundAt)
+	return i, err
+}
+
+const deleteDevice = `-- name: DeleteDevice :execrows
+DELETE FROM devices
+WHERE id = $1 AND user_id = $2`
+
+func (q *Queries) DeleteDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID) (int64, error) {
+	res, err := q.db.ExecContext(ctx, deleteDevice, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const seeDevice = `-- name: SeeDevice :one
+WITH seen AS (
+  UPDATE devices
+  SET last_seen_at = now()
+  WHERE id = $1 AND user_id = $2 AND date_trunc('second', bound_at) <= $3
+    AND (last_seen_at IS NULL OR last_seen_at < now() - interval '1 minute')
+)
+SELECT EXISTS (
+  SELECT 1 FROM devices
+  WHERE id = $1 AND user_id = $2 AND date_trunc('second', bound_at) <= $3
+)`
+
+func (q *Queries) SeeDevice(ctx context.Context, id uuid.UUID, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	row := q.db.QueryRowContext(ctx, seeDevice, id, userID, issuedAt)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+// Sessions
+
+type InsertSessionParams struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	DeviceID         uuid.UUID
+	RefreshTokenHash []byte
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+}
+
+const insertSession = `-- name: InsertSession :exec
+INSERT INTO sessions (id, user_id, device_id, refresh_token_hash, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)`
+
+func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) error {
+	_, err := q.db.ExecContext(ctx, insertSession, arg.ID, arg.UserID, arg.DeviceID, arg.RefreshTokenHash, arg.ExpiresAt, arg.CreatedAt)
+	return err
+}
+
+type RotateSessionParams struct {
+	RefreshTokenHash    []byte
+	DeviceID            uuid.UUID
+	NewRefreshTokenHash []byte
+	ExpiresAt           time.Time
+}
+
+type RotateSessionRow struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	DeviceID uuid.UUID
+}
+
+const rotateSession = `-- name: RotateSession :one
+UPDATE sessions s
+SET previous_token_hash = s.refresh_token_hash,
+    refresh_token_hash = $3,
+    refreshed_at = now(),
+    expires_at = $4
+FROM devices d
+WHERE s.refresh_token_hash = $1 AND s.device_id = $2
+  AND s.revoked_at IS NULL AND s.expires_at > now()
+  AND d.id = s.device_id AND d.user_id = s.user_id AND d.bound_at <= s.created_at
+RETURNING s.id, s.user_id, s.device_id`
+
+func (q *Queries) RotateSession(ctx context.Context, arg RotateSessionParams) (RotateSessionRow, error) {
+	row := q.db.QueryRowContext(ctx, rotateSession, arg.RefreshTokenHash, arg.DeviceID, arg.NewRefreshTokenHash, arg.ExpiresAt)
+	var i RotateSessionRow
+	err := row.Scan(&i.ID, &i.UserID, &i.DeviceID)
+	return i, err
+}
+
+const revokeSessionByPreviousToken = `-- name: RevokeSessionByPreviousToken :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE previous_token_hash = $1 AND revoked_at IS NULL
+  AND refreshed_at <= now() - $2::int * interval '1 second'`
+
+func (q *Queries) RevokeSessionByPreviousToken(ctx context.Context, previousTokenHash []byte, graceSeconds int32) (int64, error) {
+	res, err := q.db.ExecContext(ctx, revokeSessionByPreviousToken, previousTokenHash, graceSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+func (q *Queries) RevokeSession(ctx context.Context, id uuid.UUID, userID uuid.UUID) (int64, error) {
+	res, err := q.db.ExecContext(ctx, revokeSession, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL`
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	res, err := q.db.ExecContext(ctx, revokeUserSessions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const deleteEndedSessions = `-- name: DeleteEndedSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= now() - $1::int * interval '1 second'
+   OR revoked_at <= now() - $1::int * interval '1 second'`
+
+func (q *Queries) DeleteEndedSessions(ctx context.Context, keepSeconds int32) (int64, error) {
+	res, err := q.db.ExecContext(ctx, deleteEndedSessions, keepSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const seeSession = `-- name: SeeSession :one
+WITH live AS (
+  SELECT s.device_id
+  FROM sessions s
+  JOIN devices d ON d.id = s.device_id AND d.user_id = s.user_id
+  WHERE s.id = $1 AND s.user_id = $2 AND s.revoked_at IS NULL AND d.bound_at <= s.created_at
+), seen AS (
+  UPDATE devices
+  SET last_seen_at = now()
+  WHERE id IN (SELECT device_id FROM live)
+    AND (last_seen_at IS NULL OR last_seen_at < now() - interval '1 minute')
+)
+SELECT EXISTS (SELECT 1 FROM live)`
+
+func (q *Queries) SeeSession(ctx context.Context, id uuid.UUID, userID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, seeSession, id, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 // Notifications
 
 type Notification struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	Type      int16
-	CreatedAt time.Time
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Type       int16
+	Title      string
+	Body       string
+	EntityType sql.NullInt16
+	EntityID   uuid.NullUUID
+	CreatedAt  time.Time
+	ReadAt     sql.NullTime
+	DeliverAt  sql.NullTime
+}
+
+type InsertNotificationParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Type       int16
+	Title      string
+	Body       string
+	EntityType sql.NullInt16
+	EntityID   uuid.NullUUID
+	DeliverAt  sql.NullTime
+	SourceKey  sql.NullString
+}
+
+const insertNotification = `-- name: InsertNotification :one
+INSERT INTO notifications (id, user_id, type, title, body, entity_type, entity_id, deliver_at, source_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id, source_key) WHERE source_key IS NOT NULL DO NOTHING
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at`
+
+func (q *Queries) InsertNotification(ctx context.Context, arg InsertNotificationParams) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, insertNotification,
+		arg.ID, arg.UserID, arg.Type, arg.Title, arg.Body, arg.EntityType, arg.EntityID, arg.DeliverAt, arg.SourceKey)
+	var i Notification
+	err := row.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt)
+	return i, err
+}
+
+const getNotificationBySource = `-- name: GetNotificationBySource :one
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM notifications
+WHERE user_id = $1 AND source_key = $2`
+
+func (q *Queries) GetNotificationBySource(ctx context.Context, userID uuid.UUID, sourceKey string) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, getNotificationBySource, userID, sourceKey)
+	var i Notification
+	err := row.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt)
+	return i, err
+}
+
+type ListNotificationsParams struct {
+	UserID uuid.UUID
+	After  uuid.NullUUID
+	Limit  int32
 }
 
 const listNotifications = `-- name: ListNotifications :many
-SELECT id, user_id, type, created_at
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
 FROM notifications
 WHERE user_id = $1
-ORDER BY created_at DESC`
+  AND (deliver_at IS NULL OR deliver_at <= now())
+  AND ($2::uuid IS NULL OR (created_at, id) < (SELECT n.created_at, n.id FROM notifications n WHERE n.id = $2 AND n.user_id = $1))
+ORDER BY created_at DESC, id DESC
+LIMIT $3`
 
-func (q *Queries) ListNotifications(ctx context.Context, userID uuid.UUID) ([]Notification, error) {
-	rows, err := q.db.QueryContext(ctx, listNotifications, userID)
+func (q *Queries) ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, listNotifications, arg.UserID, arg.After, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -838,7 +1629,204 @@ func (q *Queries) ListNotifications(ctx context.Context, userID uuid.UUID) ([]No
 	var items []Notification
 	for rows.Next() {
 		var i Notification
-		if err := rows.Scan(&i.ID, &i.UserID, &i.Type, &i.CreatedAt); err != nil {
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreadNotifications = `-- name: ListUnreadNotifications :many
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM (
+  SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+  FROM notifications
+  WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now())
+  ORDER BY created_at DESC, id DESC
+  LIMIT $2
+) n
+ORDER BY created_at, id`
+
+func (q *Queries) ListUnreadNotifications(ctx context.Context, userID uuid.UUID, limit int32) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, listUnreadNotifications, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT count(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now())`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at`
+
+func (q *Queries) MarkNotificationRead(ctx context.Context, id, userID uuid.UUID) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, markNotificationRead, id, userID)
+	var i Notification
+	err := row.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt)
+	return i, err
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL AND (deliver_at IS NULL OR deliver_at <= now())`
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	res, err := q.db.ExecContext(ctx, markAllNotificationsRead, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const claimDueNotifications = `-- name: ClaimDueNotifications :many
+UPDATE notifications
+SET push_claimed_until = now() + $2::int * interval '1 second',
+    push_attempts = push_attempts + 1
+WHERE id IN (
+  SELECT d.id FROM notifications d
+  WHERE d.deliver_at <= now()
+    AND (d.push_claimed_until IS NULL OR d.push_claimed_until <= now())
+  ORDER BY d.deliver_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at, push_attempts`
+
+type ClaimDueNotificationsRow struct {
+	Notification Notification
+	PushAttempts int16
+}
+
+func (q *Queries) ClaimDueNotifications(ctx context.Context, limit, claimSeconds int32) ([]ClaimDueNotificationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueNotifications, limit, claimSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDueNotificationsRow
+	for rows.Next() {
+		var i ClaimDueNotificationsRow
+		n := &i.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.EntityType, &n.EntityID, &n.CreatedAt, &n.ReadAt, &n.DeliverAt, &i.PushAttempts); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeDeferredNotification = `-- name: CompleteDeferredNotification :exec
+UPDATE notifications
+SET deliver_at = NULL, push_claimed_until = NULL
+WHERE id = $1`
+
+func (q *Queries) CompleteDeferredNotification(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, completeDeferredNotification, id)
+	return err
+}
+
+const getNotification = `-- name: GetNotification :one
+SELECT id, user_id, type, title, body, entity_type, entity_id, created_at, read_at, deliver_at
+FROM notifications
+WHERE id = $1 AND user_id = $2`
+
+func (q *Queries) GetNotification(ctx context.Context, id, userID uuid.UUID) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, getNotification, id, userID)
+	var i Notification
+	err := row.Scan(&i.ID, &i.UserID, &i.Type, &i.Title, &i.Body, &i.EntityType, &i.EntityID, &i.CreatedAt, &i.ReadAt, &i.DeliverAt)
+	return i, err
+}
+
+const notifyNotification = `-- name: NotifyNotification :exec
+SELECT pg_notify($1, $2)`
+
+func (q *Queries) NotifyNotification(ctx context.Context, channel, payload string) error {
+	_, err := q.db.ExecContext(ctx, notifyNotification, channel, payload)
+	return err
+}
+
+type NotificationDelivery struct {
+	NotificationID uuid.UUID
+	Channel        int16
+	Target         string
+	DeviceID       uuid.NullUUID
+	Status         int16
+	Reason         string
+	CreatedAt      time.Time
+}
+
+type InsertNotificationDeliveriesParams struct {
+	NotificationID uuid.UUID
+	Channels       []int16
+	Targets        []string
+	DeviceIDs      []uuid.UUID
+	Statuses       []int16
+	Reasons        []string
+}
+
+const insertNotificationDeliveries = `-- name: InsertNotificationDeliveries :exec
+INSERT INTO notification_deliveries (notification_id, channel, target, device_id, status, reason)
+SELECT $1, t.channel, t.target, NULLIF(t.device_id, '00000000-0000-0000-0000-000000000000'::uuid), t.status, t.reason
+FROM unnest($2::smallint[], $3::text[], $4::uuid[], $5::smallint[], $6::text[]) AS t(channel, target, device_id, status, reason)
+ON CONFLICT (notification_id, channel, target) DO UPDATE
+SET status = EXCLUDED.status, reason = EXCLUDED.reason, created_at = now()`
+
+func (q *Queries) InsertNotificationDeliveries(ctx context.Context, arg InsertNotificationDeliveriesParams) error {
+	_, err := q.db.ExecContext(ctx, insertNotificationDeliveries,
+		arg.NotificationID, arg.Channels, arg.Targets, arg.DeviceIDs, arg.Statuses, arg.Reasons)
+	return err
+}
+
+const listNotificationDeliveries = `-- name: ListNotificationDeliveries :many
+SELECT notification_id, channel, target, device_id, status, reason, created_at
+FROM notification_deliveries
+WHERE notification_id = ANY($1::uuid[])
+ORDER BY created_at`
+
+func (q *Queries) ListNotificationDeliveries(ctx context.Context, notificationIds []uuid.UUID) ([]NotificationDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listNotificationDeliveries, notificationIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationDelivery
+	for rows.Next() {
+		var i NotificationDelivery
+		if err := rows.Scan(&i.NotificationID, &i.Channel, &i.Target, &i.DeviceID, &i.Status, &i.Reason, &i.CreatedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -849,6 +1837,278 @@ func (q *Queries) ListNotifications(ctx context.Context, userID uuid.UUID) ([]No
 	return items, nil
 }
 
+// Notification preferences
+
+type NotificationPreference struct {
+	UserID          uuid.UUID
+	Timezone        string
+	QuietStart      sql.NullInt16
+	QuietEnd        sql.NullInt16
+	DeliveryTime    sql.NullInt16
+	TeaOfTheDayTime sql.NullInt16
+}
+
+const getNotificationPreferences = `-- name: GetNotificationPreferences :one
+SELECT user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time
+FROM notification_preferences
+WHERE user_id = $1`
+
+func (q *Queries) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) (NotificationPreference, error) {
+	row := q.db.QueryRowContext(ctx, getNotificationPreferences, userID)
+	var i NotificationPreference
+	err := row.Scan(&i.UserID, &i.Timezone, &i.QuietStart, &i.QuietEnd, &i.DeliveryTime, &i.TeaOfTheDayTime)
+	return i, err
+}
+
+const upsertNotificationPreferences = `-- name: UpsertNotificationPreferences :exec
+INSERT INTO notification_preferences (user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+SET timezone = EXCLUDED.timezone,
+    quiet_start = EXCLUDED.quiet_start,
+    quiet_end = EXCLUDED.quiet_end,
+    delivery_time = EXCLUDED.delivery_time,
+    tea_of_the_day_time = EXCLUDED.tea_of_the_day_time,
+    updated_at = now()`
+
+func (q *Queries) UpsertNotificationPreferences(ctx context.Context, arg NotificationPreference) error {
+	_, err := q.db.ExecContext(ctx, upsertNotificationPreferences,
+		arg.UserID, arg.Timezone, arg.QuietStart, arg.QuietEnd, arg.DeliveryTime, arg.TeaOfTheDayTime)
+	return err
+}
+
+const listTeaOfTheDaySubscribers = `-- name: ListTeaOfTheDaySubscribers :many
+SELECT user_id, timezone, quiet_start, quiet_end, delivery_time, tea_of_the_day_time
+FROM notification_preferences
+WHERE tea_of_the_day_time IS NOT NULL`
+
+func (q *Queries) ListTeaOfTheDaySubscribers(ctx context.Context) ([]NotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, listTeaOfTheDaySubscribers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationPreference
+	for rows.Next() {
+		var i NotificationPreference
+		if err := rows.Scan(&i.UserID, &i.Timezone, &i.QuietStart, &i.QuietEnd, &i.DeliveryTime, &i.TeaOfTheDayTime); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDisabledNotificationTypes = `-- name: ListDisabledNotificationTypes :many
+SELECT type
+FROM notification_disabled_types
+WHERE user_id = $1
+ORDER BY type`
+
+func (q *Queries) ListDisabledNotificationTypes(ctx context.Context, userID uuid.UUID) ([]int16, error) {
+	rows, err := q.db.QueryContext(ctx, listDisabledNotificationTypes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int16
+	for rows.Next() {
+		var t int16
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteDisabledNotificationTypes = `-- name: DeleteDisabledNotificationTypes :exec
+DELETE FROM notification_disabled_types WHERE user_id = $1`
+
+func (q *Queries) DeleteDisabledNotificationTypes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteDisabledNotificationTypes, userID)
+	return err
+}
+
+const insertDisabledNotificationTypes = `-- name: InsertDisabledNotificationTypes :exec
+INSERT INTO notification_disabled_types (user_id, type)
+SELECT $1, unnest($2::smallint[])
+ON CONFLICT DO NOTHING`
+
+func (q *Queries) InsertDisabledNotificationTypes(ctx context.Context, userID uuid.UUID, types []int16) error {
+	_, err := q.db.ExecContext(ctx, insertDisabledNotificationTypes, userID, types)
+	return err
+}
+
+// Notification routes
+
+type NotificationRoute struct {
+	Channel  int16
+	Target   string
+	Verified bool
+}
+
+const listNotificationRoutes = `-- name: ListNotificationRoutes :many
+SELECT r.channel, r.target, (r.channel <> 2 OR v.verified_at IS NOT NULL)::boolean AS verified
+FROM notification_routes r
+LEFT JOIN notification_email_verifications v ON v.user_id = r.user_id AND v.address = r.target
+WHERE r.user_id = $1
+ORDER BY r.channel, r.target`
+
+func (q *Queries) ListNotificationRoutes(ctx context.Context, userID uuid.UUID) ([]NotificationRoute, error) {
+	rows, err := q.db.QueryContext(ctx, listNotificationRoutes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationRoute
+	for rows.Next() {
+		var i NotificationRoute
+		if err := rows.Scan(&i.Channel, &i.Target, &i.Verified); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteNotificationRoutes = `-- name: DeleteNotificationRoutes :exec
+DELETE FROM notification_routes WHERE user_id = $1`
+
+func (q *Queries) DeleteNotificationRoutes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteNotificationRoutes, userID)
+	return err
+}
+
+type InsertNotificationRoutesParams struct {
+	UserID   uuid.UUID
+	Channels []int16
+	Targets  []string
+}
+
+const insertNotificationRoutes = `-- name: InsertNotificationRoutes :exec
+INSERT INTO notification_routes (user_id, channel, target)
+SELECT $1, t.channel, t.target
+FROM unnest($2::smallint[], $3::text[]) AS t(channel, target)
+ON CONFLICT DO NOTHING`
+
+func (q *Queries) InsertNotificationRoutes(ctx context.Context, arg InsertNotificationRoutesParams) error {
+	_, err := q.db.ExecContext(ctx, insertNotificationRoutes, arg.UserID, arg.Channels, arg.Targets)
+	return err
+}
+
+type SetEmailVerificationCodeParams struct {
+	UserID        uuid.UUID
+	Address       string
+	CodeHash      []byte
+	ResendSeconds int32
+}
+
+const setEmailVerificationCode = `-- name: SetEmailVerificationCode :execrows
+INSERT INTO notification_email_verifications (user_id, address, code_hash, code_sent_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (user_id, address) DO UPDATE
+SET code_hash = EXCLUDED.code_hash, code_sent_at = now(), attempts = 0
+WHERE notification_email_verifications.verified_at IS NULL
+  AND (notification_email_verifications.code_sent_at IS NULL
+    OR notification_email_verifications.code_sent_at <= now() - $4::int * interval '1 second')`
+
+func (q *Queries) SetEmailVerificationCode(ctx context.Context, arg SetEmailVerificationCodeParams) (int64, error) {
+	res, err := q.db.ExecContext(ctx, setEmailVerificationCode, arg.UserID, arg.Address, arg.CodeHash, arg.ResendSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type ClaimEmailVerificationAttemptRow struct {
+	CodeHash   []byte
+	CodeSentAt time.Time
+	Attempts   int16
+}
+
+const claimEmailVerificationAttempt = `-- name: ClaimEmailVerificationAttempt :one
+UPDATE notification_email_verifications
+SET attempts = attempts + 1
+WHERE user_id = $1 AND address = $2 AND verified_at IS NULL AND code_hash IS NOT NULL
+RETURNING code_hash, code_sent_at, attempts`
+
+func (q *Queries) ClaimEmailVerificationAttempt(ctx context.Context, userID uuid.UUID, address string) (ClaimEmailVerificationAttemptRow, error) {
+	row := q.db.QueryRowContext(ctx, claimEmailVerificationAttempt, userID, address)
+	var i ClaimEmailVerificationAttemptRow
+	err := row.Scan(&i.CodeHash, &i.CodeSentAt, &i.Attempts)
+	return i, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE notification_email_verifications
+SET verified_at = now(), code_hash = NULL
+WHERE user_id = $1 AND address = $2`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, userID uuid.UUID, address string) error {
+	_, err := q.db.ExecContext(ctx, markEmailVerified, userID, address)
+	return err
+}
+
+// Tea of the day
+
+const getTeaOfTheDay = `-- name: GetTeaOfTheDay :one
+SELECT qr_id
+FROM tea_of_the_day
+WHERE user_id = $1 AND day = $2`
+
+func (q *Queries) GetTeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getTeaOfTheDay, userID, day)
+	var qrID uuid.UUID
+	err := row.Scan(&qrID)
+	return qrID, err
+}
+
+const insertTeaOfTheDay = `-- name: InsertTeaOfTheDay :one
+INSERT INTO tea_of_the_day (user_id, day, qr_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, day) DO UPDATE SET qr_id = tea_of_the_day.qr_id
+RETURNING qr_id`
+
+func (q *Queries) InsertTeaOfTheDay(ctx context.Context, userID uuid.UUID, day time.Time, qrID uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, insertTeaOfTheDay, userID, day, qrID)
+	var i uuid.UUID
+	err := row.Scan(&i)
+	return i, err
+}
+
+const claimTeaOfTheDayNotification = `-- name: ClaimTeaOfTheDayNotification :execrows
+UPDATE tea_of_the_day
+SET notified_at = now()
+WHERE user_id = $1 AND day = $2 AND notified_at IS NULL`
+
+func (q *Queries) ClaimTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) (int64, error) {
+	res, err := q.db.ExecContext(ctx, claimTeaOfTheDayNotification, userID, day)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const releaseTeaOfTheDayNotification = `-- name: ReleaseTeaOfTheDayNotification :exec
+UPDATE tea_of_the_day
+SET notified_at = NULL
+WHERE user_id = $1 AND day = $2`
+
+func (q *Queries) ReleaseTeaOfTheDayNotification(ctx context.Context, userID uuid.UUID, day time.Time) error {
+	_, err := q.db.ExecContext(ctx, releaseTeaOfTheDayNotification, userID, day)
+	return err
+}
+
 // Consumptions
 
 type InsertConsumptionParams struct {
@@ -867,13 +2127,16 @@ func (q *Queries) InsertConsumption(ctx context.Context, arg InsertConsumptionPa
 	return err
 }
 
-const deleteConsumptionsBefore = `-- name: DeleteConsumptionsBefore :exec
+const deleteConsumptionsBefore = `-- name: DeleteConsumptionsBefore :execrows
 DELETE FROM consumptions
-WHERE user_id = $1 AND ts < $2`
+WHERE ts < $1`
 
-func (q *Queries) DeleteConsumptionsBefore(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
-	_, err := q.db.ExecContext(ctx, deleteConsumptionsBefore, userID, cutoff)
-	return err
+func (q *Queries) DeleteConsumptionsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := q.db.ExecContext(ctx, deleteConsumptionsBefore, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
 type ListConsumptionsSinceParams struct {
@@ -911,3 +2174,298 @@ func (q *Queries) ListConsumptionsSince(ctx context.Context, arg ListConsumption
 	}
 	return items, nil
 }
+
+// Expiration alerts
+
+type ExpirationAlert struct {
+	UserID         uuid.UUID
+	QRID           uuid.UUID
+	LeadDays       int32
+	ExpirationDate time.Time
+	SentAt         time.Time
+}
+
+const listExpirationAlerts = `-- name: ListExpirationAlerts :many
+SELECT user_id, qr_id, lead_days, expiration_date, sent_at
+FROM expiration_alerts
+WHERE user_id = $1`
+
+func (q *Queries) ListExpirationAlerts(ctx context.Context, userID uuid.UUID) ([]ExpirationAlert, error) {
+	rows, err := q.db.QueryContext(ctx, listExpirationAlerts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExpirationAlert
+	for rows.Next() {
+		var i ExpirationAlert
+		if err := rows.Scan(&i.UserID, &i.QRID, &i.LeadDays, &i.ExpirationDate, &i.SentAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type InsertExpirationAlertsParams struct {
+	UserID          uuid.UUID
+	QRIDs           []uuid.UUID
+	LeadDays        []int32
+	ExpirationDates []time.Time
+}
+
+const insertExpirationAlerts = `-- name: InsertExpirationAlerts :exec
+INSERT INTO expiration_alerts (user_id, qr_id, lead_days, expiration_date)
+SELECT $1, t.qr_id, t.lead_days, t.expiration_date
+FROM unnest($2::uuid[], $3::integer[], $4::timestamptz[]) AS t(qr_id, lead_days, expiration_date)
+ON CONFLICT DO NOTHING`
+
+func (q *Queries) InsertExpirationAlerts(ctx context.Context, arg InsertExpirationAlertsParams) error {
+	_, err := q.db.ExecContext(ctx, insertExpirationAlerts, arg.UserID, arg.QRIDs, arg.LeadDays, arg.ExpirationDates)
+	return err
+}
+
+const deleteStaleExpirationAlerts = `-- name: DeleteStaleExpirationAlerts :execrows
+DELETE FROM expiration_alerts a
+USING qr_records q
+WHERE a.qr_id = q.id AND a.expiration_date <> q.expiration_date`
+
+func (q *Queries) DeleteStaleExpirationAlerts(ctx context.Context) (int64, error) {
+	res, err := q.db.ExecContext(ctx, deleteStaleExpirationAlerts)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Job runs
+
+type JobRun struct {
+	ID         uuid.UUID
+	Job        string
+	Trigger    int16
+	Status     int16
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+}
+
+type InsertJobRunParams struct {
+	ID        uuid.UUID
+	Job       string
+	Trigger   int16
+	Status    int16
+	StartedAt sql.NullTime
+}
+
+const insertJobRun = `-- name: InsertJobRun :one
+INSERT INTO job_runs (id, job, trigger, status, started_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, job, trigger, status, error, created_at, started_at, finished_at`
+
+func (q *Queries) InsertJobRun(ctx context.Context, arg InsertJobRunParams) (JobRun, error) {
+	row := q.db.QueryRowContext(ctx, insertJobRun, arg.ID, arg.Job, arg.Trigger, arg.Status, arg.StartedAt)
+	var i JobRun
+	err := row.Scan(&i.ID, &i.Job, &i.Trigger, &i.Status, &i.Error, &i.CreatedAt, &i.StartedAt, &i.FinishedAt)
+	return i, err
+}
+
+const startPendingJobRun = `-- name: StartPendingJobRun :one
+UPDATE job_runs
+SET status = 1, started_at = now()
+WHERE id = (
+  SELECT id FROM job_runs
+  WHERE job = $1 AND status = 0
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, job, trigger, status, error, created_at, started_at, finished_at`
+
+func (q *Queries) StartPendingJobRun(ctx context.Context, job string) (JobRun, error) {
+	row := q.db.QueryRowContext(ctx, startPendingJobRun, job)
+	var i JobRun
+	err := row.Scan(&i.ID, &i.Job, &i.Trigger, &i.Status, &i.Error, &i.CreatedAt, &i.StartedAt, &i.FinishedAt)
+	return i, err
+}
+
+type FinishJobRunParams struct {
+	ID     uuid.UUID
+	Status int16
+	Error  string
+}
+
+const finishJobRun = `-- name: FinishJobRun :exec
+UPDATE job_runs
+SET status = $2, error = $3, finished_at = now()
+WHERE id = $1`
+
+func (q *Queries) FinishJobRun(ctx context.Context, arg FinishJobRunParams) error {
+	_, err := q.db.ExecContext(ctx, finishJobRun, arg.ID, arg.Status, arg.Error)
+	return err
+}
+
+const abandonRunningJobRuns = `-- name: AbandonRunningJobRuns :execrows
+UPDATE job_runs
+SET status = 3, error = 'interrupted', finished_at = now()
+WHERE status = 1`
+
+func (q *Queries) AbandonRunningJobRuns(ctx context.Context) (int64, error) {
+	res, err := q.db.ExecContext(ctx, abandonRunningJobRuns)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type ListLastJobStartsRow struct {
+	Job       string
+	StartedAt time.Time
+}
+
+const listLastJobStarts = `-- name: ListLastJobStarts :many
+SELECT job, max(started_at)::timestamptz AS started_at
+FROM job_runs
+WHERE started_at IS NOT NULL
+GROUP BY job`
+
+func (q *Queries) ListLastJobStarts(ctx context.Context) ([]ListLastJobStartsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLastJobStarts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLastJobStartsRow
+	for rows.Next() {
+		var i ListLastJobStartsRow
+		if err := rows.Scan(&i.Job, &i.StartedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJobRuns = `-- name: ListJobRuns :many
+SELECT id, job, trigger, status, error, created_at, started_at, finished_at
+FROM job_runs
+WHERE $1::text = '' OR job = $1
+ORDER BY created_at DESC
+LIMIT $2`
+
+func (q *Queries) ListJobRuns(ctx context.Context, job string, limit int32) ([]JobRun, error) {
+	rows, err := q.db.QueryContext(ctx, listJobRuns, job, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []JobRun
+	for rows.Next() {
+		var i JobRun
+		if err := rows.Scan(&i.ID, &i.Job, &i.Trigger, &i.Status, &i.Error, &i.CreatedAt, &i.StartedAt, &i.FinishedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tryAdvisoryLock = `-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1)`
+
+func (q *Queries) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, tryAdvisoryLock, key)
+	var locked bool
+	err := row.Scan(&locked)
+	return locked, err
+}
+
+const advisoryUnlock = `-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock($1)`
+
+func (q *Queries) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, advisoryUnlock, key)
+	var unlocked bool
+	err := row.Scan(&unlocked)
+	return unlocked, err
+}
+
+// Admin token revocations
+
+type AdminTokenRevocation struct {
+	Jti       string
+	Reason    string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+const revokeAdminToken = `-- name: RevokeAdminToken :execrows
+INSERT INTO admin_token_revocations (jti, reason, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING`
+
+func (q *Queries) RevokeAdminToken(ctx context.Context, jti string, reason string, expiresAt time.Time) (int64, error) {
+	res, err := q.db.ExecContext(ctx, revokeAdminToken, jti, reason, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const adminTokenRevoked = `-- name: AdminTokenRevoked :one
+SELECT EXISTS (SELECT 1 FROM admin_token_revocations WHERE jti = $1)`
+
+func (q *Queries) AdminTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, adminTokenRevoked, jti)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listAdminTokenRevocations = `-- name: ListAdminTokenRevocations :many
+SELECT jti, reason, revoked_at, expires_at
+FROM admin_token_revocations
+WHERE expires_at > now()
+ORDER BY revoked_at DESC`
+
+func (q *Queries) ListAdminTokenRevocations(ctx context.Context) ([]AdminTokenRevocation, error) {
+	rows, err := q.db.QueryContext(ctx, listAdminTokenRevocations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AdminTokenRevocation
+	for rows.Next() {
+		var i AdminTokenRevocation
+		if err := rows.Scan(&i.Jti, &i.Reason, &i.RevokedAt, &i.ExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteExpiredAdminTokenRevocations = `-- name: DeleteExpiredAdminTokenRevocations :execrows
+DELETE FROM admin_token_revocations
+WHERE expires_at <= now()`
+
+func (q *Queries) DeleteExpiredAdminTokenRevocations(ctx context.Context) (int64, error) {
+	res, err := q.db.ExecContext(ctx, deleteExpiredAdminTokenRevocations)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
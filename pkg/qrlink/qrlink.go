@@ -0,0 +1,86 @@
+// Package qrlink converts QR record IDs to and from the universal links printed on labels.
+package qrlink
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PathPrefix is the URL path under which short codes are served.
+const PathPrefix = "/q/"
+
+const (
+	alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// codeLen is the number of base62 digits needed for 128 bits.
+	codeLen = 22
+)
+
+// ErrInvalidCode indicates the value is neither a UUID, a short code nor a QR link.
+var ErrInvalidCode = errors.New("invalid qr code")
+
+var base = big.NewInt(int64(len(alphabet)))
+
+// Encode returns the fixed-width base62 short code for id.
+func Encode(id uuid.UUID) string {
+	n := new(big.Int).SetBytes(id[:])
+	res := make([]byte, codeLen)
+	mod := new(big.Int)
+	for i := codeLen - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		res[i] = alphabet[mod.Int64()]
+	}
+
+	return string(res)
+}
+
+// Decode converts a short code back to the ID.
+func Decode(code string) (uuid.UUID, error) {
+	if len(code) != codeLen {
+		return uuid.Nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+
+	n := new(big.Int)
+	for i := 0; i < len(code); i++ {
+		digit := strings.IndexByte(alphabet, code[i])
+		if digit < 0 {
+			return uuid.Nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(digit)))
+	}
+
+	if n.BitLen() > len(uuid.Nil)*8 {
+		return uuid.Nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+
+	var id uuid.UUID
+	n.FillBytes(id[:])
+
+	return id, nil
+}
+
+// URL returns the universal link for id under baseURL (e.g. https://tea-elephant.com).
+func URL(baseURL string, id uuid.UUID) string {
+	return strings.TrimRight(baseURL, "/") + PathPrefix + Encode(id)
+}
+
+// Parse accepts anything a label may contain: a universal link, a bare short code or a
+// UUID printed on old stickers.
+func Parse(value string) (uuid.UUID, error) {
+	value = strings.TrimSpace(value)
+	if id, err := uuid.Parse(value); err == nil {
+		return id, nil
+	}
+
+	if i := strings.LastIndex(value, PathPrefix); i >= 0 {
+		value = value[i+len(PathPrefix):]
+		if j := strings.IndexAny(value, "/?#"); j >= 0 {
+			value = value[:j]
+		}
+	}
+
+	return Decode(value)
+}
@@ -0,0 +1,40 @@
+package qrlink
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, id := range []uuid.UUID{uuid.Nil, uuid.Max, uuid.New()} {
+		code := Encode(id)
+		assert.Len(t, code, codeLen)
+
+		got, err := Decode(code)
+		require.NoError(t, err)
+		assert.Equal(t, id, got)
+	}
+
+	_, err := Decode("zzzzzzzzzzzzzzzzzzzzzz")
+	assert.ErrorIs(t, err, ErrInvalidCode, "overflows 128 bits")
+	_, err = Decode("short")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestParse(t *testing.T) {
+	id := uuid.New()
+	link := URL("https://tea-elephant.com/", id)
+	assert.Equal(t, "https://tea-elephant.com/q/"+Encode(id), link)
+
+	for _, value := range []string{id.String(), Encode(id), link, link + "?src=label", " " + link + "\n"} {
+		got, err := Parse(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, id, got, value)
+	}
+
+	_, err := Parse("https://example.com/other")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
@@ -13,57 +13,44 @@ func NewClient(creator *creator.Creator) *Client {
 	return &Client{creator: creator}
 }
 
-func (c *Client) generatePdf(images [][]byte) error {
-	err := c.writeQRCodes(images)
-	if err != nil {
+func (c *Client) generatePdf(images [][]byte, t Template, output string) error {
+	c.creator.SetPageSize(creator.PageSize{t.Paper.Width, t.Paper.Height})
+
+	if err := c.writeQRCodes(images, t); err != nil {
 		return err
 	}
 
-	return c.creator.WriteToFile("qr_codes.pdf")
+	return c.creator.WriteToFile(output)
 }
 
-func (c *Client) writeQRCodes(images [][]byte) error {
-	table := c.creator.NewTable(cols)
+func (c *Client) writeQRCodes(images [][]byte, t Template) error {
 	for i, image := range images {
-		err := c.addQRCode(table, image, i)
-		if err != nil {
+		index := i % t.PerPage()
+		if index == 0 {
+			c.creator.NewPage()
+		}
+
+		if err := c.addQRCode(image, t, index); err != nil {
 			return err
 		}
 	}
 
-	return c.creator.Draw(table)
+	return nil
 }
 
-func (c *Client) addQRCode(table *creator.Table, data []byte, index int) error {
+func (c *Client) addQRCode(data []byte, t Template, index int) error {
 	image, err := c.creator.NewImageFromData(data)
 	if err != nil {
 		return errors.Wrap(err, "failed to create image")
 	}
 
-	l, r, t, b := qrMargins(index % codesOnList)
-	image.SetMargins(l, r, t, b)
-	image.SetFitMode(creator.FitModeFillWidth)
+	x, y, size := t.Cell(index)
+	image.ScaleToWidth(size)
+	image.SetPos(x, y)
 
-	cell := table.NewCell()
-	cell.SetBackgroundColor(creator.ColorBlack)
-
-	if err = cell.SetContent(image); err != nil {
-		return errors.Wrap(err, "failed to set image")
+	if err = c.creator.Draw(image); err != nil {
+		return errors.Wrap(err, "failed to draw image")
 	}
 
 	return nil
 }
-
-var qrMarginTable = [codesOnList][4]float64{
-	{0, 7, 0, 7}, // idx 0
-	{7, 0, 0, 7}, // idx 1
-	{0, 7, 7, 7}, // idx 2
-	{7, 0, 7, 7}, // idx 3
-	{0, 7, 7, 0}, // idx 4
-	{7, 0, 7, 0}, // idx 5
-}
-
-func qrMargins(indexOnList int) (left, right, top, bottom float64) {
-	m := qrMarginTable[indexOnList]
-	return m[0], m[1], m[2], m[3]
-}
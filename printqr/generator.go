@@ -1,52 +1,173 @@
 package printqr
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/unidoc/unipdf/v3/common/license"
 	"github.com/unidoc/unipdf/v3/creator"
+
+	"github.com/teaelephant/TeaElephantMemory/common"
+	"github.com/teaelephant/TeaElephantMemory/nfc"
+	"github.com/teaelephant/TeaElephantMemory/pkg/qrlink"
 )
 
 const (
-	marginRight = 80
-	marginEmpty = 0
-	codesOnList = 6
-	cols        = 2
+	dirPerm  = 0o750
+	filePerm = 0o600
 )
 
 type Generator interface {
-	GenerateAndSave(lists int) error
+	GenerateAndSave(ctx context.Context, pages int, printedBy string) (batch uuid.UUID, err error)
+}
+
+// Registry records issued QR codes so that only printed codes can be claimed.
+type Registry interface {
+	CreateQRBatch(ctx context.Context, printedBy string, codes []uuid.UUID) (*common.QRBatch, error)
+}
+
+// Options control the layout and destination of a generated batch.
+type Options struct {
+	Template Template
+	// Output is the PDF file for sheet backends and the directory for per-code backends.
+	Output string
+	// Inverted prints white modules on black, as the original labels did.
+	Inverted bool
+	// BaseURL turns codes into universal links (BaseURL/q/<short code>); bare UUIDs are printed when empty.
+	BaseURL string
+}
+
+// Payload returns the text encoded in the QR code for id.
+func (o Options) Payload(id uuid.UUID) string {
+	if o.BaseURL == "" {
+		return id.String()
+	}
+
+	return qrlink.URL(o.BaseURL, id)
 }
 
 type generator struct {
-	key string
+	registry Registry
+	template Template
+	render   func(codes []uuid.UUID) error
 }
 
-func NewGenerator(key string) Generator {
-	return &generator{key: key}
+// NewGenerator returns the UniDoc-backed PDF generator; it requires a metered license key.
+func NewGenerator(key string, registry Registry, opts Options) Generator {
+	return &generator{registry: registry, template: opts.Template, render: func(codes []uuid.UUID) error {
+		return generateUnidocPdf(key, codes, opts)
+	}}
 }
 
-func (g *generator) GenerateAndSave(lists int) (err error) {
-	items := make([][]byte, lists*codesOnList)
-	for i := 0; i < lists*codesOnList; i++ {
-		items[i], err = NewQR()
+// NewPDFGenerator returns a license-free generator that writes vector PDF sheets.
+func NewPDFGenerator(registry Registry, opts Options) Generator {
+	return &generator{registry: registry, template: opts.Template, render: func(codes []uuid.UUID) error {
+		f, err := os.Create(opts.Output)
 		if err != nil {
+			return errors.Wrap(err, "failed to create pdf")
+		}
+
+		if err = writePDF(f, codes, opts); err != nil {
+			_ = f.Close()
 			return err
 		}
+
+		return f.Close()
+	}}
+}
+
+// NewSVGGenerator returns a generator that writes one <code>.svg per code into opts.Output.
+func NewSVGGenerator(registry Registry, opts Options) Generator {
+	return &generator{registry: registry, template: opts.Template, render: func(codes []uuid.UUID) error {
+		return writeFiles(codes, opts.Output, ".svg", func(id uuid.UUID) ([]byte, error) {
+			return NewSVG(opts.Payload(id), opts.Inverted)
+		})
+	}}
+}
+
+// NewPNGGenerator returns a generator that writes one <code>.png per code into opts.Output.
+func NewPNGGenerator(registry Registry, opts Options) Generator {
+	return &generator{registry: registry, template: opts.Template, render: func(codes []uuid.UUID) error {
+		return writeFiles(codes, opts.Output, ".png", func(id uuid.UUID) ([]byte, error) {
+			return NewPNG(opts.Payload(id), opts.Inverted)
+		})
+	}}
+}
+
+// NewNDEFGenerator returns a generator for NFC stickers: one <code>.ndef NDEF message per code in
+// opts.Output, plus ndef.txt listing every code with its message in hex for tag writer apps.
+func NewNDEFGenerator(registry Registry, opts Options) Generator {
+	return &generator{registry: registry, template: opts.Template, render: func(codes []uuid.UUID) error {
+		var index strings.Builder
+		err := writeFiles(codes, opts.Output, ".ndef", func(id uuid.UUID) ([]byte, error) {
+			msg := nfc.Message(opts.Payload(id), "")
+			fmt.Fprintf(&index, "%s %s\n", id, nfc.Hex(msg))
+
+			return msg, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return errors.Wrap(os.WriteFile(filepath.Join(opts.Output, "ndef.txt"), []byte(index.String()), filePerm),
+			"failed to write ndef index")
+	}}
+}
+
+// GenerateAndSave mints enough codes to fill pages sheets, registers them as one batch and renders them.
+func (g *generator) GenerateAndSave(ctx context.Context, pages int, printedBy string) (uuid.UUID, error) {
+	codes := make([]uuid.UUID, pages*g.template.PerPage())
+	for i := range codes {
+		codes[i] = uuid.New()
 	}
 
-	return g.GenerateQRPdf(items)
+	// Register before rendering so that every printed code is claimable.
+	batch, err := g.registry.CreateQRBatch(ctx, printedBy, codes)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, "failed to register batch")
+	}
+
+	return batch.ID, g.render(codes)
 }
 
-func (g *generator) GenerateQRPdf(images [][]byte) error {
-	err := license.SetMeteredKey(g.key)
+func generateUnidocPdf(key string, codes []uuid.UUID, opts Options) error {
+	err := license.SetMeteredKey(key)
 	if err != nil {
 		return errors.Wrap(err, "failed to set metered key")
 	}
 
-	c := creator.New()
-	c.SetPageMargins(marginEmpty, marginRight, marginEmpty, marginEmpty)
+	images := make([][]byte, len(codes))
+	for i, id := range codes {
+		if images[i], err = NewPNG(opts.Payload(id), opts.Inverted); err != nil {
+			return err
+		}
+	}
+
+	cr := NewClient(creator.New())
 
-	cr := NewClient(c)
+	return cr.generatePdf(images, opts.Template, opts.Output)
+}
+
+func writeFiles(codes []uuid.UUID, dir, ext string, render func(id uuid.UUID) ([]byte, error)) error {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return errors.Wrap(err, "failed to create output directory")
+	}
+
+	for _, id := range codes {
+		data, err := render(id)
+		if err != nil {
+			return err
+		}
+
+		if err = os.WriteFile(filepath.Join(dir, id.String()+ext), data, filePerm); err != nil {
+			return errors.Wrap(err, "failed to write code")
+		}
+	}
 
-	return cr.generatePdf(images)
+	return nil
 }
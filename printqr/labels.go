@@ -0,0 +1,90 @@
+package printqr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+const (
+	labelMinPadding = 4
+	labelMaxFont    = 12
+	labelLeading    = 1.25
+	titleScale      = 1.15
+	// Labels at least this much wider than tall get the text beside the code, others below it.
+	wideLabelRatio = 1.5
+	// belowCodeShare is the share of the label side used by the code when the text goes below it.
+	belowCodeShare = 0.55
+)
+
+// Label is a finished label: the QR code of a record with a title and a few text lines.
+type Label struct {
+	ID    uuid.UUID
+	Title string
+	Lines []string
+}
+
+// WriteLabels lays labels out on opts.Template sheets as a vector PDF, one label per template cell.
+func WriteLabels(w io.Writer, labels []Label, opts Options) error {
+	t := opts.Template
+	_, _, bg, fg := colors(opts.Inverted)
+
+	perPage := t.PerPage()
+	var contents [][]byte
+	for start := 0; start < len(labels); start += perPage {
+		var page bytes.Buffer
+		for i, label := range labels[start:min(start+perPage, len(labels))] {
+			if err := drawLabel(&page, label, opts, i, bg, fg); err != nil {
+				return err
+			}
+		}
+		contents = append(contents, page.Bytes())
+	}
+
+	return writePages(w, contents, t.Paper)
+}
+
+func drawLabel(buf *bytes.Buffer, label Label, opts Options, index, bg, fg int) error {
+	t := opts.Template
+	x, y := t.Label(index)
+	w, h := t.LabelWidth, t.LabelHeight
+	pad := max(t.Padding, labelMinPadding)
+
+	if opts.Inverted {
+		drawRect(buf, x, y, w, h, t.Paper.Height, bg)
+	}
+
+	// Text box, filled in next to or below the code.
+	var size, tx, ty, tw, th float64
+	if w >= wideLabelRatio*h {
+		size = h - 2*pad
+		tx, ty, tw, th = x+size+2*pad, y+pad, w-size-3*pad, h-2*pad
+		if err := drawCode(buf, opts.Payload(label.ID), x+pad, y+pad, size, t.Paper.Height, bg, fg); err != nil {
+			return err
+		}
+	} else {
+		size = min(w, h) * belowCodeShare
+		tx, ty, tw, th = x+pad, y+2*pad+size, w-2*pad, h-size-3*pad
+		if err := drawCode(buf, opts.Payload(label.ID), x+(w-size)/2, y+pad, size, t.Paper.Height, bg, fg); err != nil {
+			return err
+		}
+	}
+
+	lines := float64(len(label.Lines)) + titleScale
+	font := min(labelMaxFont, th/(lines*labelLeading))
+	baseline := ty + font*titleScale
+
+	drawText(buf, label.Title, fontBold, font*titleScale, tx, baseline, tw, t.Paper.Height, fg)
+	for _, line := range label.Lines {
+		baseline += font * labelLeading
+		drawText(buf, line, fontRegular, font, tx, baseline, tw, t.Paper.Height, fg)
+	}
+
+	return nil
+}
+
+func drawRect(buf *bytes.Buffer, x, y, w, h, pageHeight float64, color int) {
+	fmt.Fprintf(buf, "%d g %.3f %.3f %.3f %.3f re f\n", color, x, pageHeight-y-h, w, h)
+}
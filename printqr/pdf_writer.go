@@ -0,0 +1,161 @@
+package printqr
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Standard Type 1 fonts every PDF reader provides; text is limited to the WinAnsi (Latin-1) range.
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	minFontSize = 6
+	// avgGlyphWidth is a conservative average Helvetica glyph width in em, used to fit text.
+	avgGlyphWidth = 0.55
+)
+
+// writePDF renders codes onto template pages as vector graphics. It is a minimal PDF 1.4 writer
+// that needs no third-party library or license.
+func writePDF(w io.Writer, codes []uuid.UUID, opts Options) error {
+	t := opts.Template
+	_, _, bg, fg := colors(opts.Inverted)
+
+	perPage := t.PerPage()
+	var contents [][]byte
+	for start := 0; start < len(codes); start += perPage {
+		var page bytes.Buffer
+		for i, id := range codes[start:min(start+perPage, len(codes))] {
+			x, y, size := t.Cell(i)
+			if err := drawCode(&page, opts.Payload(id), x, y, size, t.Paper.Height, bg, fg); err != nil {
+				return err
+			}
+		}
+		contents = append(contents, page.Bytes())
+	}
+
+	return writePages(w, contents, t.Paper)
+}
+
+// drawCode appends drawing operators for one code with its top-left corner at (x, y). PDF
+// coordinates start at the bottom-left corner, so y is flipped against pageHeight.
+func drawCode(buf *bytes.Buffer, content string, x, y, size, pageHeight float64, bg, fg int) error {
+	runs, n, err := codeRuns(content)
+	if err != nil {
+		return err
+	}
+
+	top := pageHeight - y
+	module := size / float64(n)
+
+	fmt.Fprintf(buf, "%d g %.3f %.3f %.3f %.3f re f\n", bg, x, top-size, size, size)
+	fmt.Fprintf(buf, "%d g\n", fg)
+	for _, r := range runs {
+		fmt.Fprintf(buf, "%.3f %.3f %.3f %.3f re\n",
+			x+float64(r.col)*module, top-float64(r.row+1)*module, float64(r.length)*module, module)
+	}
+	buf.WriteString("f\n")
+
+	return nil
+}
+
+// drawText appends a single line of text with its baseline at (x, y) from the top of the page. Text
+// too wide for width is set smaller, down to minFontSize, and then truncated with an ellipsis.
+func drawText(buf *bytes.Buffer, text, font string, size, x, y, width, pageHeight float64, color int) {
+	if n := len([]rune(text)); n > 0 {
+		size = max(min(size, width/(float64(n)*avgGlyphWidth)), min(size, minFontSize))
+	}
+
+	fmt.Fprintf(buf, "BT %d g /%s %.2f Tf %.3f %.3f Td (%s) Tj ET\n",
+		color, font, size, x, pageHeight-y, pdfString(fitText(text, size, width)))
+}
+
+func fitText(text string, size, width float64) string {
+	limit := int(width / (size * avgGlyphWidth))
+	r := []rune(text)
+	if len(r) <= limit {
+		return text
+	}
+	if limit < 1 {
+		return ""
+	}
+
+	return string(r[:limit-1]) + "…"
+}
+
+// pdfString escapes text for a PDF literal string in WinAnsiEncoding. Characters outside
+// Latin-1 cannot be shown by the standard fonts and are replaced with '?'.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '…':
+			b.WriteString(`\205`)
+		case r == '–':
+			b.WriteString(`\226`)
+		case r >= ' ' && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+// writePages writes the document structure: catalog, page tree, the two standard fonts, and a
+// page plus content stream object per page, followed by the cross-reference table.
+func writePages(w io.Writer, contents [][]byte, paper Paper) error {
+	const firstPageObject = 5
+
+	out := bufio.NewWriter(w)
+	offset := 0
+	var offsets []int
+
+	write := func(format string, args ...any) {
+		n, _ := fmt.Fprintf(out, format, args...)
+		offset += n
+	}
+	object := func(body string, args ...any) {
+		offsets = append(offsets, offset)
+		write("%d 0 obj\n", len(offsets))
+		write(body, args...)
+		write("\nendobj\n")
+	}
+
+	write("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	kids := bytes.Buffer{}
+	for i := range contents {
+		fmt.Fprintf(&kids, "%d 0 R ", firstPageObject+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(contents))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range contents {
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.3f %.3f] /Contents %d 0 R "+
+			"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> >>",
+			paper.Width, paper.Height, firstPageObject+2*i+1, fontRegular, fontBold)
+		object("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+	}
+
+	xref := offset
+	write("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		write("%010d 00000 n \n", o)
+	}
+	write("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Flush()
+}
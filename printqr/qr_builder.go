@@ -3,22 +3,26 @@ package printqr
 import (
 	"image/color"
 
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/skip2/go-qrcode"
 )
 
-func NewQR() ([]byte, error) {
-	id := uuid.New()
+// NewQR renders content as an inverted (white on black) PNG, as printed on the original labels.
+func NewQR(content string) ([]byte, error) {
+	return NewPNG(content, true)
+}
 
-	code, err := qrcode.New(id.String(), qrcode.Highest)
+// NewPNG renders content as a PNG, black on white unless inverted.
+func NewPNG(content string, inverted bool) ([]byte, error) {
+	code, err := newCode(content)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate QR code")
+		return nil, err
 	}
 
-	code.DisableBorder = true
-	code.BackgroundColor = color.Black
-	code.ForegroundColor = color.White
+	code.BackgroundColor, code.ForegroundColor = color.White, color.Black
+	if inverted {
+		code.BackgroundColor, code.ForegroundColor = color.Black, color.White
+	}
 
 	data, err := code.PNG(-5)
 	if err != nil {
@@ -27,3 +31,14 @@ func NewQR() ([]byte, error) {
 
 	return data, nil
 }
+
+func newCode(content string) (*qrcode.QRCode, error) {
+	code, err := qrcode.New(content, qrcode.Highest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate QR code")
+	}
+
+	code.DisableBorder = true
+
+	return code, nil
+}
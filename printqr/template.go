@@ -0,0 +1,146 @@
+package printqr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// All layout values are in PDF points (1/72 inch); the origin is the top-left corner of the page.
+const (
+	pointsPerInch = 72
+	pointsPerMM   = pointsPerInch / 25.4
+)
+
+var (
+	ErrUnknownTemplate = errors.New("unknown label template")
+	ErrUnknownPaper    = errors.New("unknown paper size")
+)
+
+// Paper is a page size.
+type Paper struct {
+	Width  float64
+	Height float64
+}
+
+var papers = map[string]Paper{
+	"a4":     {Width: 210 * pointsPerMM, Height: 297 * pointsPerMM},
+	"letter": {Width: 8.5 * pointsPerInch, Height: 11 * pointsPerInch},
+}
+
+// Template is a label-sheet grid: Rows x Cols labels of LabelWidth x LabelHeight, the first one at
+// (MarginLeft, MarginTop) and the next ones PitchX / PitchY apart. The QR code is centered in the
+// label, Padding away from its shorter edges.
+type Template struct {
+	Name        string
+	Paper       Paper
+	Rows        int
+	Cols        int
+	MarginLeft  float64
+	MarginTop   float64
+	PitchX      float64
+	PitchY      float64
+	LabelWidth  float64
+	LabelHeight float64
+	Padding     float64
+}
+
+var templates = map[string]Template{
+	// classic reproduces the original six-per-page A4 layout.
+	"classic": {
+		Paper:       papers["a4"],
+		Rows:        3,
+		Cols:        2,
+		PitchX:      264.6,
+		PitchY:      264.6,
+		LabelWidth:  250.6,
+		LabelHeight: 250.6,
+	},
+	// avery-l7160 is the 21-per-sheet A4 address label (63.5 x 38.1 mm).
+	"avery-l7160": {
+		Paper:       papers["a4"],
+		Rows:        7,
+		Cols:        3,
+		MarginLeft:  7.2 * pointsPerMM,
+		MarginTop:   15.15 * pointsPerMM,
+		PitchX:      66.04 * pointsPerMM,
+		PitchY:      38.1 * pointsPerMM,
+		LabelWidth:  63.5 * pointsPerMM,
+		LabelHeight: 38.1 * pointsPerMM,
+		Padding:     2 * pointsPerMM,
+	},
+	// label-89x36 is a single roll label as used by Dymo/Brother label printers.
+	"label-89x36": {
+		Paper:       Paper{Width: 89 * pointsPerMM, Height: 36 * pointsPerMM},
+		Rows:        1,
+		Cols:        1,
+		LabelWidth:  89 * pointsPerMM,
+		LabelHeight: 36 * pointsPerMM,
+		Padding:     2 * pointsPerMM,
+	},
+	// avery-5160 is the 30-per-sheet US Letter address label (2.625 x 1 in).
+	"avery-5160": {
+		Paper:       papers["letter"],
+		Rows:        10,
+		Cols:        3,
+		MarginLeft:  0.1875 * pointsPerInch,
+		MarginTop:   0.5 * pointsPerInch,
+		PitchX:      2.75 * pointsPerInch,
+		PitchY:      1 * pointsPerInch,
+		LabelWidth:  2.625 * pointsPerInch,
+		LabelHeight: 1 * pointsPerInch,
+		Padding:     0.05 * pointsPerInch,
+	},
+}
+
+// LookupTemplate returns the named template, optionally printed on another paper size.
+func LookupTemplate(name, paper string) (Template, error) {
+	t, ok := templates[name]
+	if !ok {
+		return Template{}, fmt.Errorf("%w %q, available: %s", ErrUnknownTemplate, name, strings.Join(TemplateNames(), ", "))
+	}
+	t.Name = name
+
+	if paper != "" {
+		p, ok := papers[strings.ToLower(paper)]
+		if !ok {
+			return Template{}, fmt.Errorf("%w %q", ErrUnknownPaper, paper)
+		}
+		t.Paper = p
+	}
+
+	return t, nil
+}
+
+// TemplateNames lists the available template names.
+func TemplateNames() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// PerPage is the number of labels on one sheet.
+func (t Template) PerPage() int {
+	return t.Rows * t.Cols
+}
+
+// Label returns the top-left corner of the index-th label on a page.
+func (t Template) Label(index int) (x, y float64) {
+	row, col := index/t.Cols, index%t.Cols
+
+	return t.MarginLeft + float64(col)*t.PitchX, t.MarginTop + float64(row)*t.PitchY
+}
+
+// Cell returns the top-left corner and side of the QR code for the index-th label on a page.
+func (t Template) Cell(index int) (x, y, size float64) {
+	x, y = t.Label(index)
+	size = min(t.LabelWidth, t.LabelHeight) - 2*t.Padding
+
+	return x + (t.LabelWidth-size)/2, y + (t.LabelHeight-size)/2, size
+}
@@ -0,0 +1,90 @@
+package printqr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTemplate(t *testing.T) {
+	tmpl, err := LookupTemplate("avery-l7160", "letter")
+	require.NoError(t, err)
+	assert.Equal(t, 21, tmpl.PerPage())
+	assert.Equal(t, papers["letter"], tmpl.Paper)
+
+	_, err = LookupTemplate("nope", "")
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+	_, err = LookupTemplate("classic", "a5")
+	assert.ErrorIs(t, err, ErrUnknownPaper)
+}
+
+func TestTemplate_Cell(t *testing.T) {
+	tmpl := templates["avery-5160"]
+
+	x, y, size := tmpl.Cell(0)
+	assert.InDelta(t, 72-2*3.6, size, 1e-9)
+	assert.InDelta(t, 13.5+(189-size)/2, x, 1e-9)
+	assert.InDelta(t, 36+3.6, y, 1e-9)
+
+	// Second column, second row.
+	x2, y2, _ := tmpl.Cell(4)
+	assert.InDelta(t, x+198, x2, 1e-9)
+	assert.InDelta(t, y+72, y2, 1e-9)
+}
+
+func TestWritePDF(t *testing.T) {
+	tmpl := templates["classic"]
+	codes := make([]uuid.UUID, tmpl.PerPage()+1)
+	for i := range codes {
+		codes[i] = uuid.New()
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePDF(&buf, codes, Options{Template: tmpl, Inverted: true, BaseURL: "https://tea-elephant.com"}))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, "/Count 2")
+
+	// Every xref entry must point at its object.
+	xref := strings.Index(out, "xref\n")
+	for i, line := range strings.Split(out[xref:], "\n")[3:8] {
+		var offset int
+		_, err := fmt.Sscanf(line, "%d", &offset)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj", i+1)))
+	}
+}
+
+func TestWriteLabels(t *testing.T) {
+	tmpl, err := LookupTemplate("label-89x36", "")
+	require.NoError(t, err)
+
+	labels := []Label{
+		{ID: uuid.New(), Title: "Da Hong Pao (2019)", Lines: []string{"Brew at 95 °C, steep 3–5 min"}},
+		{ID: uuid.New(), Title: strings.Repeat("Very long tea name ", 10)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLabels(&buf, labels, Options{Template: tmpl}))
+	out := buf.String()
+
+	assert.Contains(t, out, "/Count 2")
+	assert.Contains(t, out, "/BaseFont /Helvetica-Bold")
+	assert.Contains(t, out, `(Da Hong Pao \(2019\)) Tj`)
+	assert.Contains(t, out, `(Brew at 95 \260C, steep 3\2265 min) Tj`)
+	assert.Contains(t, out, `\205) Tj`)
+}
+
+func TestNewSVG(t *testing.T) {
+	svg, err := NewSVG(uuid.NewString(), false)
+	require.NoError(t, err)
+	assert.Contains(t, string(svg), `fill="#ffffff"`)
+	assert.Contains(t, string(svg), `<path fill="#000000"`)
+}
@@ -0,0 +1,69 @@
+package printqr
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// run is a horizontal stretch of dark modules; drawing runs instead of single modules keeps
+// vector output small.
+type run struct {
+	row, col, length int
+}
+
+// codeRuns returns the dark modules of the code for content and the code side in modules.
+func codeRuns(content string) ([]run, int, error) {
+	code, err := newCode(content)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bitmap := code.Bitmap()
+
+	var runs []run
+	for r, line := range bitmap {
+		for c := 0; c < len(line); c++ {
+			if !line[c] {
+				continue
+			}
+			start := c
+			for c < len(line) && line[c] {
+				c++
+			}
+			runs = append(runs, run{row: r, col: start, length: c - start})
+		}
+	}
+
+	return runs, len(bitmap), nil
+}
+
+// colors returns the background and module colors as hex for SVG and gray levels for PDF.
+func colors(inverted bool) (bgHex, fgHex string, bgGray, fgGray int) {
+	if inverted {
+		return "#000000", "#ffffff", 0, 1
+	}
+
+	return "#ffffff", "#000000", 1, 0
+}
+
+// NewSVG renders content as a standalone SVG image, one unit per module.
+func NewSVG(content string, inverted bool) ([]byte, error) {
+	runs, n, err := codeRuns(content)
+	if err != nil {
+		return nil, err
+	}
+
+	bg, fg, _, _ := colors(inverted)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, n, n, bg)
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, fg)
+	for _, r := range runs {
+		fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", r.col, r.row, r.length, r.length)
+	}
+	buf.WriteString(`"/></svg>`)
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
@@ -86,6 +86,7 @@ if [[ -n "$KID" ]]; then
   echo
   echo "Suggested JWT header for client (kid support):"
   echo "  { \"alg\": \"ES256\", \"kid\": \"$KID\" }"
+  echo "With the single key file, also set ADMIN_KEY_ID=$KID on the server, or tokens with this kid are rejected."
 fi
 
 echo